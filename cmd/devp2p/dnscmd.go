@@ -0,0 +1,322 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p/dnsdisc"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/rlp"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	dnsCommand = cli.Command{
+		Name:  "dns",
+		Usage: "DNS Discovery Commands",
+		Subcommands: []cli.Command{
+			dnsSyncCommand,
+			dnsSignCommand,
+			dnsTXTCommand,
+		},
+	}
+	dnsSyncCommand = cli.Command{
+		Name:      "sync",
+		Usage:     "Download a DNS discovery tree",
+		ArgsUsage: "<url> [ <directory> ]",
+		Action:    dnsSync,
+	}
+	dnsSignCommand = cli.Command{
+		Name:      "sign",
+		Usage:     "Sign a DNS discovery tree",
+		ArgsUsage: "<tree-directory> <key-file>",
+		Action:    dnsSign,
+		Flags:     []cli.Flag{dnsDomainFlag, dnsSeqFlag},
+	}
+	dnsTXTCommand = cli.Command{
+		Name:      "to-txt",
+		Usage:     "Create a DNS TXT records for a discovery tree",
+		ArgsUsage: "<tree-directory> <output-file>",
+		Action:    dnsToTXT,
+	}
+)
+
+var (
+	dnsDomainFlag = cli.StringFlag{
+		Name:  "domain",
+		Usage: "Domain name of the tree",
+	}
+	dnsSeqFlag = cli.UintFlag{
+		Name:  "seq",
+		Usage: "New sequence number of the tree",
+	}
+)
+
+const (
+	treeMetaFile  = "enrtree-info.json"
+	treeNodesFile = "nodes.json"
+)
+
+// dnsSync performs dnsSyncCommand.
+func dnsSync(ctx *cli.Context) error {
+	if ctx.NArg() < 1 {
+		return fmt.Errorf("need tree URL as argument")
+	}
+	var (
+		c      = dnsdisc.NewClient(dnsdisc.Config{})
+		url    = ctx.Args().Get(0)
+		outdir = ctx.Args().Get(1)
+	)
+	domain, _, err := dnsdisc.ParseURL(url)
+	if err != nil {
+		return err
+	}
+	if outdir == "" {
+		outdir = domain
+	}
+	t, err := c.SyncTree(url)
+	if err != nil {
+		return err
+	}
+	def := treeToDefinition(url, t)
+	def.Meta.LastModified = time.Now()
+	return writeTreeDefinition(outdir, def)
+}
+
+// dnsSign performs dnsSignCommand.
+func dnsSign(ctx *cli.Context) error {
+	if ctx.NArg() < 2 {
+		return fmt.Errorf("need tree definition directory and key file as arguments")
+	}
+	var (
+		defdir  = ctx.Args().Get(0)
+		keyfile = ctx.Args().Get(1)
+		domain  = filepath.Base(defdir)
+	)
+	def, err := loadTreeDefinition(defdir)
+	if err != nil {
+		return err
+	}
+	if def.Meta.URL != "" {
+		if domain, _, err = dnsdisc.ParseURL(def.Meta.URL); err != nil {
+			return fmt.Errorf("invalid 'url' field: %v", err)
+		}
+	}
+	if ctx.IsSet(dnsDomainFlag.Name) {
+		domain = ctx.String(dnsDomainFlag.Name)
+	}
+	if ctx.IsSet(dnsSeqFlag.Name) {
+		def.Meta.Seq = ctx.Uint(dnsSeqFlag.Name)
+	} else {
+		def.Meta.Seq++ // Auto-bump sequence number if not supplied via flag.
+	}
+	t, err := dnsdisc.MakeTree(def.Meta.Seq, def.Nodes, def.Meta.Links)
+	if err != nil {
+		return err
+	}
+	key, err := crypto.LoadECDSA(keyfile)
+	if err != nil {
+		return fmt.Errorf("can't load key: %v", err)
+	}
+	url, err := t.Sign(key, domain)
+	if err != nil {
+		return fmt.Errorf("can't sign: %v", err)
+	}
+	def = treeToDefinition(url, t)
+	def.Meta.LastModified = time.Now()
+	if err := writeTreeDefinition(defdir, def); err != nil {
+		return err
+	}
+	fmt.Println(url)
+	return nil
+}
+
+// dnsToTXT performs dnsTXTCommand.
+func dnsToTXT(ctx *cli.Context) error {
+	if ctx.NArg() < 1 {
+		return fmt.Errorf("need tree definition directory as argument")
+	}
+	output := ctx.Args().Get(1)
+	if output == "" {
+		output = "-" // default to stdout
+	}
+	domain, t, err := loadSignedTree(ctx.Args().Get(0))
+	if err != nil {
+		return err
+	}
+	return writeTXTJSON(output, t.ToTXT(domain))
+}
+
+// loadSignedTree loads a tree definition and verifies its signature.
+func loadSignedTree(defdir string) (string, *dnsdisc.Tree, error) {
+	def, err := loadTreeDefinition(defdir)
+	if err != nil {
+		return "", nil, err
+	}
+	if def.Meta.URL == "" {
+		return "", nil, fmt.Errorf("missing 'url' field, run 'devp2p dns sign' first")
+	}
+	domain, pubkey, err := dnsdisc.ParseURL(def.Meta.URL)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid 'url' field: %v", err)
+	}
+	t, err := dnsdisc.MakeTree(def.Meta.Seq, def.Nodes, def.Meta.Links)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := t.SetSignature(pubkey, def.Meta.Sig); err != nil {
+		return "", nil, fmt.Errorf("invalid signature on tree, run 'devp2p dns sign' to update it")
+	}
+	return domain, t, nil
+}
+
+// dnsDefinition represents a DNS tree definition on disk.
+type dnsDefinition struct {
+	Meta  dnsMetaJSON
+	Nodes []*enode.Node
+}
+
+// dnsMetaJSON is the content of the tree metadata file.
+type dnsMetaJSON struct {
+	URL          string    `json:"url,omitempty"`
+	Seq          uint      `json:"seq"`
+	Sig          string    `json:"signature,omitempty"`
+	Links        []string  `json:"links"`
+	LastModified time.Time `json:"lastModified"`
+}
+
+func treeToDefinition(url string, t *dnsdisc.Tree) *dnsDefinition {
+	meta := dnsMetaJSON{
+		URL:   url,
+		Seq:   t.Seq(),
+		Sig:   t.Signature(),
+		Links: t.Links(),
+	}
+	if meta.Links == nil {
+		meta.Links = []string{}
+	}
+	return &dnsDefinition{Meta: meta, Nodes: t.Nodes()}
+}
+
+// loadTreeDefinition loads a directory in 'definition' format.
+func loadTreeDefinition(directory string) (*dnsDefinition, error) {
+	metaFile, nodesFile := treeDefinitionFiles(directory)
+	var def dnsDefinition
+	if err := loadJSON(metaFile, &def.Meta); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if def.Meta.Links == nil {
+		def.Meta.Links = []string{}
+	}
+	// Check link syntax.
+	for _, link := range def.Meta.Links {
+		if _, _, err := dnsdisc.ParseURL(link); err != nil {
+			return nil, fmt.Errorf("invalid link %q: %v", link, err)
+		}
+	}
+	// Load and check nodes.
+	var records []string
+	if err := loadJSON(nodesFile, &records); err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		n, err := enode.Parse(enode.ValidSchemes, record)
+		if err != nil {
+			return nil, fmt.Errorf("invalid node %q: %v", record, err)
+		}
+		def.Nodes = append(def.Nodes, n)
+	}
+	return &def, nil
+}
+
+// writeTreeDefinition writes a DNS node tree definition to the given directory.
+func writeTreeDefinition(directory string, def *dnsDefinition) error {
+	metaJSON, err := json.MarshalIndent(&def.Meta, "", jsonIndent)
+	if err != nil {
+		return err
+	}
+	records := make([]string, len(def.Nodes))
+	for i, n := range def.Nodes {
+		if records[i], err = nodeToText(n); err != nil {
+			return err
+		}
+	}
+	sort.Strings(records)
+	nodesJSON, err := json.MarshalIndent(records, "", jsonIndent)
+	if err != nil {
+		return err
+	}
+	if err := os.Mkdir(directory, 0744); err != nil && !os.IsExist(err) {
+		return err
+	}
+	metaFile, nodesFile := treeDefinitionFiles(directory)
+	if err := ioutil.WriteFile(nodesFile, nodesJSON, 0644); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(metaFile, metaJSON, 0644)
+}
+
+func treeDefinitionFiles(directory string) (string, string) {
+	meta := filepath.Join(directory, treeMetaFile)
+	nodes := filepath.Join(directory, treeNodesFile)
+	return meta, nodes
+}
+
+// nodeToText encodes the record of a node in "enr:" text form.
+func nodeToText(n *enode.Node) (string, error) {
+	enc, err := rlp.EncodeToBytes(n.Record())
+	if err != nil {
+		return "", err
+	}
+	return "enr:" + base64.RawURLEncoding.EncodeToString(enc), nil
+}
+
+// writeTXTJSON writes TXT records in JSON format.
+func writeTXTJSON(file string, txt map[string]string) error {
+	txtJSON, err := json.MarshalIndent(txt, "", jsonIndent)
+	if err != nil {
+		return err
+	}
+	if file == "-" {
+		os.Stdout.Write(txtJSON)
+		fmt.Println()
+		return nil
+	}
+	return ioutil.WriteFile(file, txtJSON, 0644)
+}
+
+const jsonIndent = "    "
+
+func loadJSON(file string, val interface{}) error {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, val); err != nil {
+		return fmt.Errorf("invalid JSON in %s: %v", file, err)
+	}
+	return nil
+}
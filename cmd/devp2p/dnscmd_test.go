@@ -0,0 +1,105 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+)
+
+func TestDNSSignAndExport(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "devp2p-dns-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	// Create a tree definition and a signing key.
+	var (
+		defdir  = filepath.Join(tmp, "nodes.example.org")
+		keyfile = filepath.Join(tmp, "key")
+		txtfile = filepath.Join(tmp, "txt.json")
+		nodes   = make([]*enode.Node, 3)
+	)
+	for i := range nodes {
+		var r enr.Record
+		r.Set(enr.IP{127, 0, 0, byte(i)})
+		key, _ := crypto.GenerateKey()
+		if err := enode.SignV4(&r, key); err != nil {
+			t.Fatal(err)
+		}
+		nodes[i], _ = enode.New(enode.ValidSchemes, &r)
+	}
+	if err := writeTreeDefinition(defdir, &dnsDefinition{Nodes: nodes}); err != nil {
+		t.Fatal(err)
+	}
+	key, _ := crypto.GenerateKey()
+	if err := crypto.SaveECDSA(keyfile, key); err != nil {
+		t.Fatal(err)
+	}
+
+	// Sign the tree and check the result.
+	if err := app.Run([]string{"devp2p", "dns", "sign", defdir, keyfile}); err != nil {
+		t.Fatal("sign failed:", err)
+	}
+	domain, tree, err := loadSignedTree(defdir)
+	if err != nil {
+		t.Fatal("can't load signed tree:", err)
+	}
+	if domain != "nodes.example.org" {
+		t.Errorf("wrong domain %q", domain)
+	}
+	if tree.Seq() != 1 {
+		t.Errorf("wrong sequence number %d", tree.Seq())
+	}
+	if len(tree.Nodes()) != len(nodes) {
+		t.Errorf("wrong node count %d, want %d", len(tree.Nodes()), len(nodes))
+	}
+
+	// Export the TXT records.
+	if err := app.Run([]string{"devp2p", "dns", "to-txt", defdir, txtfile}); err != nil {
+		t.Fatal("to-txt failed:", err)
+	}
+	var txt map[string]string
+	if err := loadJSON(txtfile, &txt); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(txt, tree.ToTXT(domain)) {
+		t.Error("wrong TXT records exported")
+	}
+	if !strings.HasPrefix(txt[domain], "enrtree-root:v1") {
+		t.Errorf("missing tree root record, have %q", txt[domain])
+	}
+
+	// Modifying the tree invalidates the signature.
+	def, _ := loadTreeDefinition(defdir)
+	def.Nodes = def.Nodes[1:]
+	if err := writeTreeDefinition(defdir, def); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := loadSignedTree(defdir); err == nil {
+		t.Error("modified tree accepted with old signature")
+	}
+}
@@ -22,10 +22,13 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/tests"
 
 	cli "gopkg.in/urfave/cli.v1"
@@ -36,8 +39,38 @@ var stateTestCommand = cli.Command{
 	Name:      "statetest",
 	Usage:     "executes the given state tests",
 	ArgsUsage: "<file>",
+	Flags: []cli.Flag{
+		ChainConfigFlag,
+		ForkFlag,
+		FillFlag,
+	},
+	Description: `
+The statetest command runs every subtest of the given state test file under the
+chain config of the fork it is recorded for.
+
+If --chainconfig is given, only the subtests recorded for --fork are run, under
+the given named network configuration (Classic, Social, Mix, Kotti, ...) or JSON
+encoded chain config. All forks of a named configuration are activated at the
+genesis block, while a JSON config file is used as given. With --fill, the
+post-states for --fork are computed under that config instead and the filled
+test file is printed.`,
 }
 
+var (
+	ChainConfigFlag = cli.StringFlag{
+		Name:  "chainconfig",
+		Usage: "Named network configuration or chain config JSON file to run the tests under",
+	}
+	ForkFlag = cli.StringFlag{
+		Name:  "fork",
+		Usage: "Name of the post-states to run or fill (default = chain config name)",
+	}
+	FillFlag = cli.BoolFlag{
+		Name:  "fill",
+		Usage: "Fill the post-states of the fork under the chain config and print the filled tests",
+	}
+)
+
 // StatetestResult contains the execution status after running a state test, any
 // error that might have occurred and a dump of the final state if requested.
 type StatetestResult struct {
@@ -77,6 +110,23 @@ func stateTestCmd(ctx *cli.Context) error {
 	default:
 		debugger = vm.NewStructLogger(config)
 	}
+	// Resolve the custom chain config to run or fill the tests under, if any
+	var (
+		chainConfig *params.ChainConfig
+		fork        = ctx.String(ForkFlag.Name)
+		err         error
+	)
+	if name := ctx.String(ChainConfigFlag.Name); name != "" {
+		if chainConfig, err = tests.LoadChainConfig(name); err != nil {
+			return err
+		}
+		if fork == "" {
+			fork = strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+		}
+	}
+	if ctx.Bool(FillFlag.Name) && chainConfig == nil {
+		return errors.New("--fill requires --chainconfig")
+	}
 	// Load the test content from the input file
 	src, err := ioutil.ReadFile(ctx.Args().First())
 	if err != nil {
@@ -86,26 +136,45 @@ func stateTestCmd(ctx *cli.Context) error {
 	if err = json.Unmarshal(src, &tests); err != nil {
 		return err
 	}
-	// Iterate over all the tests, run them and aggregate the results
 	cfg := vm.Config{
 		Tracer: tracer,
 		Debug:  ctx.GlobalBool(DebugFlag.Name) || ctx.GlobalBool(MachineFlag.Name),
 	}
+	if ctx.Bool(FillFlag.Name) {
+		for key, test := range tests {
+			if err := test.Fill(fork, chainConfig, cfg); err != nil {
+				return fmt.Errorf("failed to fill %s: %v", key, err)
+			}
+			tests[key] = test
+		}
+		out, _ := json.MarshalIndent(tests, "", "  ")
+		fmt.Println(string(out))
+		return nil
+	}
+	// Iterate over all the tests, run them and aggregate the results
 	results := make([]StatetestResult, 0, len(tests))
 	for key, test := range tests {
 		for _, st := range test.Subtests() {
+			if chainConfig != nil && st.Fork != fork {
+				continue
+			}
 			// Run the test and aggregate the result
 			result := &StatetestResult{Name: key, Fork: st.Fork, Pass: true}
-			state, err := test.Run(st, cfg)
+			var statedb *state.StateDB
+			if chainConfig != nil {
+				statedb, err = test.RunWithConfig(st, chainConfig, cfg)
+			} else {
+				statedb, err = test.Run(st, cfg)
+			}
 			// print state root for evmlab tracing
-			if ctx.GlobalBool(MachineFlag.Name) && state != nil {
-				fmt.Fprintf(os.Stderr, "{\"stateRoot\": \"%x\"}\n", state.IntermediateRoot(false))
+			if ctx.GlobalBool(MachineFlag.Name) && statedb != nil {
+				fmt.Fprintf(os.Stderr, "{\"stateRoot\": \"%x\"}\n", statedb.IntermediateRoot(false))
 			}
 			if err != nil {
 				// Test failed, mark as so and dump any state to aid debugging
 				result.Pass, result.Error = false, err.Error()
-				if ctx.GlobalBool(DumpFlag.Name) && state != nil {
-					dump := state.RawDump()
+				if ctx.GlobalBool(DumpFlag.Name) && statedb != nil {
+					dump := statedb.RawDump()
 					result.State = &dump
 				}
 			}
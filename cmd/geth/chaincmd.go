@@ -17,9 +17,12 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"runtime"
 	"strconv"
 	"sync/atomic"
@@ -27,16 +30,19 @@ import (
 
 	"github.com/ethereum/go-ethereum/cmd/utils"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/console"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/chainspec"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/state/pruner"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/trie"
-	"github.com/syndtr/goleveldb/leveldb/util"
 	"gopkg.in/urfave/cli.v1"
 )
 
@@ -48,6 +54,7 @@ var (
 		ArgsUsage: "<genesisPath>",
 		Flags: []cli.Flag{
 			utils.DataDirFlag,
+			utils.AncientFlag,
 		},
 		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
@@ -55,7 +62,27 @@ The init command initializes a new genesis block and definition for the network.
 This is a destructive action and changes the network in which you will be
 participating.
 
-It expects the genesis file as argument.`,
+It expects the genesis file as argument, which may be either a go-ethereum
+genesis specification or a Parity chainspec.`,
+	}
+	dumpGenesisFormatFlag = cli.StringFlag{
+		Name:  "format",
+		Usage: "Format of the dumped genesis specification (geth, parity)",
+		Value: "geth",
+	}
+	dumpGenesisCommand = cli.Command{
+		Action:    utils.MigrateFlags(dumpGenesis),
+		Name:      "dumpgenesis",
+		Usage:     "Dumps genesis block JSON configuration to stdout",
+		ArgsUsage: "",
+		Flags: []cli.Flag{
+			dumpGenesisFormatFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The dumpgenesis command dumps the genesis block configuration of the selected
+network in JSON format to stdout, either as a go-ethereum genesis specification
+or as a Parity chainspec.`,
 	}
 	importCommand = cli.Command{
 		Action:    utils.MigrateFlags(importChain),
@@ -64,6 +91,7 @@ It expects the genesis file as argument.`,
 		ArgsUsage: "<filename> (<filename 2> ... <filename N>) ",
 		Flags: []cli.Flag{
 			utils.DataDirFlag,
+			utils.AncientFlag,
 			utils.CacheFlag,
 			utils.SyncModeFlag,
 			utils.GCModeFlag,
@@ -85,6 +113,7 @@ processing will proceed even if an individual RLP-file import failure occurs.`,
 		ArgsUsage: "<filename> [<blockNumFirst> <blockNumLast>]",
 		Flags: []cli.Flag{
 			utils.DataDirFlag,
+			utils.AncientFlag,
 			utils.CacheFlag,
 			utils.SyncModeFlag,
 		},
@@ -103,6 +132,7 @@ be gzipped.`,
 		ArgsUsage: "<datafile>",
 		Flags: []cli.Flag{
 			utils.DataDirFlag,
+			utils.AncientFlag,
 			utils.CacheFlag,
 			utils.SyncModeFlag,
 		},
@@ -117,6 +147,7 @@ be gzipped.`,
 		ArgsUsage: "<dumpfile>",
 		Flags: []cli.Flag{
 			utils.DataDirFlag,
+			utils.AncientFlag,
 			utils.CacheFlag,
 			utils.SyncModeFlag,
 		},
@@ -131,6 +162,7 @@ The export-preimages command export hash preimages to an RLP encoded stream`,
 		ArgsUsage: "<sourceChaindataDir>",
 		Flags: []cli.Flag{
 			utils.DataDirFlag,
+			utils.AncientFlag,
 			utils.CacheFlag,
 			utils.SyncModeFlag,
 			utils.FakePoWFlag,
@@ -152,6 +184,7 @@ The first argument must be the directory containing the blockchain to download f
 		ArgsUsage: " ",
 		Flags: []cli.Flag{
 			utils.DataDirFlag,
+			utils.AncientFlag,
 		},
 		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
@@ -164,6 +197,7 @@ Remove blockchain and state databases`,
 		ArgsUsage: "[<blockHash> | <blockNum>]...",
 		Flags: []cli.Flag{
 			utils.DataDirFlag,
+			utils.AncientFlag,
 			utils.CacheFlag,
 			utils.SyncModeFlag,
 		},
@@ -172,6 +206,83 @@ Remove blockchain and state databases`,
 The arguments are interpreted as block numbers or hashes.
 Use "ethereum dump 0" to dump the genesis block.`,
 	}
+	supplyCommand = cli.Command{
+		Action:    utils.MigrateFlags(supply),
+		Name:      "supply",
+		Usage:     "Calculate the ether issued by a range of blocks",
+		ArgsUsage: "[<fromBlockNum> [<toBlockNum>]]",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.AncientFlag,
+			utils.CacheFlag,
+			utils.SyncModeFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The supply command sums up the block and uncle rewards of a range of local blocks,
+both ends included, as credited by the ethash monetary policy of the chain. The
+range defaults to the genesis block and the current head.`,
+	}
+	dbCommand = cli.Command{
+		Name:      "db",
+		Usage:     "Low level database operations",
+		ArgsUsage: "",
+		Category:  "BLOCKCHAIN COMMANDS",
+		Subcommands: []cli.Command{
+			{
+				Action:    utils.MigrateFlags(inspect),
+				Name:      "inspect",
+				Usage:     "Inspect the storage size for each type of data in the database",
+				ArgsUsage: " ",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.AncientFlag,
+					utils.CacheFlag,
+					utils.SyncModeFlag,
+				},
+				Category: "BLOCKCHAIN COMMANDS",
+				Description: `
+Walks over all the entries of the chain database and reports the size and
+item count of headers, bodies, receipts, difficulties, transaction lookups,
+bloombits, trie nodes, preimages and any unaccounted data.`,
+			},
+		},
+	}
+	pruneStateBloomSizeFlag = cli.Uint64Flag{
+		Name:  "bloomfilter.size",
+		Usage: "Megabytes of memory allocated to the bloom filter tracking the state to keep",
+		Value: 2048,
+	}
+	pruneStateCommand = cli.Command{
+		Action:    utils.MigrateFlags(pruneState),
+		Name:      "prune-state",
+		Usage:     "Prune stale state data from the database",
+		ArgsUsage: "[<root>]",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.AncientFlag,
+			utils.CacheFlag,
+			utils.SyncModeFlag,
+			pruneStateBloomSizeFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+geth prune-state [<root>]
+
+Deletes all trie nodes and contract codes from the database which are not
+reachable from the given state root, or the state root of the head block if
+none is given. The genesis state is always retained. The node must not be
+running while pruning.
+
+The given root must be the state of the head block or of one of the last 128
+canonical blocks. In the latter case the chain is rewound to the block owning
+the state, as the states of the blocks above it do not survive the pruning.
+
+The live state is tracked in a bloom filter of --bloomfilter.size megabytes;
+a larger filter leaves less stale data behind. Once the live state is marked,
+the filter is persisted into the data directory, so an interrupted pruning is
+finished by the next prune-state invocation or node startup.`,
+	}
 )
 
 // initGenesis will initialise the given JSON format genesis file and writes it as
@@ -182,14 +293,20 @@ func initGenesis(ctx *cli.Context) error {
 	if len(genesisPath) == 0 {
 		utils.Fatalf("Must supply path to genesis JSON file")
 	}
-	file, err := os.Open(genesisPath)
+	blob, err := ioutil.ReadFile(genesisPath)
 	if err != nil {
 		utils.Fatalf("Failed to read genesis file: %v", err)
 	}
-	defer file.Close()
-
 	genesis := new(core.Genesis)
-	if err := json.NewDecoder(file).Decode(genesis); err != nil {
+	if chainspec.IsParityChainSpec(blob) {
+		spec := new(chainspec.ParityChainSpec)
+		if err := json.Unmarshal(blob, spec); err != nil {
+			utils.Fatalf("invalid parity chainspec: %v", err)
+		}
+		if genesis, err = spec.ToGenesis(); err != nil {
+			utils.Fatalf("unsupported parity chainspec: %v", err)
+		}
+	} else if err := json.Unmarshal(blob, genesis); err != nil {
 		utils.Fatalf("invalid genesis file: %v", err)
 	}
 	// Open an initialise both full and light databases
@@ -210,6 +327,46 @@ func initGenesis(ctx *cli.Context) error {
 	return nil
 }
 
+// dumpGenesis writes the genesis specification of the selected network to stdout
+// in the requested format.
+func dumpGenesis(ctx *cli.Context) error {
+	genesis := utils.MakeGenesis(ctx)
+	if genesis == nil {
+		genesis = core.DefaultGenesisBlock()
+	}
+	var out interface{} = genesis
+	switch format := ctx.String(dumpGenesisFormatFlag.Name); format {
+	case "geth":
+	case "parity":
+		spec, err := chainspec.NewParityChainSpec(genesisName(ctx), genesis, nil)
+		if err != nil {
+			utils.Fatalf("Failed to convert genesis: %v", err)
+		}
+		out = spec
+	default:
+		utils.Fatalf("Unknown genesis format %q", format)
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(out); err != nil {
+		utils.Fatalf("Failed to encode genesis: %v", err)
+	}
+	return nil
+}
+
+// genesisName returns the name of the network selected on the command line.
+func genesisName(ctx *cli.Context) string {
+	for _, flag := range []cli.BoolFlag{
+		utils.TestnetFlag, utils.ClassicFlag, utils.SocialFlag, utils.MixFlag,
+		utils.EthersocialFlag, utils.RinkebyFlag, utils.KottiFlag, utils.GoerliFlag,
+	} {
+		if ctx.GlobalBool(flag.Name) {
+			return flag.Name
+		}
+	}
+	return "mainnet"
+}
+
 func importChain(ctx *cli.Context) error {
 	if len(ctx.Args()) < 1 {
 		utils.Fatalf("This command requires an argument.")
@@ -253,15 +410,13 @@ func importChain(ctx *cli.Context) error {
 	fmt.Printf("Import done in %v.\n\n", time.Since(start))
 
 	// Output pre-compaction stats mostly to see the import trashing
-	db := chainDb.(*ethdb.LDBDatabase)
-
-	stats, err := db.LDB().GetProperty("leveldb.stats")
+	stats, err := chainDb.Stat("leveldb.stats")
 	if err != nil {
 		utils.Fatalf("Failed to read database stats: %v", err)
 	}
 	fmt.Println(stats)
 
-	ioStats, err := db.LDB().GetProperty("leveldb.iostats")
+	ioStats, err := chainDb.Stat("leveldb.iostats")
 	if err != nil {
 		utils.Fatalf("Failed to read database iostats: %v", err)
 	}
@@ -286,18 +441,18 @@ func importChain(ctx *cli.Context) error {
 	// Compact the entire database to more accurately measure disk io and print the stats
 	start = time.Now()
 	fmt.Println("Compacting entire database...")
-	if err = db.LDB().CompactRange(util.Range{}); err != nil {
+	if err = chainDb.Compact(nil, nil); err != nil {
 		utils.Fatalf("Compaction failed: %v", err)
 	}
 	fmt.Printf("Compaction done in %v.\n\n", time.Since(start))
 
-	stats, err = db.LDB().GetProperty("leveldb.stats")
+	stats, err = chainDb.Stat("leveldb.stats")
 	if err != nil {
 		utils.Fatalf("Failed to read database stats: %v", err)
 	}
 	fmt.Println(stats)
 
-	ioStats, err = db.LDB().GetProperty("leveldb.iostats")
+	ioStats, err = chainDb.Stat("leveldb.iostats")
 	if err != nil {
 		utils.Fatalf("Failed to read database iostats: %v", err)
 	}
@@ -348,7 +503,7 @@ func importPreimages(ctx *cli.Context) error {
 	stack := makeFullNode(ctx)
 	defer stack.Close()
 
-	diskdb := utils.MakeChainDatabase(ctx, stack).(*ethdb.LDBDatabase)
+	diskdb := utils.MakeChainDatabase(ctx, stack, true)
 	start := time.Now()
 
 	if err := utils.ImportPreimages(diskdb, ctx.Args().First()); err != nil {
@@ -366,7 +521,7 @@ func exportPreimages(ctx *cli.Context) error {
 	stack := makeFullNode(ctx)
 	defer stack.Close()
 
-	diskdb := utils.MakeChainDatabase(ctx, stack).(*ethdb.LDBDatabase)
+	diskdb := utils.MakeChainDatabase(ctx, stack, true)
 	start := time.Now()
 
 	if err := utils.ExportPreimages(diskdb, ctx.Args().First()); err != nil {
@@ -417,7 +572,7 @@ func copyDb(ctx *cli.Context) error {
 	// Compact the entire database to remove any sync overhead
 	start = time.Now()
 	fmt.Println("Compacting entire database...")
-	if err = chainDb.(*ethdb.LDBDatabase).LDB().CompactRange(util.Range{}); err != nil {
+	if err = chainDb.Compact(nil, nil); err != nil {
 		utils.Fatalf("Compaction failed: %v", err)
 	}
 	fmt.Printf("Compaction done in %v.\n\n", time.Since(start))
@@ -428,11 +583,15 @@ func copyDb(ctx *cli.Context) error {
 func removeDB(ctx *cli.Context) error {
 	stack, _ := makeConfigNode(ctx)
 
-	for _, name := range []string{"chaindata", "lightchaindata"} {
+	dbdirs := []string{stack.ResolvePath("chaindata"), stack.ResolvePath("lightchaindata")}
+	if ancient := ctx.GlobalString(utils.AncientFlag.Name); ancient != "" && filepath.IsAbs(ancient) {
+		// Relative ancient directories live inside the chaindata and are removed with it
+		dbdirs = append(dbdirs, ancient)
+	}
+	for _, dbdir := range dbdirs {
 		// Ensure the database exists in the first place
-		logger := log.New("database", name)
+		logger := log.New("database", filepath.Base(dbdir))
 
-		dbdir := stack.ResolvePath(name)
 		if !common.FileExist(dbdir) {
 			logger.Info("Database doesn't exist, skipping", "path", dbdir)
 			continue
@@ -482,6 +641,79 @@ func dump(ctx *cli.Context) error {
 	return nil
 }
 
+// supply calculates the ether issued by the block and uncle rewards of a range of
+// local blocks.
+func supply(ctx *cli.Context) error {
+	if len(ctx.Args()) > 2 {
+		utils.Fatalf("This command accepts at most two arguments.")
+	}
+	stack := makeFullNode(ctx)
+	defer stack.Close()
+
+	chain, chainDb := utils.MakeChain(ctx, stack)
+	defer chainDb.Close()
+
+	if chain.Config().Clique != nil {
+		utils.Fatalf("Supply unavailable for clique networks")
+	}
+	from, to := uint64(0), chain.CurrentBlock().NumberU64()
+	for i, arg := range ctx.Args() {
+		number, err := strconv.ParseUint(arg, 0, 64)
+		if err != nil {
+			utils.Fatalf("Invalid block number: %v", err)
+		}
+		if i == 0 {
+			from = number
+		} else {
+			to = number
+		}
+	}
+	issuance, err := ethash.CalcIssuance(context.Background(), chain, from, to)
+	if err != nil {
+		utils.Fatalf("Failed to calculate issuance: %v", err)
+	}
+	fmt.Printf("Blocks: #%d - #%d\n", issuance.From, issuance.To)
+	fmt.Printf("Miner:  %v wei\n", issuance.Miner)
+	fmt.Printf("Uncles: %v wei\n", issuance.Uncles)
+	fmt.Printf("Total:  %v wei\n", issuance.Total())
+	return nil
+}
+
+func inspect(ctx *cli.Context) error {
+	stack := makeFullNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, true)
+	defer db.Close()
+
+	return rawdb.InspectDatabase(db)
+}
+
+func pruneState(ctx *cli.Context) error {
+	if len(ctx.Args()) > 1 {
+		utils.Fatalf("This command accepts at most one argument.")
+	}
+	var root common.Hash
+	if len(ctx.Args()) == 1 {
+		if !hashish(ctx.Args().First()) || len(common.FromHex(ctx.Args().First())) != common.HashLength {
+			utils.Fatalf("Invalid state root: %s", ctx.Args().First())
+		}
+		root = common.HexToHash(ctx.Args().First())
+	}
+	stack := makeFullNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, true)
+	defer db.Close()
+
+	start := time.Now()
+	if err := pruner.NewPruner(db, stack.ResolvePath(""), ctx.Uint64(pruneStateBloomSizeFlag.Name)).Prune(root); err != nil {
+		utils.Fatalf("Failed to prune state: %v", err)
+	}
+	fmt.Printf("State pruning done in %v\n", time.Since(start))
+	return nil
+}
+
 // hashish returns true for strings that look like hashes.
 func hashish(x string) bool {
 	_, err := strconv.Atoi(x)
@@ -82,6 +82,40 @@ var customGenesisTests = []struct {
 		query:  "eth.getBlock(0).nonce",
 		result: "0x0000000000000042",
 	},
+	// Parity chainspec instead of a genesis file
+	{
+		genesis: `{
+			"name"   : "Test",
+			"engine" : {
+				"Ethash" : {
+					"params" : {
+						"blockReward"         : "0x4563918244f40000",
+						"homesteadTransition" : 314
+					}
+				}
+			},
+			"params" : {
+				"networkID" : "0x539"
+			},
+			"genesis" : {
+				"seal" : {
+					"ethereum" : {
+						"nonce"   : "0x0000000000000042",
+						"mixHash" : "0x0000000000000000000000000000000000000000000000000000000000000000"
+					}
+				},
+				"difficulty" : "0x20000",
+				"author"     : "0x0000000000000000000000000000000000000000",
+				"timestamp"  : "0x00",
+				"parentHash" : "0x0000000000000000000000000000000000000000000000000000000000000000",
+				"extraData"  : "0x",
+				"gasLimit"   : "0x2fefd8"
+			},
+			"accounts" : {}
+		}`,
+		query:  "eth.getBlock(0).nonce",
+		result: "0x0000000000000042",
+	},
 }
 
 // Tests that initializing Geth with a custom genesis block and chain definitions
@@ -38,7 +38,8 @@ var (
 		ArgsUsage: "<blockNum> <outputDir>",
 		Category:  "MISCELLANEOUS COMMANDS",
 		Description: `
-The makecache command generates an ethash cache in <outputDir>.
+The makecache command generates an ethash cache in <outputDir>,
+for the epoch of <blockNum> on the selected network (e.g. --classic).
 
 This command exists to support the system testing project.
 Regular users do not need to execute it.
@@ -51,7 +52,8 @@ Regular users do not need to execute it.
 		ArgsUsage: "<blockNum> <outputDir>",
 		Category:  "MISCELLANEOUS COMMANDS",
 		Description: `
-The makedag command generates an ethash DAG in <outputDir>.
+The makedag command generates an ethash DAG in <outputDir>,
+for the epoch of <blockNum> on the selected network (e.g. --classic).
 
 This command exists to support the system testing project.
 Regular users do not need to execute it.
@@ -86,7 +88,7 @@ func makecache(ctx *cli.Context) error {
 	if err != nil {
 		utils.Fatalf("Invalid block number: %v", err)
 	}
-	ethash.MakeCache(block, args[1])
+	ethash.MakeCache(block, chainConfig(ctx).ECIP1099FBlock, args[1])
 
 	return nil
 }
@@ -101,11 +103,20 @@ func makedag(ctx *cli.Context) error {
 	if err != nil {
 		utils.Fatalf("Invalid block number: %v", err)
 	}
-	ethash.MakeDataset(block, args[1])
+	ethash.MakeDataset(block, chainConfig(ctx).ECIP1099FBlock, args[1])
 
 	return nil
 }
 
+// chainConfig returns the chain config of the network selected on the command line,
+// defaulting to the main network.
+func chainConfig(ctx *cli.Context) *params.ChainConfig {
+	if genesis := utils.MakeGenesis(ctx); genesis != nil {
+		return genesis.Config
+	}
+	return params.MainnetChainConfig
+}
+
 func version(ctx *cli.Context) error {
 	fmt.Println(strings.Title(clientIdentifier))
 	fmt.Println("Version:", params.VersionWithMeta)
@@ -35,27 +35,47 @@ import (
 )
 
 const (
-	datasetInitBytes   = 1 << 30 // Bytes in dataset at genesis
-	datasetGrowthBytes = 1 << 23 // Dataset growth per epoch
-	cacheInitBytes     = 1 << 24 // Bytes in cache at genesis
-	cacheGrowthBytes   = 1 << 17 // Cache growth per epoch
-	epochLength        = 30000   // Blocks per epoch
-	mixBytes           = 128     // Width of mix
-	hashBytes          = 64      // Hash length in bytes
-	hashWords          = 16      // Number of 32 bit ints in a hash
-	datasetParents     = 256     // Number of parents of each dataset element
-	cacheRounds        = 3       // Number of rounds in cache production
-	loopAccesses       = 64      // Number of accesses in hashimoto loop
+	datasetInitBytes    = 1 << 30 // Bytes in dataset at genesis
+	datasetGrowthBytes  = 1 << 23 // Dataset growth per epoch
+	cacheInitBytes      = 1 << 24 // Bytes in cache at genesis
+	cacheGrowthBytes    = 1 << 17 // Cache growth per epoch
+	epochLengthDefault  = 30000   // Blocks per epoch
+	epochLengthECIP1099 = 60000   // Blocks per epoch after ECIP-1099
+	mixBytes            = 128     // Width of mix
+	hashBytes           = 64      // Hash length in bytes
+	hashWords           = 16      // Number of 32 bit ints in a hash
+	datasetParents      = 256     // Number of parents of each dataset element
+	cacheRounds         = 3       // Number of rounds in cache production
+	loopAccesses        = 64      // Number of accesses in hashimoto loop
 )
 
+// calcEpochLength returns the number of blocks per epoch at a certain block number,
+// doubled from the ECIP-1099 transition on.
+func calcEpochLength(block uint64, ecip1099FBlock *big.Int) uint64 {
+	if ecip1099FBlock != nil && ecip1099FBlock.IsUint64() && ecip1099FBlock.Uint64() <= block {
+		return epochLengthECIP1099
+	}
+	return epochLengthDefault
+}
+
+// calcEpoch returns the epoch a certain block number belongs to.
+func calcEpoch(block uint64, epochLength uint64) uint64 {
+	return block / epochLength
+}
+
+// calcEpochBlock returns the first block number past the start of an epoch, which
+// the seed of the epoch is derived from.
+func calcEpochBlock(epoch uint64, epochLength uint64) uint64 {
+	return epoch*epochLength + 1
+}
+
 // cacheSize returns the size of the ethash verification cache that belongs to a certain
-// block number.
-func cacheSize(block uint64) uint64 {
-	epoch := int(block / epochLength)
+// epoch.
+func cacheSize(epoch uint64) uint64 {
 	if epoch < maxEpoch {
-		return cacheSizes[epoch]
+		return cacheSizes[int(epoch)]
 	}
-	return calcCacheSize(epoch)
+	return calcCacheSize(int(epoch))
 }
 
 // calcCacheSize calculates the cache size for epoch. The cache size grows linearly,
@@ -70,13 +90,12 @@ func calcCacheSize(epoch int) uint64 {
 }
 
 // datasetSize returns the size of the ethash mining dataset that belongs to a certain
-// block number.
-func datasetSize(block uint64) uint64 {
-	epoch := int(block / epochLength)
+// epoch.
+func datasetSize(epoch uint64) uint64 {
 	if epoch < maxEpoch {
-		return datasetSizes[epoch]
+		return datasetSizes[int(epoch)]
 	}
-	return calcDatasetSize(epoch)
+	return calcDatasetSize(int(epoch))
 }
 
 // calcDatasetSize calculates the dataset size for epoch. The dataset size grows linearly,
@@ -117,14 +136,17 @@ func makeHasher(h hash.Hash) hasher {
 }
 
 // seedHash is the seed to use for generating a verification cache and the mining
-// dataset.
-func seedHash(block uint64) []byte {
+// dataset. The seed keeps advancing once per default length epoch, so an ECIP-1099
+// epoch shares the seed of the default length epoch it starts with.
+func seedHash(epoch uint64, epochLength uint64) []byte {
+	block := calcEpochBlock(epoch, epochLength)
+
 	seed := make([]byte, 32)
-	if block < epochLength {
+	if block < epochLengthDefault {
 		return seed
 	}
 	keccak256 := makeHasher(sha3.NewLegacyKeccak256())
-	for i := 0; i < int(block/epochLength); i++ {
+	for i := 0; i < int(block/epochLengthDefault); i++ {
 		keccak256(seed, seed)
 	}
 	return seed
@@ -56,6 +56,37 @@ func TestSizeCalculations(t *testing.T) {
 	}
 }
 
+// Tests that epochs double in length from the ECIP-1099 transition on, whilst the
+// seeds keep advancing once per default length epoch.
+func TestEpochLengthECIP1099(t *testing.T) {
+	transition := big.NewInt(11700000)
+
+	tests := []struct {
+		block  uint64
+		epoch  uint64
+		length uint64
+		seed   uint64 // Default length epoch sharing the seed
+	}{
+		{0, 0, epochLengthDefault, 0},
+		{11699999, 389, epochLengthDefault, 389},
+		{11700000, 195, epochLengthECIP1099, 390},
+		{11759999, 195, epochLengthECIP1099, 390},
+		{11760000, 196, epochLengthECIP1099, 392},
+	}
+	for i, tt := range tests {
+		length := calcEpochLength(tt.block, transition)
+		if epoch := calcEpoch(tt.block, length); epoch != tt.epoch || length != tt.length {
+			t.Errorf("test %d: epoch mismatch: have %d/%d, want %d/%d", i, epoch, length, tt.epoch, tt.length)
+		}
+		if have, want := SeedHash(tt.block, transition), seedHash(tt.seed, epochLengthDefault); !bytes.Equal(have, want) {
+			t.Errorf("test %d: seed mismatch: have %x, want %x", i, have, want)
+		}
+	}
+	if length := calcEpochLength(11700000, nil); length != epochLengthDefault {
+		t.Errorf("epoch length mismatch without transition: have %d, want %d", length, epochLengthDefault)
+	}
+}
+
 // Tests that verification caches can be correctly generated.
 func TestCacheGeneration(t *testing.T) {
 	tests := []struct {
@@ -108,7 +139,7 @@ func TestCacheGeneration(t *testing.T) {
 	}
 	for i, tt := range tests {
 		cache := make([]uint32, tt.size/4)
-		generateCache(cache, tt.epoch, seedHash(tt.epoch*epochLength+1))
+		generateCache(cache, tt.epoch, seedHash(tt.epoch, epochLengthDefault))
 
 		want := make([]uint32, tt.size/4)
 		prepare(want, tt.cache)
@@ -648,7 +679,7 @@ func TestDatasetGeneration(t *testing.T) {
 	}
 	for i, tt := range tests {
 		cache := make([]uint32, tt.cacheSize/4)
-		generateCache(cache, tt.epoch, seedHash(tt.epoch*epochLength+1))
+		generateCache(cache, tt.epoch, seedHash(tt.epoch, epochLengthDefault))
 
 		dataset := make([]uint32, tt.datasetSize/4)
 		generateDataset(dataset, tt.epoch, cache)
@@ -729,7 +760,7 @@ func TestConcurrentDiskCacheGeneration(t *testing.T) {
 
 		go func(idx int) {
 			defer pend.Done()
-			ethash := New(Config{cachedir, 0, 1, "", 0, 0, ModeNormal}, nil, false)
+			ethash := New(Config{cachedir, 0, 1, "", 0, 0, ModeNormal, nil}, nil, false)
 			defer ethash.Close()
 			if err := ethash.VerifySeal(nil, block.Header()); err != nil {
 				t.Errorf("proc %d: block verification failed: %v", idx, err)
@@ -742,7 +773,7 @@ func TestConcurrentDiskCacheGeneration(t *testing.T) {
 // Benchmarks the cache generation performance.
 func BenchmarkCacheGeneration(b *testing.B) {
 	for i := 0; i < b.N; i++ {
-		cache := make([]uint32, cacheSize(1)/4)
+		cache := make([]uint32, cacheSize(0)/4)
 		generateCache(cache, 0, make([]byte, 32))
 	}
 }
@@ -761,14 +792,14 @@ func BenchmarkSmallDatasetGeneration(b *testing.B) {
 
 // Benchmarks the light verification performance.
 func BenchmarkHashimotoLight(b *testing.B) {
-	cache := make([]uint32, cacheSize(1)/4)
+	cache := make([]uint32, cacheSize(0)/4)
 	generateCache(cache, 0, make([]byte, 32))
 
 	hash := hexutil.MustDecode("0xc9149cc0386e689d789a1c2f3d5d169a61a6218ed30e74414dc736e442ef3d1f")
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		hashimotoLight(datasetSize(1), cache, hash, 0)
+		hashimotoLight(datasetSize(0), cache, hash, 0)
 	}
 }
 
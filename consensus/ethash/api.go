@@ -17,11 +17,14 @@
 package ethash
 
 import (
+	"context"
 	"errors"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
 )
 
 var errEthashStopped = errors.New("ethash stopped")
@@ -116,3 +119,55 @@ func (api *API) SubmitHashRate(rate hexutil.Uint64, id common.Hash) bool {
 func (api *API) GetHashrate() uint64 {
 	return uint64(api.ethash.Hashrate())
 }
+
+// maxIssuanceRange is the maximum number of blocks a single issuance query over
+// RPC may span. The issuance of longer ranges can be calculated offline with the
+// geth supply command.
+const maxIssuanceRange = 1024
+
+// IssuanceAPI exposes the monetary policy of the chain, as enforced by ethash,
+// for the RPC interface.
+type IssuanceAPI struct {
+	chain consensus.ChainReader
+}
+
+// BlockReward returns the ether minted by the block and uncle rewards of a block.
+func (api *IssuanceAPI) BlockReward(ctx context.Context, number rpc.BlockNumber) (map[string]interface{}, error) {
+	block := api.resolve(number)
+	return api.issuance(ctx, block, block)
+}
+
+// Issuance returns the ether minted by the block and uncle rewards of a range of
+// blocks, both ends included. The range may span at most maxIssuanceRange blocks.
+func (api *IssuanceAPI) Issuance(ctx context.Context, from, to rpc.BlockNumber) (map[string]interface{}, error) {
+	start, end := api.resolve(from), api.resolve(to)
+	if end >= start && end-start >= maxIssuanceRange {
+		return nil, errIssuanceRange
+	}
+	return api.issuance(ctx, start, end)
+}
+
+// resolve converts a block number of the RPC interface into an actual one, mapping
+// the pending block to the latest one.
+func (api *IssuanceAPI) resolve(number rpc.BlockNumber) uint64 {
+	if number == rpc.LatestBlockNumber || number == rpc.PendingBlockNumber {
+		return api.chain.CurrentHeader().Number.Uint64()
+	}
+	return uint64(number.Int64())
+}
+
+// issuance calculates the issuance of a block range and flattens it into an RPC
+// response.
+func (api *IssuanceAPI) issuance(ctx context.Context, from, to uint64) (map[string]interface{}, error) {
+	issuance, err := CalcIssuance(ctx, api.chain, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"from":   hexutil.Uint64(issuance.From),
+		"to":     hexutil.Uint64(issuance.To),
+		"miner":  (*hexutil.Big)(issuance.Miner),
+		"uncles": (*hexutil.Big)(issuance.Uncles),
+		"total":  (*hexutil.Big)(issuance.Total()),
+	}, nil
+}
@@ -488,7 +488,7 @@ func (ethash *Ethash) verifySeal(chain consensus.ChainReader, header *types.Head
 	if !fulldag {
 		cache := ethash.cache(number)
 
-		size := datasetSize(number)
+		size := datasetSize(cache.epoch)
 		if ethash.config.PowMode == ModeTest {
 			size = 32 * 1024
 		}
@@ -564,6 +564,17 @@ var (
 // reward. The total reward consists of the static block reward and rewards for
 // included uncles. The coinbase of each uncle block is also rewarded.
 func accumulateRewards(config *params.ChainConfig, state *state.StateDB, header *types.Header, uncles []*types.Header) {
+	minerReward, uncleRewards := blockRewards(config, header, uncles)
+	for i, uncle := range uncles {
+		state.AddBalance(uncle.Coinbase, uncleRewards[i])
+	}
+	state.AddBalance(header.Coinbase, minerReward)
+}
+
+// blockRewards calculates the reward credited to the coinbase of the given block,
+// including the rewards for any included uncles, and the rewards credited to the
+// coinbase of each uncle block.
+func blockRewards(config *params.ChainConfig, header *types.Header, uncles []*types.Header) (*big.Int, []*big.Int) {
 	// Select the correct block reward based on chain progression
 	blockReward := FrontierBlockReward
 	if config.IsEIP649F(header.Number) {
@@ -578,6 +589,7 @@ func accumulateRewards(config *params.ChainConfig, state *state.StateDB, header
 	if config.IsEthersocial(header.Number) {
 		blockReward = EthersocialBlockReward
 	}
+	uncleRewards := make([]*big.Int, len(uncles))
 	if config.HasECIP1017() {
 		// Ensure value 'era' is configured.
 		eraLen := config.ECIP1017EraRounds
@@ -585,29 +597,25 @@ func accumulateRewards(config *params.ChainConfig, state *state.StateDB, header
 		wr := GetBlockWinnerRewardByEra(era, blockReward)                    // wr "winner reward". 5, 4, 3.2, 2.56, ...
 		wurs := GetBlockWinnerRewardForUnclesByEra(era, uncles, blockReward) // wurs "winner uncle rewards"
 		wr.Add(wr, wurs)
-		state.AddBalance(header.Coinbase, wr) // $$
 
 		// Reward uncle miners.
-		for _, uncle := range uncles {
-			ur := GetBlockUncleRewardByEra(era, header, uncle, blockReward)
-			state.AddBalance(uncle.Coinbase, ur) // $$
-		}
-	} else {
-		// Accumulate the rewards for the miner and any included uncles
-		reward := new(big.Int).Set(blockReward)
-		r := new(big.Int)
-		for _, uncle := range uncles {
-			r.Add(uncle.Number, big8)
-			r.Sub(r, header.Number)
-			r.Mul(r, blockReward)
-			r.Div(r, big8)
-			state.AddBalance(uncle.Coinbase, r)
-
-			r.Div(blockReward, big32)
-			reward.Add(reward, r)
+		for i, uncle := range uncles {
+			uncleRewards[i] = GetBlockUncleRewardByEra(era, header, uncle, blockReward)
 		}
-		state.AddBalance(header.Coinbase, reward)
+		return wr, uncleRewards
+	}
+	// Accumulate the rewards for the miner and any included uncles
+	reward := new(big.Int).Set(blockReward)
+	for i, uncle := range uncles {
+		r := new(big.Int).Add(uncle.Number, big8)
+		r.Sub(r, header.Number)
+		r.Mul(r, blockReward)
+		r.Div(r, big8)
+		uncleRewards[i] = r
+
+		reward.Add(reward, new(big.Int).Div(blockReward, big32))
 	}
+	return reward, uncleRewards
 }
 
 // As of "Era 2" (zero-index era 1), uncle miners and winners are rewarded equally for each included block.
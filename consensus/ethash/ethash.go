@@ -50,7 +50,7 @@ var (
 	two256 = new(big.Int).Exp(big.NewInt(2), big.NewInt(256), big.NewInt(0))
 
 	// sharedEthash is a full instance that can be shared between multiple users.
-	sharedEthash = New(Config{"", 3, 0, "", 1, 0, ModeNormal}, nil, false)
+	sharedEthash = New(Config{"", 3, 0, "", 1, 0, ModeNormal, nil}, nil, false)
 
 	// algorithmRevision is the data structure version used for file naming.
 	algorithmRevision = 23
@@ -147,21 +147,33 @@ func memoryMapAndGenerate(path string, size uint64, generator func(buffer []uint
 	return memoryMap(path)
 }
 
+// epochKey identifies an epoch by its number along with the length it was counted
+// in, as the same number denotes different epochs before and after ECIP-1099.
+type epochKey struct {
+	epoch  uint64
+	length uint64
+}
+
+// start returns the first block number of the epoch.
+func (key epochKey) start() uint64 {
+	return key.epoch * key.length
+}
+
 // lru tracks caches or datasets by their last use time, keeping at most N of them.
 type lru struct {
 	what string
-	new  func(epoch uint64) interface{}
+	new  func(epoch uint64, epochLength uint64) interface{}
 	mu   sync.Mutex
 	// Items are kept in a LRU cache, but there is a special case:
 	// We always keep an item for (highest seen epoch) + 1 as the 'future item'.
 	cache      *simplelru.LRU
-	future     uint64
+	future     epochKey
 	futureItem interface{}
 }
 
 // newlru create a new least-recently-used cache for either the verification caches
 // or the mining datasets.
-func newlru(what string, maxItems int, new func(epoch uint64) interface{}) *lru {
+func newlru(what string, maxItems int, new func(epoch uint64, epochLength uint64) interface{}) *lru {
 	if maxItems <= 0 {
 		maxItems = 1
 	}
@@ -174,51 +186,82 @@ func newlru(what string, maxItems int, new func(epoch uint64) interface{}) *lru
 // get retrieves or creates an item for the given epoch. The first return value is always
 // non-nil. The second return value is non-nil if lru thinks that an item will be useful in
 // the near future.
-func (lru *lru) get(epoch uint64) (item, future interface{}) {
+func (lru *lru) get(epoch uint64, epochLength uint64, ecip1099FBlock *big.Int) (item, future interface{}) {
 	lru.mu.Lock()
 	defer lru.mu.Unlock()
 
 	// Get or create the item for the requested epoch.
-	item, ok := lru.cache.Get(epoch)
+	key := epochKey{epoch, epochLength}
+	item, ok := lru.cache.Get(key)
 	if !ok {
-		if lru.future > 0 && lru.future == epoch {
+		if lru.futureItem != nil && lru.future == key {
 			item = lru.futureItem
 		} else {
-			log.Trace("Requiring new ethash "+lru.what, "epoch", epoch)
-			item = lru.new(epoch)
+			log.Trace("Requiring new ethash "+lru.what, "epoch", epoch, "length", epochLength)
+			item = lru.new(epoch, epochLength)
 		}
-		lru.cache.Add(epoch, item)
+		lru.cache.Add(key, item)
 	}
-	// Update the 'future item' if epoch is larger than previously seen.
-	if epoch < maxEpoch-1 && lru.future < epoch+1 {
-		log.Trace("Requiring new future ethash "+lru.what, "epoch", epoch+1)
-		future = lru.new(epoch + 1)
-		lru.future = epoch + 1
+	// Update the 'future item' if epoch is larger than previously seen. The next
+	// epoch may already be counted in the ECIP-1099 length.
+	next := epochKey{length: calcEpochLength(key.start()+epochLength, ecip1099FBlock)}
+	next.epoch = calcEpoch(key.start()+epochLength, next.length)
+
+	if next.epoch < maxEpoch && lru.future.start() < next.start() {
+		log.Trace("Requiring new future ethash "+lru.what, "epoch", next.epoch, "length", next.length)
+		future = lru.new(next.epoch, next.length)
+		lru.future = next
 		lru.futureItem = future
 	}
 	return item, future
 }
 
+// dumpName returns the file name of a cache or dataset dump. The ECIP-1099 epochs
+// reuse the seeds of default length ones but differ in size, so their length is
+// made part of the name too.
+func dumpName(kind string, seed []byte, epochLength uint64, endian string) string {
+	if epochLength == epochLengthDefault {
+		return fmt.Sprintf("%s-R%d-%x%s", kind, algorithmRevision, seed[:8], endian)
+	}
+	return fmt.Sprintf("%s-R%d-E%d-%x%s", kind, algorithmRevision, epochLength, seed[:8], endian)
+}
+
+// removeDumps deletes the cache or dataset dumps of all epochs ending before the
+// last limit epochs up to and including the given one. The dumps are searched in
+// both the default and the ECIP-1099 epoch lengths, as the ones generated prior
+// to the transition are named after a different epoch numbering.
+func removeDumps(dir string, kind string, epoch uint64, epochLength uint64, limit int, endian string) {
+	// Retain every dump overlapping with the block range of the last limit epochs
+	keep := (int64(epoch) - int64(limit) + 1) * int64(epochLength)
+
+	for _, length := range []uint64{epochLengthDefault, epochLengthECIP1099} {
+		for ep := uint64(0); int64((ep+1)*length) <= keep; ep++ {
+			os.Remove(filepath.Join(dir, dumpName(kind, seedHash(ep, length), length, endian)))
+		}
+	}
+}
+
 // cache wraps an ethash cache with some metadata to allow easier concurrent use.
 type cache struct {
-	epoch uint64    // Epoch for which this cache is relevant
-	dump  *os.File  // File descriptor of the memory mapped cache
-	mmap  mmap.MMap // Memory map itself to unmap before releasing
-	cache []uint32  // The actual cache data content (may be memory mapped)
-	once  sync.Once // Ensures the cache is generated only once
+	epoch       uint64    // Epoch for which this cache is relevant
+	epochLength uint64    // Number of blocks in the epoch
+	dump        *os.File  // File descriptor of the memory mapped cache
+	mmap        mmap.MMap // Memory map itself to unmap before releasing
+	cache       []uint32  // The actual cache data content (may be memory mapped)
+	once        sync.Once // Ensures the cache is generated only once
 }
 
 // newCache creates a new ethash verification cache and returns it as a plain Go
 // interface to be usable in an LRU cache.
-func newCache(epoch uint64) interface{} {
-	return &cache{epoch: epoch}
+func newCache(epoch uint64, epochLength uint64) interface{} {
+	return &cache{epoch: epoch, epochLength: epochLength}
 }
 
 // generate ensures that the cache content is generated before use.
 func (c *cache) generate(dir string, limit int, test bool) {
 	c.once.Do(func() {
-		size := cacheSize(c.epoch*epochLength + 1)
-		seed := seedHash(c.epoch*epochLength + 1)
+		size := cacheSize(c.epoch)
+		seed := seedHash(c.epoch, c.epochLength)
 		if test {
 			size = 1024
 		}
@@ -233,8 +276,8 @@ func (c *cache) generate(dir string, limit int, test bool) {
 		if !isLittleEndian() {
 			endian = ".be"
 		}
-		path := filepath.Join(dir, fmt.Sprintf("cache-R%d-%x%s", algorithmRevision, seed[:8], endian))
-		logger := log.New("epoch", c.epoch)
+		path := filepath.Join(dir, dumpName("cache", seed, c.epochLength, endian))
+		logger := log.New("epoch", c.epoch, "length", c.epochLength)
 
 		// We're about to mmap the file, ensure that the mapping is cleaned up when the
 		// cache becomes unused.
@@ -258,11 +301,7 @@ func (c *cache) generate(dir string, limit int, test bool) {
 			generateCache(c.cache, c.epoch, seed)
 		}
 		// Iterate over all previous instances and delete old ones
-		for ep := int(c.epoch) - limit; ep >= 0; ep-- {
-			seed := seedHash(uint64(ep)*epochLength + 1)
-			path := filepath.Join(dir, fmt.Sprintf("cache-R%d-%x%s", algorithmRevision, seed[:8], endian))
-			os.Remove(path)
-		}
+		removeDumps(dir, "cache", c.epoch, c.epochLength, limit, endian)
 	})
 }
 
@@ -277,18 +316,19 @@ func (c *cache) finalizer() {
 
 // dataset wraps an ethash dataset with some metadata to allow easier concurrent use.
 type dataset struct {
-	epoch   uint64    // Epoch for which this cache is relevant
-	dump    *os.File  // File descriptor of the memory mapped cache
-	mmap    mmap.MMap // Memory map itself to unmap before releasing
-	dataset []uint32  // The actual cache data content
-	once    sync.Once // Ensures the cache is generated only once
-	done    uint32    // Atomic flag to determine generation status
+	epoch       uint64    // Epoch for which this cache is relevant
+	epochLength uint64    // Number of blocks in the epoch
+	dump        *os.File  // File descriptor of the memory mapped cache
+	mmap        mmap.MMap // Memory map itself to unmap before releasing
+	dataset     []uint32  // The actual cache data content
+	once        sync.Once // Ensures the cache is generated only once
+	done        uint32    // Atomic flag to determine generation status
 }
 
 // newDataset creates a new ethash mining dataset and returns it as a plain Go
 // interface to be usable in an LRU cache.
-func newDataset(epoch uint64) interface{} {
-	return &dataset{epoch: epoch}
+func newDataset(epoch uint64, epochLength uint64) interface{} {
+	return &dataset{epoch: epoch, epochLength: epochLength}
 }
 
 // generate ensures that the dataset content is generated before use.
@@ -297,9 +337,9 @@ func (d *dataset) generate(dir string, limit int, test bool) {
 		// Mark the dataset generated after we're done. This is needed for remote
 		defer atomic.StoreUint32(&d.done, 1)
 
-		csize := cacheSize(d.epoch*epochLength + 1)
-		dsize := datasetSize(d.epoch*epochLength + 1)
-		seed := seedHash(d.epoch*epochLength + 1)
+		csize := cacheSize(d.epoch)
+		dsize := datasetSize(d.epoch)
+		seed := seedHash(d.epoch, d.epochLength)
 		if test {
 			csize = 1024
 			dsize = 32 * 1024
@@ -319,8 +359,8 @@ func (d *dataset) generate(dir string, limit int, test bool) {
 		if !isLittleEndian() {
 			endian = ".be"
 		}
-		path := filepath.Join(dir, fmt.Sprintf("full-R%d-%x%s", algorithmRevision, seed[:8], endian))
-		logger := log.New("epoch", d.epoch)
+		path := filepath.Join(dir, dumpName("full", seed, d.epochLength, endian))
+		logger := log.New("epoch", d.epoch, "length", d.epochLength)
 
 		// We're about to mmap the file, ensure that the mapping is cleaned up when the
 		// cache becomes unused.
@@ -347,11 +387,7 @@ func (d *dataset) generate(dir string, limit int, test bool) {
 			generateDataset(d.dataset, d.epoch, cache)
 		}
 		// Iterate over all previous instances and delete old ones
-		for ep := int(d.epoch) - limit; ep >= 0; ep-- {
-			seed := seedHash(uint64(ep)*epochLength + 1)
-			path := filepath.Join(dir, fmt.Sprintf("full-R%d-%x%s", algorithmRevision, seed[:8], endian))
-			os.Remove(path)
-		}
+		removeDumps(dir, "full", d.epoch, d.epochLength, limit, endian)
 	})
 }
 
@@ -371,15 +407,19 @@ func (d *dataset) finalizer() {
 	}
 }
 
-// MakeCache generates a new ethash cache and optionally stores it to disk.
-func MakeCache(block uint64, dir string) {
-	c := cache{epoch: block / epochLength}
+// MakeCache generates a new ethash cache and optionally stores it to disk. The
+// epoch length is doubled for blocks past ecip1099FBlock, if set.
+func MakeCache(block uint64, ecip1099FBlock *big.Int, dir string) {
+	epochLength := calcEpochLength(block, ecip1099FBlock)
+	c := cache{epoch: calcEpoch(block, epochLength), epochLength: epochLength}
 	c.generate(dir, math.MaxInt32, false)
 }
 
-// MakeDataset generates a new ethash dataset and optionally stores it to disk.
-func MakeDataset(block uint64, dir string) {
-	d := dataset{epoch: block / epochLength}
+// MakeDataset generates a new ethash dataset and optionally stores it to disk. The
+// epoch length is doubled for blocks past ecip1099FBlock, if set.
+func MakeDataset(block uint64, ecip1099FBlock *big.Int, dir string) {
+	epochLength := calcEpochLength(block, ecip1099FBlock)
+	d := dataset{epoch: calcEpoch(block, epochLength), epochLength: epochLength}
 	d.generate(dir, math.MaxInt32, false)
 }
 
@@ -403,6 +443,10 @@ type Config struct {
 	DatasetsInMem  int
 	DatasetsOnDisk int
 	PowMode        Mode
+
+	// ECIP1099Block is the block from which epochs are twice as long, taken from
+	// the chain config rather than configured on its own.
+	ECIP1099Block *big.Int `toml:"-"`
 }
 
 // sealTask wraps a seal block with relative result channel for remote sealer thread.
@@ -588,8 +632,9 @@ func (ethash *Ethash) Close() error {
 // by first checking against a list of in-memory caches, then against caches
 // stored on disk, and finally generating one if none can be found.
 func (ethash *Ethash) cache(block uint64) *cache {
-	epoch := block / epochLength
-	currentI, futureI := ethash.caches.get(epoch)
+	epochLength := calcEpochLength(block, ethash.config.ECIP1099Block)
+	epoch := calcEpoch(block, epochLength)
+	currentI, futureI := ethash.caches.get(epoch, epochLength, ethash.config.ECIP1099Block)
 	current := currentI.(*cache)
 
 	// Wait for generation finish.
@@ -611,8 +656,9 @@ func (ethash *Ethash) cache(block uint64) *cache {
 // generates on a background thread.
 func (ethash *Ethash) dataset(block uint64, async bool) *dataset {
 	// Retrieve the requested ethash dataset
-	epoch := block / epochLength
-	currentI, futureI := ethash.datasets.get(epoch)
+	epochLength := calcEpochLength(block, ethash.config.ECIP1099Block)
+	epoch := calcEpoch(block, epochLength)
+	currentI, futureI := ethash.datasets.get(epoch, epochLength, ethash.config.ECIP1099Block)
 	current := currentI.(*dataset)
 
 	// If async is specified, generate everything in a background thread
@@ -707,11 +753,18 @@ func (ethash *Ethash) APIs(chain consensus.ChainReader) []rpc.API {
 			Service:   &API{ethash},
 			Public:    true,
 		},
+		{
+			Namespace: "ethash",
+			Version:   "1.0",
+			Service:   &IssuanceAPI{chain},
+			Public:    true,
+		},
 	}
 }
 
 // SeedHash is the seed to use for generating a verification cache and the mining
-// dataset.
-func SeedHash(block uint64) []byte {
-	return seedHash(block)
+// dataset. The epoch length is doubled for blocks past ecip1099FBlock, if set.
+func SeedHash(block uint64, ecip1099FBlock *big.Int) []byte {
+	epochLength := calcEpochLength(block, ecip1099FBlock)
+	return seedHash(calcEpoch(block, epochLength), epochLength)
 }
@@ -21,6 +21,7 @@ import (
 	"math/big"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"sync"
 	"testing"
 	"time"
@@ -78,10 +79,10 @@ func TestCacheFileEvict(t *testing.T) {
 func verifyTest(wg *sync.WaitGroup, e *Ethash, workerIndex, epochs int) {
 	defer wg.Done()
 
-	const wiggle = 4 * epochLength
+	const wiggle = 4 * epochLengthDefault
 	r := rand.New(rand.NewSource(int64(workerIndex)))
 	for epoch := 0; epoch < epochs; epoch++ {
-		block := int64(epoch)*epochLength - wiggle/2 + r.Int63n(wiggle)
+		block := int64(epoch)*epochLengthDefault - wiggle/2 + r.Int63n(wiggle)
 		if block < 0 {
 			block = 0
 		}
@@ -90,6 +91,67 @@ func verifyTest(wg *sync.WaitGroup, e *Ethash, workerIndex, epochs int) {
 	}
 }
 
+// Tests that the future item of the lru is counted in the ECIP-1099 epoch length
+// once the next epoch starts past the transition.
+func TestLRUFutureECIP1099(t *testing.T) {
+	transition := big.NewInt(60000)
+	caches := newlru("cache", 2, newCache)
+
+	_, future := caches.get(0, epochLengthDefault, transition)
+	if c := future.(*cache); c.epoch != 1 || c.epochLength != epochLengthDefault {
+		t.Errorf("future cache mismatch: have %d/%d, want %d/%d", c.epoch, c.epochLength, 1, epochLengthDefault)
+	}
+	_, future = caches.get(1, epochLengthDefault, transition)
+	if c := future.(*cache); c.epoch != 1 || c.epochLength != epochLengthECIP1099 {
+		t.Errorf("future cache mismatch: have %d/%d, want %d/%d", c.epoch, c.epochLength, 1, epochLengthECIP1099)
+	}
+	item, _ := caches.get(1, epochLengthECIP1099, transition)
+	if item != future {
+		t.Errorf("future cache not reused")
+	}
+}
+
+// Tests that the dumps generated prior to the ECIP-1099 transition are cleaned
+// up along with the post-transition ones once they fall out of the retained range.
+func TestRemoveDumpsECIP1099(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "ethash-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	path := func(epoch, length uint64) string {
+		return filepath.Join(tmpdir, dumpName("cache", seedHash(epoch, length), length, ""))
+	}
+	for epoch := uint64(0); epoch < 6; epoch++ {
+		for _, length := range []uint64{epochLengthDefault, epochLengthECIP1099} {
+			if err := ioutil.WriteFile(path(epoch, length), nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	// Retaining the two epochs up to the third doubled one keeps blocks 60000 on
+	removeDumps(tmpdir, "cache", 2, epochLengthECIP1099, 2, "")
+
+	tests := []struct {
+		epoch, length uint64
+		exist         bool
+	}{
+		{0, epochLengthDefault, false},
+		{1, epochLengthDefault, false},
+		{2, epochLengthDefault, true},
+		{5, epochLengthDefault, true},
+		{0, epochLengthECIP1099, false},
+		{1, epochLengthECIP1099, true},
+		{2, epochLengthECIP1099, true},
+	}
+	for _, tt := range tests {
+		if exist := common.FileExist(path(tt.epoch, tt.length)); exist != tt.exist {
+			t.Errorf("epoch %d/%d: existence mismatch: have %v, want %v", tt.epoch, tt.length, exist, tt.exist)
+		}
+	}
+}
+
 func TestRemoteSealer(t *testing.T) {
 	ethash := NewTester(nil, false)
 	defer ethash.Close()
@@ -0,0 +1,102 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethash
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// errInvalidRange is returned if the issuance of a block range ending before its
+// start is requested.
+var errInvalidRange = errors.New("invalid block range")
+
+// errIssuanceRange is returned if an issuance query over RPC spans more than
+// maxIssuanceRange blocks.
+var errIssuanceRange = fmt.Errorf("block range exceeds the maximum of %d blocks", maxIssuanceRange)
+
+// Issuance is the amount of ether minted by the block and uncle rewards of a range
+// of blocks, both ends included.
+type Issuance struct {
+	From   uint64   // First block of the range
+	To     uint64   // Last block of the range
+	Miner  *big.Int // Rewards credited to the coinbases of the blocks, uncle inclusion rewards included
+	Uncles *big.Int // Rewards credited to the coinbases of the uncles
+}
+
+// Total returns the total amount of ether minted within the block range.
+func (is *Issuance) Total() *big.Int {
+	return new(big.Int).Add(is.Miner, is.Uncles)
+}
+
+// CalcIssuance sums up the block and uncle rewards of a range of blocks of the
+// local chain, exactly as credited during block finalization. The genesis block
+// is never finalized, so it doesn't mint anything.
+func CalcIssuance(ctx context.Context, chain consensus.ChainReader, from, to uint64) (*Issuance, error) {
+	if from > to {
+		return nil, errInvalidRange
+	}
+	var (
+		config = chain.Config()
+		start  = time.Now()
+		logged = time.Now()
+	)
+	issuance := &Issuance{From: from, To: to, Miner: new(big.Int), Uncles: new(big.Int)}
+	for number := from; ; number++ {
+		if number > 0 {
+			header := chain.GetHeaderByNumber(number)
+			if header == nil {
+				return nil, fmt.Errorf("block #%d not found", number)
+			}
+			// Only retrieve the block body if there are any uncles to reward
+			var uncles []*types.Header
+			if header.UncleHash != types.EmptyUncleHash {
+				block := chain.GetBlock(header.Hash(), number)
+				if block == nil {
+					return nil, fmt.Errorf("block #%d body not found", number)
+				}
+				uncles = block.Uncles()
+			}
+			minerReward, uncleRewards := blockRewards(config, header, uncles)
+			issuance.Miner.Add(issuance.Miner, minerReward)
+			for _, reward := range uncleRewards {
+				issuance.Uncles.Add(issuance.Uncles, reward)
+			}
+		}
+		if number == to {
+			break
+		}
+		if number%1024 == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			if time.Since(logged) > 8*time.Second {
+				log.Info("Calculating issuance", "number", number, "to", to, "elapsed", common.PrettyDuration(time.Since(start)))
+				logged = time.Now()
+			}
+		}
+	}
+	return issuance, nil
+}
@@ -0,0 +1,151 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethash
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// testChain is a canonical chain of blocks implementing consensus.ChainReader.
+type testChain struct {
+	config *params.ChainConfig
+	blocks []*types.Block
+}
+
+// newTestChain creates a chain of the given length, including an uncle from the
+// previous height in each of the blocks listed.
+func newTestChain(config *params.ChainConfig, length int, withUncles ...int) *testChain {
+	chain := &testChain{config: config}
+
+	var parent common.Hash
+	for i := 0; i < length; i++ {
+		header := &types.Header{ParentHash: parent, Number: big.NewInt(int64(i)), Difficulty: big.NewInt(1)}
+
+		var uncles []*types.Header
+		for _, n := range withUncles {
+			if n == i {
+				uncles = append(uncles, &types.Header{Number: big.NewInt(int64(i - 1)), Coinbase: common.Address{0x01}})
+			}
+		}
+		block := types.NewBlock(header, nil, uncles, nil)
+		chain.blocks = append(chain.blocks, block)
+		parent = block.Hash()
+	}
+	return chain
+}
+
+func (c *testChain) Config() *params.ChainConfig { return c.config }
+func (c *testChain) CurrentHeader() *types.Header {
+	return c.blocks[len(c.blocks)-1].Header()
+}
+func (c *testChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if block := c.GetBlock(hash, number); block != nil {
+		return block.Header()
+	}
+	return nil
+}
+func (c *testChain) GetHeaderByNumber(number uint64) *types.Header {
+	if number < uint64(len(c.blocks)) {
+		return c.blocks[number].Header()
+	}
+	return nil
+}
+func (c *testChain) GetHeaderByHash(hash common.Hash) *types.Header {
+	for _, block := range c.blocks {
+		if block.Hash() == hash {
+			return block.Header()
+		}
+	}
+	return nil
+}
+func (c *testChain) GetBlock(hash common.Hash, number uint64) *types.Block {
+	if number < uint64(len(c.blocks)) && c.blocks[number].Hash() == hash {
+		return c.blocks[number]
+	}
+	return nil
+}
+
+// Tests that the issuance of block ranges matches the rewards credited by block
+// finalization, both with and without the ECIP-1017 era disinflation.
+func TestCalcIssuance(t *testing.T) {
+	szabo := func(n int64) *big.Int {
+		return new(big.Int).Mul(big.NewInt(n), big.NewInt(params.GWei*1000))
+	}
+	disinflated := &params.ChainConfig{ECIP1017EraRounds: big.NewInt(2), Ethash: new(params.EthashConfig)}
+
+	tests := []struct {
+		config   *params.ChainConfig
+		from, to uint64
+		miner    *big.Int
+		uncles   *big.Int
+	}{
+		// Genesis block doesn't mint anything
+		{params.TestChainConfig, 0, 0, szabo(0), szabo(0)},
+		// Block rewards only, the Constantinople ones on the test config
+		{params.TestChainConfig, 0, 1, szabo(2000000), szabo(0)},
+		// Uncle inclusion rewards the miner with 1/32 and the uncle with 7/8
+		{params.TestChainConfig, 2, 2, szabo(2062500), szabo(1750000)},
+		{params.TestChainConfig, 0, 3, szabo(6125000), szabo(3500000)},
+		// Era 2 rewards the miner with 4/5, and 1/32 of that to each side of an uncle
+		{disinflated, 1, 2, szabo(10156250), szabo(4375000)},
+		{disinflated, 3, 3, szabo(4125000), szabo(125000)},
+	}
+	for i, tt := range tests {
+		chain := newTestChain(tt.config, 4, 2, 3)
+
+		issuance, err := CalcIssuance(context.Background(), chain, tt.from, tt.to)
+		if err != nil {
+			t.Errorf("test %d: failed to calculate issuance: %v", i, err)
+			continue
+		}
+		if issuance.Miner.Cmp(tt.miner) != 0 || issuance.Uncles.Cmp(tt.uncles) != 0 {
+			t.Errorf("test %d: issuance mismatch: have %v/%v, want %v/%v", i, issuance.Miner, issuance.Uncles, tt.miner, tt.uncles)
+		}
+		if total := new(big.Int).Add(tt.miner, tt.uncles); issuance.Total().Cmp(total) != 0 {
+			t.Errorf("test %d: total mismatch: have %v, want %v", i, issuance.Total(), total)
+		}
+	}
+	chain := newTestChain(params.TestChainConfig, 4)
+	if _, err := CalcIssuance(context.Background(), chain, 2, 1); err != errInvalidRange {
+		t.Errorf("inverted range: error mismatch: have %v, want %v", err, errInvalidRange)
+	}
+	if _, err := CalcIssuance(context.Background(), chain, 2, 4); err == nil {
+		t.Errorf("missing block: issuance calculated")
+	}
+}
+
+// Tests that the RPC issuance query rejects ranges longer than maxIssuanceRange.
+func TestIssuanceAPIRange(t *testing.T) {
+	api := &IssuanceAPI{chain: newTestChain(params.TestChainConfig, maxIssuanceRange+1)}
+
+	if _, err := api.Issuance(context.Background(), 0, maxIssuanceRange-1); err != nil {
+		t.Errorf("maximum range rejected: %v", err)
+	}
+	if _, err := api.Issuance(context.Background(), 0, maxIssuanceRange); err != errIssuanceRange {
+		t.Errorf("oversized range: error mismatch: have %v, want %v", err, errIssuanceRange)
+	}
+	if _, err := api.Issuance(context.Background(), 0, rpc.LatestBlockNumber); err != errIssuanceRange {
+		t.Errorf("oversized range to latest: error mismatch: have %v, want %v", err, errIssuanceRange)
+	}
+}
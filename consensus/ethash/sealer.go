@@ -240,7 +240,7 @@ func (ethash *Ethash) remote(notify []string, noverify bool) {
 		hash := ethash.SealHash(block.Header())
 
 		currentWork[0] = hash.Hex()
-		currentWork[1] = common.BytesToHash(SeedHash(block.NumberU64())).Hex()
+		currentWork[1] = common.BytesToHash(SeedHash(block.NumberU64(), ethash.config.ECIP1099Block)).Hex()
 		currentWork[2] = common.BytesToHash(new(big.Int).Div(two256, block.Difficulty()).Bytes()).Hex()
 		currentWork[3] = hexutil.EncodeBig(block.Number())
 
@@ -1744,3 +1744,45 @@ func TestLowDiffLongChain(t *testing.T) {
 		header = chain.GetHeader(header.ParentHash, number-1)
 	}
 }
+
+// Tests that importing a chain with the state snapshot enabled yields the same
+// state as without it, and that the snapshot is persisted on shutdown.
+func TestSnapshotChainImport(t *testing.T) {
+	engine := ethash.NewFaker()
+
+	db := ethdb.NewMemDatabase()
+	genesis := new(Genesis).MustCommit(db)
+	blocks, _ := GenerateChain(params.TestChainConfig, genesis, engine, db, 2*triesInMemory, func(i int, b *BlockGen) {
+		b.SetCoinbase(common.Address{byte(i % 16)})
+	})
+	diskdb := ethdb.NewMemDatabase()
+	new(Genesis).MustCommit(diskdb)
+
+	chain, err := NewBlockChain(diskdb, &CacheConfig{TrieCleanLimit: 256, TrieDirtyLimit: 256, TrieTimeLimit: 5 * time.Minute, SnapshotLimit: 16}, params.TestChainConfig, engine, vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	head := chain.CurrentBlock()
+	if chain.snaps.Snapshot(head.Root()) == nil {
+		t.Fatalf("head snapshot missing")
+	}
+	snapdb, err := chain.StateAt(head.Root())
+	if err != nil {
+		t.Fatalf("failed to open snapshotted state: %v", err)
+	}
+	triedb, _ := state.New(head.Root(), chain.stateCache)
+	for i := 0; i < 16; i++ {
+		addr := common.Address{byte(i)}
+		if have, want := snapdb.GetBalance(addr), triedb.GetBalance(addr); have.Cmp(want) != 0 {
+			t.Errorf("account %x: balance mismatch: have %v, want %v", addr, have, want)
+		}
+	}
+	chain.Stop()
+
+	if root := rawdb.ReadSnapshotRoot(diskdb); root != head.Root() {
+		t.Errorf("persisted snapshot root mismatch: have %x, want %x", root, head.Root())
+	}
+}
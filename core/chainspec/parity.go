@@ -0,0 +1,887 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package chainspec converts between go-ethereum genesis specifications and the
+// chain specification formats of other Ethereum clients.
+package chainspec
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	gomath "math"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// never is the transition block Parity chainspecs use to disable a feature whose
+// transition would otherwise default to the genesis block.
+const never = Uint64(gomath.MaxInt64)
+
+// cliqueMaxExtraDataSize is the extra-data limit advertised for clique networks,
+// which carry the signer list and seal in the header extra-data.
+const cliqueMaxExtraDataSize = 0xffff
+
+// daoCanonBlock is the canonical block at the fork block of a network opposing
+// the DAO hard-fork.
+type daoCanonBlock struct {
+	number uint64
+	hash   common.Hash
+}
+
+// daoOpposedCanonBlocks are the canonical fork blocks of the known networks that
+// opposed the DAO hard-fork, keyed by their genesis hash. Parity has no notion of
+// opposing the fork, it instead pins the canonical chain at the fork block.
+var daoOpposedCanonBlocks = map[common.Hash]daoCanonBlock{
+	params.MainnetGenesisHash: {1920000, common.HexToHash("0x94365e3a8c0b35089c1d1195081fe7489b528a84b22199c916180db8b28ade7f")}, // Classic
+}
+
+// Uint64 is a block number or count in a Parity chainspec. Hand written specs
+// freely mix JSON numbers with hex and decimal strings, so all are accepted.
+type Uint64 uint64
+
+// MarshalText implements encoding.TextMarshaler.
+func (u Uint64) MarshalText() ([]byte, error) {
+	return math.HexOrDecimal64(u).MarshalText()
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (u *Uint64) UnmarshalText(input []byte) error {
+	return (*math.HexOrDecimal64)(u).UnmarshalText(input)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (u *Uint64) UnmarshalJSON(input []byte) error {
+	if len(input) > 0 && input[0] == '"' {
+		var text string
+		if err := json.Unmarshal(input, &text); err != nil {
+			return err
+		}
+		return u.UnmarshalText([]byte(text))
+	}
+	return json.Unmarshal(input, (*uint64)(u))
+}
+
+// block converts a transition into a fork block, nil meaning never.
+func (u *Uint64) block() *big.Int {
+	if u == nil || *u >= never {
+		return nil
+	}
+	return new(big.Int).SetUint64(uint64(*u))
+}
+
+// ParityBlockReward is the block reward schedule of the ethash engine, mapping
+// the block numbers of reward changes to the new rewards.
+type ParityBlockReward map[Uint64]*math.HexOrDecimal256
+
+// UnmarshalJSON implements json.Unmarshaler, accepting a single reward too.
+func (r *ParityBlockReward) UnmarshalJSON(input []byte) error {
+	if len(input) > 0 && input[0] == '"' {
+		reward := new(math.HexOrDecimal256)
+		if err := json.Unmarshal(input, reward); err != nil {
+			return err
+		}
+		*r = ParityBlockReward{0: reward}
+		return nil
+	}
+	return json.Unmarshal(input, (*map[Uint64]*math.HexOrDecimal256)(r))
+}
+
+// ParityChainSpec is the chain specification format used by Parity. Only the
+// subset of the format expressible as a go-ethereum genesis is supported.
+type ParityChainSpec struct {
+	Name    string       `json:"name"`
+	DataDir string       `json:"dataDir,omitempty"`
+	Engine  ParityEngine `json:"engine"`
+
+	Params   ParityParams                                `json:"params"`
+	Genesis  ParityGenesis                               `json:"genesis"`
+	Nodes    []string                                    `json:"nodes,omitempty"`
+	Accounts map[common.UnprefixedAddress]*ParityAccount `json:"accounts"`
+}
+
+// ParityEngine is the consensus engine definition, only one of which may be set.
+type ParityEngine struct {
+	Ethash *ParityEthash `json:"Ethash,omitempty"`
+	Clique *ParityClique `json:"clique,omitempty"`
+}
+
+// ParityEthash is the ethash engine definition.
+type ParityEthash struct {
+	Params ParityEthashParams `json:"params"`
+}
+
+// ParityClique is the clique engine definition.
+type ParityClique struct {
+	Params ParityCliqueParams `json:"params"`
+}
+
+// ParityEthashParams are the consensus parameters of the ethash engine.
+type ParityEthashParams struct {
+	MinimumDifficulty      *math.HexOrDecimal256 `json:"minimumDifficulty,omitempty"`
+	DifficultyBoundDivisor *math.HexOrDecimal256 `json:"difficultyBoundDivisor,omitempty"`
+	DurationLimit          *math.HexOrDecimal256 `json:"durationLimit,omitempty"`
+	BlockReward            ParityBlockReward     `json:"blockReward,omitempty"`
+	DifficultyBombDelays   map[Uint64]Uint64     `json:"difficultyBombDelays,omitempty"`
+
+	HomesteadTransition        *Uint64          `json:"homesteadTransition,omitempty"`
+	EIP100bTransition          *Uint64          `json:"eip100bTransition,omitempty"`
+	DAOHardforkTransition      *Uint64          `json:"daoHardforkTransition,omitempty"`
+	DAOHardforkBeneficiary     *common.Address  `json:"daoHardforkBeneficiary,omitempty"`
+	DAOHardforkAccounts        []common.Address `json:"daoHardforkAccounts,omitempty"`
+	BombDefuseTransition       *Uint64          `json:"bombDefuseTransition,omitempty"`
+	ECIP1010PauseTransition    *Uint64          `json:"ecip1010PauseTransition,omitempty"`
+	ECIP1010ContinueTransition *Uint64          `json:"ecip1010ContinueTransition,omitempty"`
+	ECIP1017EraRounds          *Uint64          `json:"ecip1017EraRounds,omitempty"`
+	ECIP1099Transition         *Uint64          `json:"ecip1099Transition,omitempty"`
+}
+
+// ParityCliqueParams are the consensus parameters of the clique engine.
+type ParityCliqueParams struct {
+	Period Uint64 `json:"period"`
+	Epoch  Uint64 `json:"epoch"`
+}
+
+// ParityParams are the engine independent chain parameters. Transitions left
+// out of a spec activate at genesis up to Spurious Dragon and never afterwards.
+type ParityParams struct {
+	AccountStartNonce     *Uint64 `json:"accountStartNonce,omitempty"`
+	MaximumExtraDataSize  Uint64  `json:"maximumExtraDataSize"`
+	MinGasLimit           Uint64  `json:"minGasLimit"`
+	GasLimitBoundDivisor  Uint64  `json:"gasLimitBoundDivisor"`
+	NetworkID             Uint64  `json:"networkID"`
+	ChainID               *Uint64 `json:"chainID,omitempty"`
+	MaxCodeSize           *Uint64 `json:"maxCodeSize,omitempty"`
+	MaxCodeSizeTransition *Uint64 `json:"maxCodeSizeTransition,omitempty"`
+
+	ForkBlock     *Uint64      `json:"forkBlock,omitempty"`
+	ForkCanonHash *common.Hash `json:"forkCanonHash,omitempty"`
+
+	EIP98Transition           *Uint64 `json:"eip98Transition,omitempty"`
+	EIP150Transition          *Uint64 `json:"eip150Transition,omitempty"`
+	EIP155Transition          *Uint64 `json:"eip155Transition,omitempty"`
+	EIP160Transition          *Uint64 `json:"eip160Transition,omitempty"`
+	EIP161abcTransition       *Uint64 `json:"eip161abcTransition,omitempty"`
+	EIP161dTransition         *Uint64 `json:"eip161dTransition,omitempty"`
+	EIP140Transition          *Uint64 `json:"eip140Transition,omitempty"`
+	EIP211Transition          *Uint64 `json:"eip211Transition,omitempty"`
+	EIP214Transition          *Uint64 `json:"eip214Transition,omitempty"`
+	EIP658Transition          *Uint64 `json:"eip658Transition,omitempty"`
+	EIP145Transition          *Uint64 `json:"eip145Transition,omitempty"`
+	EIP1014Transition         *Uint64 `json:"eip1014Transition,omitempty"`
+	EIP1052Transition         *Uint64 `json:"eip1052Transition,omitempty"`
+	EIP1283Transition         *Uint64 `json:"eip1283Transition,omitempty"`
+	EIP1283DisableTransition  *Uint64 `json:"eip1283DisableTransition,omitempty"`
+	EIP1283ReenableTransition *Uint64 `json:"eip1283ReenableTransition,omitempty"`
+	EIP1344Transition         *Uint64 `json:"eip1344Transition,omitempty"`
+	EIP1706Transition         *Uint64 `json:"eip1706Transition,omitempty"`
+	EIP1884Transition         *Uint64 `json:"eip1884Transition,omitempty"`
+	EIP2028Transition         *Uint64 `json:"eip2028Transition,omitempty"`
+}
+
+// ParityGenesis is the genesis block header of a Parity chainspec.
+type ParityGenesis struct {
+	Seal struct {
+		Ethereum struct {
+			Nonce   types.BlockNonce `json:"nonce"`
+			MixHash common.Hash      `json:"mixHash"`
+		} `json:"ethereum"`
+	} `json:"seal"`
+
+	Difficulty *math.HexOrDecimal256 `json:"difficulty"`
+	Author     common.Address        `json:"author"`
+	Timestamp  Uint64                `json:"timestamp"`
+	ParentHash common.Hash           `json:"parentHash"`
+	ExtraData  hexutil.Bytes         `json:"extraData"`
+	GasLimit   Uint64                `json:"gasLimit"`
+}
+
+// ParityAccount is a prefunded genesis account and/or precompiled contract.
+type ParityAccount struct {
+	Balance *math.HexOrDecimal256       `json:"balance,omitempty"`
+	Nonce   *Uint64                     `json:"nonce,omitempty"`
+	Code    hexutil.Bytes               `json:"code,omitempty"`
+	Storage map[common.Hash]common.Hash `json:"storage,omitempty"`
+	Builtin *ParityBuiltin              `json:"builtin,omitempty"`
+}
+
+// ParityBuiltin is a precompiled contract definition. Builtins are active from
+// genesis unless an activation block is given.
+type ParityBuiltin struct {
+	Name              string         `json:"name"`
+	ActivateAt        *Uint64        `json:"activate_at,omitempty"`
+	EIP1108Transition *Uint64        `json:"eip1108_transition,omitempty"`
+	Pricing           *ParityPricing `json:"pricing,omitempty"`
+}
+
+// ParityPricing represents the different pricing models that builtin contracts
+// might advertise using.
+type ParityPricing struct {
+	Linear               *ParityLinearPricing               `json:"linear,omitempty"`
+	ModExp               *ParityModExpPricing               `json:"modexp,omitempty"`
+	AltBnConstOperations *ParityAltBnConstOperationsPricing `json:"alt_bn128_const_operations,omitempty"`
+	AltBnPairing         *ParityAltBnPairingPricing         `json:"alt_bn128_pairing,omitempty"`
+	Blake2F              *ParityBlake2FPricing              `json:"blake2_f,omitempty"`
+}
+
+type ParityLinearPricing struct {
+	Base uint64 `json:"base"`
+	Word uint64 `json:"word"`
+}
+
+type ParityModExpPricing struct {
+	Divisor uint64 `json:"divisor"`
+}
+
+type ParityAltBnConstOperationsPricing struct {
+	Price                  uint64 `json:"price"`
+	EIP1108TransitionPrice uint64 `json:"eip1108_transition_price"`
+}
+
+type ParityAltBnPairingPricing struct {
+	Base                  uint64 `json:"base"`
+	Pair                  uint64 `json:"pair"`
+	EIP1108TransitionBase uint64 `json:"eip1108_transition_base,omitempty"`
+	EIP1108TransitionPair uint64 `json:"eip1108_transition_pair,omitempty"`
+}
+
+type ParityBlake2FPricing struct {
+	GasPerRound uint64 `json:"gas_per_round"`
+}
+
+// IsParityChainSpec reports whether the JSON blob looks like a Parity chainspec
+// rather than a go-ethereum genesis specification.
+func IsParityChainSpec(blob []byte) bool {
+	var probe struct {
+		Engine json.RawMessage `json:"engine"`
+		Config json.RawMessage `json:"config"`
+	}
+	if err := json.Unmarshal(blob, &probe); err != nil {
+		return false
+	}
+	return probe.Engine != nil && probe.Config == nil
+}
+
+// activation returns the earliest of the fork blocks enabling a feature, nil if
+// none of them are scheduled.
+func activation(blocks ...*big.Int) *big.Int {
+	var first *big.Int
+	for _, block := range blocks {
+		if block != nil && (first == nil || block.Cmp(first) < 0) {
+			first = block
+		}
+	}
+	return first
+}
+
+// transition converts the fork blocks enabling a feature into a transition that
+// defaults to never, nil if none of them are scheduled.
+func transition(blocks ...*big.Int) *Uint64 {
+	block := activation(blocks...)
+	if block == nil {
+		return nil
+	}
+	u := Uint64(block.Uint64())
+	return &u
+}
+
+// transitionOrNever converts the fork blocks enabling a feature into a transition
+// that defaults to genesis, explicitly disabling it if none are scheduled.
+func transitionOrNever(blocks ...*big.Int) *Uint64 {
+	if u := transition(blocks...); u != nil {
+		return u
+	}
+	u := never
+	return &u
+}
+
+// sameBlock reports whether two optional fork blocks are the same.
+func sameBlock(a, b *big.Int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Cmp(b) == 0
+}
+
+// NewParityChainSpec converts a go-ethereum genesis block into a Parity specific
+// chain specification format.
+func NewParityChainSpec(network string, genesis *core.Genesis, bootnodes []string) (*ParityChainSpec, error) {
+	config := genesis.Config
+	if config == nil {
+		return nil, errors.New("missing chain configuration")
+	}
+	// Reject the forks Parity has no notion of
+	switch {
+	case config.SocialBlock != nil:
+		return nil, errors.New("unsupported fork: Social")
+	case config.EthersocialBlock != nil:
+		return nil, errors.New("unsupported fork: Ethersocial")
+	case config.EWASMBlock != nil:
+		return nil, errors.New("unsupported fork: EWASM")
+	}
+	homestead := activation(config.HomesteadBlock, config.EIP2FBlock)
+	if !sameBlock(homestead, activation(config.HomesteadBlock, config.EIP7FBlock)) {
+		return nil, errors.New("EIP2 and EIP7 must activate together")
+	}
+	spec := &ParityChainSpec{
+		Name:    network,
+		DataDir: strings.ToLower(network),
+		Nodes:   bootnodes,
+	}
+	// Assemble the consensus engine parameters
+	switch {
+	case config.Ethash != nil:
+		spec.Engine.Ethash = new(ParityEthash)
+		engine := &spec.Engine.Ethash.Params
+
+		engine.MinimumDifficulty = (*math.HexOrDecimal256)(params.MinimumDifficulty)
+		engine.DifficultyBoundDivisor = (*math.HexOrDecimal256)(params.DifficultyBoundDivisor)
+		engine.DurationLimit = (*math.HexOrDecimal256)(params.DurationLimit)
+		engine.HomesteadTransition = transitionOrNever(homestead)
+		engine.EIP100bTransition = transition(config.ByzantiumBlock, config.ConstantinopleBlock, config.EIP100FBlock)
+
+		engine.BlockReward = ParityBlockReward{0: (*math.HexOrDecimal256)(ethash.FrontierBlockReward)}
+		engine.DifficultyBombDelays = make(map[Uint64]Uint64)
+		if block := transition(config.ByzantiumBlock, config.EIP649FBlock); block != nil {
+			engine.BlockReward[*block] = (*math.HexOrDecimal256)(ethash.EIP649FBlockReward)
+			engine.DifficultyBombDelays[*block] += 3000000
+		}
+		if block := transition(config.ConstantinopleBlock, config.EIP1234FBlock); block != nil {
+			engine.BlockReward[*block] = (*math.HexOrDecimal256)(ethash.EIP1234FBlockReward)
+			engine.DifficultyBombDelays[*block] += 2000000
+		}
+		if config.DAOForkSupport && config.DAOForkBlock != nil {
+			engine.DAOHardforkTransition = transition(config.DAOForkBlock)
+			engine.DAOHardforkBeneficiary = &params.DAORefundContract
+			engine.DAOHardforkAccounts = params.DAODrainList()
+		}
+		engine.BombDefuseTransition = transition(config.DisposalBlock)
+		if config.ECIP1010PauseBlock != nil {
+			if config.ECIP1010Length == nil {
+				return nil, errors.New("ECIP1010 pause without length")
+			}
+			engine.ECIP1010PauseTransition = transition(config.ECIP1010PauseBlock)
+			engine.ECIP1010ContinueTransition = transition(new(big.Int).Add(config.ECIP1010PauseBlock, config.ECIP1010Length))
+		}
+		engine.ECIP1017EraRounds = transition(config.ECIP1017EraRounds)
+		engine.ECIP1099Transition = transition(config.ECIP1099FBlock)
+
+		spec.Params.MaximumExtraDataSize = Uint64(params.MaximumExtraDataSize)
+
+	case config.Clique != nil:
+		// Parity runs clique networks under the Homestead rules from genesis
+		if homestead == nil || homestead.Uint64() > 1 {
+			return nil, errors.New("clique networks must activate Homestead at genesis")
+		}
+		if config.DAOForkSupport && config.DAOForkBlock != nil {
+			return nil, errors.New("DAO hard-fork unsupported on clique")
+		}
+		// The ethash specific rewards and difficulty bomb are meaningless here
+		spec.Engine.Clique = new(ParityClique)
+		spec.Engine.Clique.Params.Period = Uint64(config.Clique.Period)
+		spec.Engine.Clique.Params.Epoch = Uint64(config.Clique.Epoch)
+
+		spec.Params.MaximumExtraDataSize = cliqueMaxExtraDataSize
+
+	default:
+		return nil, errors.New("unsupported consensus engine")
+	}
+	// Assemble the engine independent chain parameters
+	var (
+		chainID      = Uint64(config.ChainID.Uint64())
+		startNonce   = Uint64(0)
+		petersburg   = config.PetersburgBlock
+		maxCodeSize  = Uint64(params.MaxCodeSize)
+		eip98Disable = never
+	)
+	if petersburg == nil {
+		petersburg = config.ConstantinopleBlock
+	}
+	spec.Params.AccountStartNonce = &startNonce
+	spec.Params.MinGasLimit = Uint64(params.MinGasLimit)
+	spec.Params.GasLimitBoundDivisor = Uint64(params.GasLimitBoundDivisor)
+	spec.Params.NetworkID = chainID
+	spec.Params.ChainID = &chainID
+	if config.DAOForkBlock != nil && !config.DAOForkSupport {
+		hash, err := daoForkCanonHash(genesis, config.DAOForkBlock)
+		if err != nil {
+			return nil, err
+		}
+		spec.Params.ForkBlock = transition(config.DAOForkBlock)
+		spec.Params.ForkCanonHash = &hash
+	}
+	if block := transition(config.EIP158Block, config.EIP170FBlock); block != nil {
+		spec.Params.MaxCodeSize = &maxCodeSize
+		spec.Params.MaxCodeSizeTransition = block
+	}
+	spec.Params.EIP98Transition = &eip98Disable
+
+	// Tangerine Whistle and Spurious Dragon
+	spec.Params.EIP150Transition = transitionOrNever(config.EIP150Block)
+	spec.Params.EIP155Transition = transitionOrNever(config.EIP155Block)
+	spec.Params.EIP160Transition = transitionOrNever(config.EIP158Block, config.EIP160FBlock)
+	spec.Params.EIP161abcTransition = transitionOrNever(config.EIP158Block, config.EIP161FBlock)
+	spec.Params.EIP161dTransition = transitionOrNever(config.EIP158Block, config.EIP161FBlock)
+
+	// Byzantium
+	spec.Params.EIP140Transition = transition(config.ByzantiumBlock, config.EIP140FBlock)
+	spec.Params.EIP211Transition = transition(config.ByzantiumBlock, config.EIP211FBlock)
+	spec.Params.EIP214Transition = transition(config.ByzantiumBlock, config.EIP214FBlock)
+	spec.Params.EIP658Transition = transition(config.ByzantiumBlock, config.EIP658FBlock)
+
+	// Constantinople and Petersburg
+	spec.Params.EIP145Transition = transition(config.ConstantinopleBlock, config.EIP145FBlock)
+	spec.Params.EIP1014Transition = transition(config.ConstantinopleBlock, config.EIP1014FBlock)
+	spec.Params.EIP1052Transition = transition(config.ConstantinopleBlock, config.EIP1052FBlock)
+	spec.Params.EIP1283Transition = transition(config.ConstantinopleBlock, config.EIP1283FBlock)
+	spec.Params.EIP1283DisableTransition = transition(petersburg)
+
+	// Istanbul, where EIP2200 is EIP1283 reenabled with the EIP1706 sentry
+	spec.Params.EIP1283ReenableTransition = transition(config.IstanbulBlock, config.EIP2200FBlock)
+	spec.Params.EIP1706Transition = transition(config.IstanbulBlock, config.EIP2200FBlock)
+	spec.Params.EIP1344Transition = transition(config.IstanbulBlock, config.EIP1344FBlock)
+	spec.Params.EIP1884Transition = transition(config.IstanbulBlock, config.EIP1884FBlock)
+	spec.Params.EIP2028Transition = transition(config.IstanbulBlock, config.EIP2028FBlock)
+
+	// Assemble the genesis header and allocations
+	spec.Genesis.Seal.Ethereum.Nonce = types.EncodeNonce(genesis.Nonce)
+	spec.Genesis.Seal.Ethereum.MixHash = genesis.Mixhash
+	spec.Genesis.Difficulty = (*math.HexOrDecimal256)(genesis.Difficulty)
+	spec.Genesis.Author = genesis.Coinbase
+	spec.Genesis.Timestamp = Uint64(genesis.Timestamp)
+	spec.Genesis.ParentHash = genesis.ParentHash
+	spec.Genesis.ExtraData = genesis.ExtraData
+	spec.Genesis.GasLimit = Uint64(genesis.GasLimit)
+
+	spec.Accounts = make(map[common.UnprefixedAddress]*ParityAccount)
+	for address, account := range genesis.Alloc {
+		acc := &ParityAccount{
+			Balance: (*math.HexOrDecimal256)(new(big.Int)),
+			Code:    account.Code,
+		}
+		if account.Balance != nil {
+			acc.Balance = (*math.HexOrDecimal256)(account.Balance)
+		}
+		if account.Nonce != 0 {
+			nonce := Uint64(account.Nonce)
+			acc.Nonce = &nonce
+		}
+		if len(account.Storage) > 0 {
+			acc.Storage = account.Storage
+		}
+		spec.Accounts[common.UnprefixedAddress(address)] = acc
+	}
+	spec.setBuiltins(config)
+
+	return spec, nil
+}
+
+// setBuiltins defines the precompiled contracts enabled by the chain config.
+func (spec *ParityChainSpec) setBuiltins(config *params.ChainConfig) {
+	spec.setPrecompile(1, &ParityBuiltin{
+		Name: "ecrecover", Pricing: &ParityPricing{Linear: &ParityLinearPricing{Base: params.EcrecoverGas}},
+	})
+	spec.setPrecompile(2, &ParityBuiltin{
+		Name: "sha256", Pricing: &ParityPricing{Linear: &ParityLinearPricing{Base: params.Sha256BaseGas, Word: params.Sha256PerWordGas}},
+	})
+	spec.setPrecompile(3, &ParityBuiltin{
+		Name: "ripemd160", Pricing: &ParityPricing{Linear: &ParityLinearPricing{Base: params.Ripemd160BaseGas, Word: params.Ripemd160PerWordGas}},
+	})
+	spec.setPrecompile(4, &ParityBuiltin{
+		Name: "identity", Pricing: &ParityPricing{Linear: &ParityLinearPricing{Base: params.IdentityBaseGas, Word: params.IdentityPerWordGas}},
+	})
+	if block := transition(config.ByzantiumBlock, config.EIP198FBlock); block != nil {
+		spec.setPrecompile(5, &ParityBuiltin{
+			Name: "modexp", ActivateAt: block, Pricing: &ParityPricing{ModExp: &ParityModExpPricing{Divisor: params.ModExpQuadCoeffDiv}},
+		})
+	}
+	eip1108 := transition(config.IstanbulBlock, config.EIP1108FBlock)
+	if block := transition(config.ByzantiumBlock, config.EIP213FBlock); block != nil {
+		add := &ParityBuiltin{Name: "alt_bn128_add", ActivateAt: block, EIP1108Transition: eip1108}
+		mul := &ParityBuiltin{Name: "alt_bn128_mul", ActivateAt: block, EIP1108Transition: eip1108}
+		if eip1108 == nil {
+			add.Pricing = &ParityPricing{Linear: &ParityLinearPricing{Base: params.Bn256AddGas}}
+			mul.Pricing = &ParityPricing{Linear: &ParityLinearPricing{Base: params.Bn256ScalarMulGas}}
+		} else {
+			add.Pricing = &ParityPricing{AltBnConstOperations: &ParityAltBnConstOperationsPricing{
+				Price: params.Bn256AddGas, EIP1108TransitionPrice: params.Bn256AddGasEIP1108,
+			}}
+			mul.Pricing = &ParityPricing{AltBnConstOperations: &ParityAltBnConstOperationsPricing{
+				Price: params.Bn256ScalarMulGas, EIP1108TransitionPrice: params.Bn256ScalarMulGasEIP1108,
+			}}
+		}
+		spec.setPrecompile(6, add)
+		spec.setPrecompile(7, mul)
+	}
+	if block := transition(config.ByzantiumBlock, config.EIP212FBlock); block != nil {
+		pricing := &ParityAltBnPairingPricing{Base: params.Bn256PairingBaseGas, Pair: params.Bn256PairingPerPointGas}
+		if eip1108 != nil {
+			pricing.EIP1108TransitionBase = params.Bn256PairingBaseGasEIP1108
+			pricing.EIP1108TransitionPair = params.Bn256PairingPerPointGasEIP1108
+		}
+		spec.setPrecompile(8, &ParityBuiltin{
+			Name: "alt_bn128_pairing", ActivateAt: block, EIP1108Transition: eip1108, Pricing: &ParityPricing{AltBnPairing: pricing},
+		})
+	}
+	if block := transition(config.IstanbulBlock, config.EIP152FBlock); block != nil {
+		spec.setPrecompile(9, &ParityBuiltin{
+			Name: "blake2_f", ActivateAt: block, Pricing: &ParityPricing{Blake2F: &ParityBlake2FPricing{GasPerRound: params.Blake2FRoundGas}},
+		})
+	}
+}
+
+func (spec *ParityChainSpec) setPrecompile(address byte, data *ParityBuiltin) {
+	a := common.UnprefixedAddress(common.BytesToAddress([]byte{address}))
+	if _, exist := spec.Accounts[a]; !exist {
+		spec.Accounts[a] = new(ParityAccount)
+	}
+	spec.Accounts[a].Builtin = data
+}
+
+// ToGenesis converts a Parity chainspec into a go-ethereum genesis block. The per
+// EIP transitions are mapped onto the EIP*F fork blocks, which are folded back
+// into their hard fork blocks whenever all of them activate together.
+func (spec *ParityChainSpec) ToGenesis() (*core.Genesis, error) {
+	config := new(params.ChainConfig)
+
+	// Convert the consensus engine parameters
+	switch {
+	case spec.Engine.Ethash != nil:
+		if err := spec.Engine.Ethash.Params.apply(config); err != nil {
+			return nil, err
+		}
+		config.Ethash = new(params.EthashConfig)
+
+	case spec.Engine.Clique != nil:
+		config.HomesteadBlock = new(big.Int)
+		config.Clique = &params.CliqueConfig{
+			Period: uint64(spec.Engine.Clique.Params.Period),
+			Epoch:  uint64(spec.Engine.Clique.Params.Epoch),
+		}
+
+	default:
+		return nil, errors.New("unsupported consensus engine")
+	}
+	// Convert the engine independent chain parameters
+	if err := spec.Params.apply(config); err != nil {
+		return nil, err
+	}
+	for address, account := range spec.Accounts {
+		if account.Builtin == nil {
+			continue
+		}
+		if err := account.Builtin.apply(config); err != nil {
+			return nil, fmt.Errorf("builtin %x: %v", common.Address(address), err)
+		}
+	}
+	collapseForks(config)
+
+	// Convert the genesis header and allocations
+	genesis := &core.Genesis{
+		Config:     config,
+		Nonce:      spec.Genesis.Seal.Ethereum.Nonce.Uint64(),
+		Timestamp:  uint64(spec.Genesis.Timestamp),
+		ExtraData:  spec.Genesis.ExtraData,
+		GasLimit:   uint64(spec.Genesis.GasLimit),
+		Difficulty: (*big.Int)(spec.Genesis.Difficulty),
+		Mixhash:    spec.Genesis.Seal.Ethereum.MixHash,
+		Coinbase:   spec.Genesis.Author,
+		ParentHash: spec.Genesis.ParentHash,
+		Alloc:      make(core.GenesisAlloc),
+	}
+	if genesis.Difficulty == nil {
+		return nil, errors.New("missing genesis difficulty")
+	}
+	for address, account := range spec.Accounts {
+		// Skip builtins without state, they mustn't end up in the genesis state
+		if account.Balance == nil && account.Nonce == nil && account.Code == nil && account.Storage == nil {
+			continue
+		}
+		alloc := core.GenesisAccount{
+			Balance: new(big.Int),
+			Code:    account.Code,
+			Storage: account.Storage,
+		}
+		if account.Balance != nil {
+			alloc.Balance = (*big.Int)(account.Balance)
+		}
+		if account.Nonce != nil {
+			alloc.Nonce = uint64(*account.Nonce)
+		}
+		genesis.Alloc[common.Address(address)] = alloc
+	}
+	return genesis, nil
+}
+
+// apply converts the ethash engine parameters into the chain config.
+func (p *ParityEthashParams) apply(config *params.ChainConfig) error {
+	for _, check := range []struct {
+		name       string
+		have, want *big.Int
+	}{
+		{"minimumDifficulty", (*big.Int)(p.MinimumDifficulty), params.MinimumDifficulty},
+		{"difficultyBoundDivisor", (*big.Int)(p.DifficultyBoundDivisor), params.DifficultyBoundDivisor},
+		{"durationLimit", (*big.Int)(p.DurationLimit), params.DurationLimit},
+	} {
+		if check.have != nil && check.have.Cmp(check.want) != 0 {
+			return fmt.Errorf("unsupported %s %v, want %v", check.name, check.have, check.want)
+		}
+	}
+	config.HomesteadBlock = new(big.Int)
+	if p.HomesteadTransition != nil {
+		config.HomesteadBlock = p.HomesteadTransition.block()
+	}
+	config.EIP100FBlock = p.EIP100bTransition.block()
+
+	// Derive the reward reductions from the reward schedule, a straight drop to the
+	// Constantinople reward implying the Byzantium reduction too
+	blocks := make([]Uint64, 0, len(p.BlockReward))
+	for block := range p.BlockReward {
+		blocks = append(blocks, block)
+	}
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i] < blocks[j] })
+
+	for _, block := range blocks {
+		switch reward := (*big.Int)(p.BlockReward[block]); {
+		case reward.Cmp(ethash.FrontierBlockReward) == 0 && config.EIP649FBlock == nil:
+		case reward.Cmp(ethash.EIP649FBlockReward) == 0 && config.EIP649FBlock == nil:
+			config.EIP649FBlock = block.block()
+		case reward.Cmp(ethash.EIP1234FBlockReward) == 0 && config.EIP1234FBlock == nil:
+			if config.EIP649FBlock == nil {
+				config.EIP649FBlock = block.block()
+			}
+			config.EIP1234FBlock = block.block()
+		default:
+			return fmt.Errorf("unsupported block reward %v at block %d", reward, block)
+		}
+	}
+	// The difficulty bomb delays are implied by the reward reductions
+	if len(p.DifficultyBombDelays) > 0 {
+		delays := make(map[Uint64]Uint64)
+		if config.EIP649FBlock != nil {
+			delays[Uint64(config.EIP649FBlock.Uint64())] += 3000000
+		}
+		if config.EIP1234FBlock != nil {
+			delays[Uint64(config.EIP1234FBlock.Uint64())] += 2000000
+		}
+		if len(delays) != len(p.DifficultyBombDelays) {
+			return errors.New("difficulty bomb delays mismatch block rewards")
+		}
+		for block, delay := range p.DifficultyBombDelays {
+			if delays[block] != delay {
+				return errors.New("difficulty bomb delays mismatch block rewards")
+			}
+		}
+	}
+	if block := p.DAOHardforkTransition.block(); block != nil {
+		if p.DAOHardforkBeneficiary != nil && *p.DAOHardforkBeneficiary != params.DAORefundContract {
+			return fmt.Errorf("unsupported DAO hard-fork beneficiary %x", *p.DAOHardforkBeneficiary)
+		}
+		config.DAOForkBlock, config.DAOForkSupport = block, true
+	}
+	config.DisposalBlock = p.BombDefuseTransition.block()
+
+	pause, resume := p.ECIP1010PauseTransition.block(), p.ECIP1010ContinueTransition.block()
+	if (pause == nil) != (resume == nil) || (pause != nil && resume.Cmp(pause) < 0) {
+		return errors.New("unsupported ECIP1010 transitions")
+	}
+	if pause != nil {
+		config.ECIP1010PauseBlock, config.ECIP1010Length = pause, new(big.Int).Sub(resume, pause)
+	}
+	config.ECIP1017EraRounds = p.ECIP1017EraRounds.block()
+	config.ECIP1099FBlock = p.ECIP1099Transition.block()
+
+	return nil
+}
+
+// apply converts the engine independent chain parameters into the chain config.
+// The consensus engine must already be set.
+func (p *ParityParams) apply(config *params.ChainConfig) error {
+	if p.AccountStartNonce != nil && *p.AccountStartNonce != 0 {
+		return fmt.Errorf("unsupported account start nonce %d", *p.AccountStartNonce)
+	}
+	if p.MinGasLimit != Uint64(params.MinGasLimit) {
+		return fmt.Errorf("unsupported min gas limit %d, want %d", p.MinGasLimit, params.MinGasLimit)
+	}
+	if p.GasLimitBoundDivisor != Uint64(params.GasLimitBoundDivisor) {
+		return fmt.Errorf("unsupported gas limit bound divisor %d, want %d", p.GasLimitBoundDivisor, params.GasLimitBoundDivisor)
+	}
+	// Clique headers carry the vanity, signer list and seal in the extra-data,
+	// which isn't capped, so only the limit advertised for clique is accepted
+	maxExtra := Uint64(params.MaximumExtraDataSize)
+	if config.Clique != nil {
+		maxExtra = cliqueMaxExtraDataSize
+	}
+	if p.MaximumExtraDataSize != maxExtra {
+		return fmt.Errorf("unsupported maximum extra-data size %d, want %d", p.MaximumExtraDataSize, maxExtra)
+	}
+	// A fork block pinned without a DAO hard-fork is the opposed DAO fork
+	if p.ForkBlock != nil && config.DAOForkBlock == nil {
+		config.DAOForkBlock, config.DAOForkSupport = p.ForkBlock.block(), false
+	}
+	config.ChainID = new(big.Int).SetUint64(uint64(p.NetworkID))
+	if p.ChainID != nil {
+		config.ChainID = new(big.Int).SetUint64(uint64(*p.ChainID))
+	}
+	if p.MaxCodeSize != nil {
+		if *p.MaxCodeSize != params.MaxCodeSize {
+			return fmt.Errorf("unsupported max code size %d, want %d", *p.MaxCodeSize, params.MaxCodeSize)
+		}
+		config.EIP170FBlock = genesisDefault(p.MaxCodeSizeTransition)
+	}
+	// Tangerine Whistle and Spurious Dragon
+	config.EIP150Block = genesisDefault(p.EIP150Transition)
+	config.EIP155Block = genesisDefault(p.EIP155Transition)
+	config.EIP160FBlock = genesisDefault(p.EIP160Transition)
+	config.EIP161FBlock = genesisDefault(p.EIP161abcTransition)
+	if !sameBlock(config.EIP161FBlock, genesisDefault(p.EIP161dTransition)) {
+		return errors.New("EIP161abc and EIP161d must activate together")
+	}
+	// Byzantium
+	config.EIP140FBlock = p.EIP140Transition.block()
+	config.EIP211FBlock = p.EIP211Transition.block()
+	config.EIP214FBlock = p.EIP214Transition.block()
+	config.EIP658FBlock = p.EIP658Transition.block()
+
+	// Constantinople and Petersburg
+	config.EIP145FBlock = p.EIP145Transition.block()
+	config.EIP1014FBlock = p.EIP1014Transition.block()
+	config.EIP1052FBlock = p.EIP1052Transition.block()
+	config.EIP1283FBlock = p.EIP1283Transition.block()
+	config.PetersburgBlock = p.EIP1283DisableTransition.block()
+
+	// Istanbul
+	config.EIP2200FBlock = p.EIP1283ReenableTransition.block()
+	if !sameBlock(config.EIP2200FBlock, p.EIP1706Transition.block()) {
+		return errors.New("EIP1283 reenable and EIP1706 must activate together")
+	}
+	config.EIP1344FBlock = p.EIP1344Transition.block()
+	config.EIP1884FBlock = p.EIP1884Transition.block()
+	config.EIP2028FBlock = p.EIP2028Transition.block()
+
+	return nil
+}
+
+// apply converts the activation of a precompiled contract into the chain config.
+func (b *ParityBuiltin) apply(config *params.ChainConfig) error {
+	block := new(big.Int)
+	if b.ActivateAt != nil {
+		block = b.ActivateAt.block()
+	}
+	switch b.Name {
+	case "ecrecover", "sha256", "ripemd160", "identity":
+		if block == nil || block.Sign() != 0 {
+			return fmt.Errorf("%s must be active from genesis", b.Name)
+		}
+	case "modexp":
+		config.EIP198FBlock = block
+	case "alt_bn128_add", "alt_bn128_mul":
+		if config.EIP213FBlock != nil && !sameBlock(config.EIP213FBlock, block) {
+			return errors.New("alt_bn128_add and alt_bn128_mul must activate together")
+		}
+		config.EIP213FBlock = block
+	case "alt_bn128_pairing":
+		config.EIP212FBlock = block
+	case "blake2_f":
+		config.EIP152FBlock = block
+	default:
+		return fmt.Errorf("unsupported builtin %q", b.Name)
+	}
+	if eip1108 := b.EIP1108Transition.block(); eip1108 != nil {
+		if config.EIP1108FBlock != nil && !sameBlock(config.EIP1108FBlock, eip1108) {
+			return errors.New("alt_bn128 repricing must activate together")
+		}
+		config.EIP1108FBlock = eip1108
+	}
+	return nil
+}
+
+// daoForkCanonHash returns the hash of the canonical block at the fork block of
+// a network opposing the DAO hard-fork.
+func daoForkCanonHash(genesis *core.Genesis, block *big.Int) (common.Hash, error) {
+	hash := genesis.ToBlock(nil).Hash()
+	if block.Sign() == 0 {
+		return hash, nil
+	}
+	if canon, ok := daoOpposedCanonBlocks[hash]; ok && canon.number == block.Uint64() {
+		return canon.hash, nil
+	}
+	return common.Hash{}, fmt.Errorf("unknown canonical block at opposed DAO hard-fork block %v", block)
+}
+
+// genesisDefault converts a transition defaulting to the genesis block into a
+// fork block, nil meaning never.
+func genesisDefault(u *Uint64) *big.Int {
+	if u == nil {
+		return new(big.Int)
+	}
+	return u.block()
+}
+
+// together returns the block at which all the given features activate, nil if
+// any of them is unscheduled or they activate at different blocks.
+func together(blocks ...*big.Int) *big.Int {
+	for _, block := range blocks {
+		if block == nil || block.Cmp(blocks[0]) != 0 {
+			return nil
+		}
+	}
+	return blocks[0]
+}
+
+// collapseForks folds the per EIP fork blocks of the hard forks into the hard
+// fork blocks themselves if all of them activate together. Features specific to
+// ethash are only considered on ethash networks.
+func collapseForks(config *params.ChainConfig) {
+	c := config
+
+	if block := together(c.EIP160FBlock, c.EIP161FBlock, c.EIP170FBlock); block != nil {
+		c.EIP158Block = block
+		c.EIP160FBlock, c.EIP161FBlock, c.EIP170FBlock = nil, nil, nil
+	}
+	byzantium := []*big.Int{c.EIP140FBlock, c.EIP198FBlock, c.EIP211FBlock, c.EIP212FBlock, c.EIP213FBlock, c.EIP214FBlock, c.EIP658FBlock}
+	if c.Ethash != nil {
+		byzantium = append(byzantium, c.EIP100FBlock, c.EIP649FBlock)
+	}
+	if block := together(byzantium...); block != nil {
+		c.ByzantiumBlock = block
+		c.EIP100FBlock, c.EIP140FBlock, c.EIP198FBlock, c.EIP211FBlock, c.EIP212FBlock = nil, nil, nil, nil, nil
+		c.EIP213FBlock, c.EIP214FBlock, c.EIP649FBlock, c.EIP658FBlock = nil, nil, nil, nil
+	}
+	// Constantinople implies EIP100 and, without an explicit Petersburg block, the
+	// immediate disabling of EIP1283
+	constantinople := []*big.Int{c.EIP145FBlock, c.EIP1014FBlock, c.EIP1052FBlock, c.EIP1283FBlock}
+	if c.Ethash != nil {
+		constantinople = append(constantinople, c.EIP1234FBlock)
+	}
+	if block := together(constantinople...); block != nil && c.PetersburgBlock != nil && (c.Ethash == nil || c.IsEIP100F(block)) {
+		c.ConstantinopleBlock = block
+		c.EIP145FBlock, c.EIP1014FBlock, c.EIP1052FBlock, c.EIP1234FBlock, c.EIP1283FBlock = nil, nil, nil, nil, nil
+	}
+	if block := together(c.EIP152FBlock, c.EIP1108FBlock, c.EIP1344FBlock, c.EIP1884FBlock, c.EIP2028FBlock, c.EIP2200FBlock); block != nil {
+		c.IstanbulBlock = block
+		c.EIP152FBlock, c.EIP1108FBlock, c.EIP1344FBlock, c.EIP1884FBlock, c.EIP2028FBlock, c.EIP2200FBlock = nil, nil, nil, nil, nil, nil
+	}
+}
@@ -0,0 +1,291 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package chainspec
+
+import (
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the bundled chain configs survive a conversion into a Parity chainspec
+// and back, retaining the genesis block and the consensus rules at every fork.
+func TestParityRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		genesis *core.Genesis
+	}{
+		{"mainnet", core.DefaultGenesisBlock()},
+		{"classic", core.DefaultClassicGenesisBlock()},
+		{"testnet", core.DefaultTestnetGenesisBlock()},
+		{"rinkeby", core.DefaultRinkebyGenesisBlock()},
+		{"kotti", core.DefaultKottiGenesisBlock()},
+		{"goerli", core.DefaultGoerliGenesisBlock()},
+	}
+	for _, tt := range tests {
+		spec, err := NewParityChainSpec(tt.name, tt.genesis, nil)
+		if err != nil {
+			t.Errorf("%s: failed to create chainspec: %v", tt.name, err)
+			continue
+		}
+		blob, err := json.Marshal(spec)
+		if err != nil {
+			t.Errorf("%s: failed to encode chainspec: %v", tt.name, err)
+			continue
+		}
+		if !IsParityChainSpec(blob) {
+			t.Errorf("%s: chainspec not detected", tt.name)
+		}
+		var decoded ParityChainSpec
+		if err := json.Unmarshal(blob, &decoded); err != nil {
+			t.Errorf("%s: failed to decode chainspec: %v", tt.name, err)
+			continue
+		}
+		genesis, err := decoded.ToGenesis()
+		if err != nil {
+			t.Errorf("%s: failed to convert chainspec: %v", tt.name, err)
+			continue
+		}
+		if have, want := genesis.ToBlock(nil).Hash(), tt.genesis.ToBlock(nil).Hash(); have != want {
+			t.Errorf("%s: genesis hash mismatch: have %x, want %x", tt.name, have, want)
+		}
+		checkRules(t, tt.name, genesis.Config, tt.genesis.Config)
+	}
+}
+
+// checkRules ensures that two chain configs enforce the same consensus rules
+// around each of their fork blocks, as reported by the Is* predicates.
+func checkRules(t *testing.T, name string, have, want *params.ChainConfig) {
+	t.Helper()
+
+	if have.ChainID.Cmp(want.ChainID) != 0 {
+		t.Errorf("%s: chain id mismatch: have %v, want %v", name, have.ChainID, want.ChainID)
+	}
+	if !reflect.DeepEqual(have.Ethash, want.Ethash) || !reflect.DeepEqual(have.Clique, want.Clique) {
+		t.Errorf("%s: engine mismatch: have %v, want %v", name, have, want)
+	}
+	if want.Ethash != nil && !sameBlock(have.ECIP1017EraRounds, want.ECIP1017EraRounds) {
+		t.Errorf("%s: ECIP1017 era rounds mismatch: have %v, want %v", name, have.ECIP1017EraRounds, want.ECIP1017EraRounds)
+	}
+	// Parity can't express the rules of ethash on a clique network
+	skip := make(map[string]bool)
+	if want.Clique != nil {
+		for _, rule := range []string{"IsEIP100F", "IsEIP649F", "IsEIP1234F", "IsECIP1010", "IsECIP1099F", "IsBombDisposal"} {
+			skip[rule] = true
+		}
+	}
+	// The genesis block is never processed, so check from the first block on
+	blocks := []uint64{1}
+	for _, config := range []*params.ChainConfig{have, want} {
+		for _, field := range config.ForkSchedule() {
+			if block := config.Value(field).Uint64(); block > 1 {
+				blocks = append(blocks, block-1, block)
+			}
+		}
+	}
+	var (
+		haveValue = reflect.ValueOf(have)
+		wantValue = reflect.ValueOf(want)
+		predicate = reflect.TypeOf(have.IsEIP150)
+	)
+	for i := 0; i < haveValue.NumMethod(); i++ {
+		method := haveValue.Type().Method(i)
+		if !strings.HasPrefix(method.Name, "Is") || method.Type.NumIn() != 2 || haveValue.Method(i).Type() != predicate || skip[method.Name] {
+			continue
+		}
+		for _, block := range blocks {
+			num := []reflect.Value{reflect.ValueOf(new(big.Int).SetUint64(block))}
+			if h, w := haveValue.Method(i).Call(num)[0].Bool(), wantValue.Method(i).Call(num)[0].Bool(); h != w {
+				t.Errorf("%s: %s(%d) mismatch: have %v, want %v", name, method.Name, block, h, w)
+			}
+		}
+	}
+}
+
+// Tests that chain configs containing forks unknown to Parity are rejected.
+func TestParityUnsupported(t *testing.T) {
+	for name, genesis := range map[string]*core.Genesis{
+		"social":      core.DefaultSocialGenesisBlock(),
+		"ethersocial": core.DefaultEthersocialGenesisBlock(),
+		"mix":         core.DefaultMixGenesisBlock(),
+	} {
+		if _, err := NewParityChainSpec(name, genesis, nil); err == nil {
+			t.Errorf("%s: chainspec created for unsupported network", name)
+		}
+	}
+}
+
+// parityTestSpec is a hand written Parity chainspec.
+const parityTestSpec = `{
+	"name": "Test",
+	"engine": {
+		"Ethash": {
+			"params": {
+				"minimumDifficulty": "0x20000",
+				"difficultyBoundDivisor": "0x800",
+				"durationLimit": "0xd",
+				"blockReward": {
+					"0x0": "0x4563918244f40000",
+					"100": "0x29a2241af62c0000"
+				},
+				"homesteadTransition": 10,
+				"eip100bTransition": "0x64"
+			}
+		}
+	},
+	"params": {
+		"accountStartNonce": "0x0",
+		"maximumExtraDataSize": "0x20",
+		"minGasLimit": "0x1388",
+		"gasLimitBoundDivisor": "0x400",
+		"networkID": "0x539",
+		"maxCodeSize": 24576,
+		"maxCodeSizeTransition": "50",
+		"eip150Transition": 20,
+		"eip155Transition": 50,
+		"eip160Transition": 50,
+		"eip161abcTransition": 50,
+		"eip161dTransition": 50,
+		"eip98Transition": "0x7fffffffffffffff",
+		"eip140Transition": 100,
+		"eip211Transition": 100,
+		"eip214Transition": 100,
+		"eip658Transition": 100,
+		"eip145Transition": 200,
+		"eip1344Transition": 300
+	},
+	"genesis": {
+		"seal": {
+			"ethereum": {
+				"nonce": "0x0000000000000042",
+				"mixHash": "0x0000000000000000000000000000000000000000000000000000000000000000"
+			}
+		},
+		"difficulty": "0x400000000",
+		"author": "0x0000000000000000000000000000000000000000",
+		"timestamp": "0x00",
+		"parentHash": "0x0000000000000000000000000000000000000000000000000000000000000000",
+		"extraData": "0x",
+		"gasLimit": "0x1388"
+	},
+	"accounts": {
+		"0000000000000000000000000000000000000001": { "builtin": { "name": "ecrecover", "pricing": { "linear": { "base": 3000, "word": 0 } } } },
+		"0000000000000000000000000000000000000005": { "builtin": { "name": "modexp", "activate_at": 100, "pricing": { "modexp": { "divisor": 20 } } } },
+		"0000000000000000000000000000000000000006": { "builtin": { "name": "alt_bn128_add", "activate_at": "0x64", "pricing": { "linear": { "base": 500, "word": 0 } } } },
+		"0000000000000000000000000000000000000007": { "builtin": { "name": "alt_bn128_mul", "activate_at": "100", "pricing": { "linear": { "base": 40000, "word": 0 } } } },
+		"0000000000000000000000000000000000000008": { "builtin": { "name": "alt_bn128_pairing", "activate_at": 100, "pricing": { "alt_bn128_pairing": { "base": 100000, "pair": 80000 } } } },
+		"0000000000000000000000000000000000000009": { "builtin": { "name": "blake2_f", "activate_at": 300, "pricing": { "blake2_f": { "gas_per_round": 1 } } } },
+		"7a9f3a2b63ec6f7fe5a1b9d1b6a2a9f9d8b8c6e1": { "balance": "1000000000000000000", "nonce": "0x1" }
+	}
+}`
+
+// Tests that a hand written Parity chainspec is imported into the matching per
+// EIP fork blocks, folding the hard forks activating all their EIPs at once.
+func TestParityImport(t *testing.T) {
+	blob := []byte(parityTestSpec)
+	if !IsParityChainSpec(blob) {
+		t.Fatalf("chainspec not detected")
+	}
+	var spec ParityChainSpec
+	if err := json.Unmarshal(blob, &spec); err != nil {
+		t.Fatalf("failed to decode chainspec: %v", err)
+	}
+	genesis, err := spec.ToGenesis()
+	if err != nil {
+		t.Fatalf("failed to convert chainspec: %v", err)
+	}
+	want := &params.ChainConfig{
+		ChainID:        big.NewInt(1337),
+		HomesteadBlock: big.NewInt(10),
+		EIP150Block:    big.NewInt(20),
+		EIP155Block:    big.NewInt(50),
+		EIP158Block:    big.NewInt(50),
+		ByzantiumBlock: big.NewInt(100),
+		EIP145FBlock:   big.NewInt(200),
+		EIP152FBlock:   big.NewInt(300),
+		EIP1344FBlock:  big.NewInt(300),
+		Ethash:         new(params.EthashConfig),
+	}
+	if !reflect.DeepEqual(genesis.Config, want) {
+		t.Errorf("chain config mismatch:\nhave %v\nwant %v", genesis.Config, want)
+	}
+	if genesis.Nonce != 0x42 {
+		t.Errorf("genesis nonce mismatch: have %#x, want %#x", genesis.Nonce, 0x42)
+	}
+	if len(genesis.Alloc) != 1 {
+		t.Fatalf("genesis alloc size mismatch: have %d, want %d", len(genesis.Alloc), 1)
+	}
+	account := genesis.Alloc[common.HexToAddress("0x7a9f3a2b63ec6f7fe5a1b9d1b6a2a9f9d8b8c6e1")]
+	if account.Balance == nil || account.Balance.Cmp(big.NewInt(1e18)) != 0 || account.Nonce != 1 {
+		t.Errorf("genesis account mismatch: have balance %v nonce %d", account.Balance, account.Nonce)
+	}
+}
+
+// Tests that chainspecs with chain parameters differing from the consensus rules
+// enforced by go-ethereum are rejected.
+func TestParityImportUnsupported(t *testing.T) {
+	tests := []struct {
+		mutate func(*ParityChainSpec)
+		err    string
+	}{
+		{func(spec *ParityChainSpec) { nonce := Uint64(1); spec.Params.AccountStartNonce = &nonce }, "account start nonce"},
+		{func(spec *ParityChainSpec) { size := Uint64(0x6000 + 1); spec.Params.MaxCodeSize = &size }, "max code size"},
+		{func(spec *ParityChainSpec) { spec.Params.MinGasLimit = 3141592 }, "min gas limit"},
+		{func(spec *ParityChainSpec) { spec.Params.GasLimitBoundDivisor = 0x800 }, "gas limit bound divisor"},
+		{func(spec *ParityChainSpec) { spec.Params.MaximumExtraDataSize = 0x40 }, "maximum extra-data size"},
+		{func(spec *ParityChainSpec) { spec.Engine.Ethash, spec.Engine.Clique = nil, new(ParityClique) }, "maximum extra-data size"},
+	}
+	for i, tt := range tests {
+		var spec ParityChainSpec
+		if err := json.Unmarshal([]byte(parityTestSpec), &spec); err != nil {
+			t.Fatalf("failed to decode chainspec: %v", err)
+		}
+		tt.mutate(&spec)
+		if _, err := spec.ToGenesis(); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("test %d: error mismatch: have %v, want %q", i, err, tt.err)
+		}
+	}
+}
+
+// Tests that a fork block pinned without the DAO hard-fork is imported as the
+// opposed DAO hard-fork, and that exporting an opposed DAO hard-fork fails if
+// the canonical block at the fork block is unknown.
+func TestParityOpposedDAOFork(t *testing.T) {
+	var spec ParityChainSpec
+	if err := json.Unmarshal([]byte(parityTestSpec), &spec); err != nil {
+		t.Fatalf("failed to decode chainspec: %v", err)
+	}
+	block, hash := Uint64(1000), common.Hash{0x01}
+	spec.Params.ForkBlock, spec.Params.ForkCanonHash = &block, &hash
+
+	genesis, err := spec.ToGenesis()
+	if err != nil {
+		t.Fatalf("failed to convert chainspec: %v", err)
+	}
+	if !sameBlock(genesis.Config.DAOForkBlock, big.NewInt(1000)) || genesis.Config.DAOForkSupport {
+		t.Errorf("DAO fork mismatch: have block %v support %v, want block 1000 support false", genesis.Config.DAOForkBlock, genesis.Config.DAOForkSupport)
+	}
+	if _, err := NewParityChainSpec("test", genesis, nil); err == nil {
+		t.Errorf("chainspec created for opposed DAO hard-fork at unknown block")
+	}
+}
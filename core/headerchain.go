@@ -481,6 +481,12 @@ func (hc *HeaderChain) SetHead(head uint64, delFn DeleteCallback) {
 	for i := height; i > head; i-- {
 		rawdb.DeleteCanonicalHash(batch, i)
 	}
+	// Discard any frozen chain segment above the new head
+	if ancients, ok := hc.chainDb.(ethdb.AncientWriter); ok {
+		if err := ancients.TruncateAncients(head + 1); err != nil {
+			log.Crit("Failed to truncate ancient data", "number", head, "err", err)
+		}
+	}
 	batch.Write()
 
 	// Clear out any stale content from the caches
@@ -0,0 +1,105 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+var (
+	// errReorgFinality is returned if a reorg is rejected by the modified exponential
+	// subjective scoring (MESS) of the chain.
+	errReorgFinality = errors.New("reorg rejected by subjective finality")
+
+	messRejectedMeter = metrics.NewRegisteredMeter("chain/reorg/mess/rejected", nil)
+	messAcceptedMeter = metrics.NewRegisteredMeter("chain/reorg/mess/accepted", nil)
+)
+
+// The MESS antigravity curve is a cubic rising from 1 at a zero age to 31 after
+// xcap seconds, all values being scaled up by the denominator:
+//
+//	denominator + (3x² - 2x³/xcap) * height / xcap²
+//
+// The curve is flat at both ends, sparing short lived reorgs and capping the
+// difficulty advantage demanded from the competing chain after about 7 hours.
+var (
+	messCurveDenominator = big.NewInt(128)
+	messCurveXCap        = big.NewInt(25132) // floor(8000*pi)
+	messCurveHeight      = big.NewInt(128 * 15 * 2)
+)
+
+// messAntigravity returns the factor, scaled up by messCurveDenominator, by which
+// a competing chain's total difficulty since the common ancestor must exceed the
+// local one's, given the age of the common ancestor in seconds.
+func messAntigravity(age *big.Int) *big.Int {
+	x := math.BigMin(age, messCurveXCap)
+
+	quadratic := new(big.Int).Mul(x, x)
+	quadratic.Mul(quadratic, common.Big3)
+
+	cubic := new(big.Int).Exp(x, common.Big3, nil)
+	cubic.Mul(cubic, common.Big2)
+	cubic.Div(cubic, messCurveXCap)
+
+	out := quadratic.Sub(quadratic, cubic)
+	out.Mul(out, messCurveHeight)
+	out.Div(out, new(big.Int).Mul(messCurveXCap, messCurveXCap))
+	return out.Add(out, messCurveDenominator)
+}
+
+// checkMESS verifies that a reorg from the current head onto the proposed block
+// satisfies the modified exponential subjective scoring of the chain: the total
+// difficulty added by the proposed chain since the common ancestor must exceed
+// the one added by the local chain by the antigravity of the ancestor's age.
+func (bc *BlockChain) checkMESS(ancestor, current, proposed *types.Header) error {
+	var (
+		ancestorTd = bc.GetTd(ancestor.Hash(), ancestor.Number.Uint64())
+		currentTd  = bc.GetTd(current.Hash(), current.Number.Uint64())
+		proposedTd = bc.GetTd(proposed.Hash(), proposed.Number.Uint64())
+	)
+	if ancestorTd == nil || currentTd == nil || proposedTd == nil {
+		return errors.New("missing total difficulty for reorg")
+	}
+	age := new(big.Int)
+	if current.Time > ancestor.Time {
+		age.SetUint64(current.Time - ancestor.Time)
+	}
+	var (
+		localGain    = new(big.Int).Sub(currentTd, ancestorTd)
+		proposedGain = new(big.Int).Sub(proposedTd, ancestorTd)
+
+		want = new(big.Int).Mul(localGain, messAntigravity(age))
+		have = new(big.Int).Mul(proposedGain, messCurveDenominator)
+	)
+	ratio, _ := new(big.Float).Quo(new(big.Float).SetInt(have), new(big.Float).SetInt(math.BigMax(want, common.Big1))).Float64()
+	if have.Cmp(want) < 0 {
+		messRejectedMeter.Mark(1)
+		log.Warn("Rejected reorg by subjective finality", "ancestor", ancestor.Number, "hash", ancestor.Hash(), "age", age,
+			"drop", current.Number.Uint64()-ancestor.Number.Uint64(), "add", proposed.Number.Uint64()-ancestor.Number.Uint64(), "ratio", ratio)
+		return errReorgFinality
+	}
+	messAcceptedMeter.Mark(1)
+	log.Debug("Accepted reorg by subjective finality", "ancestor", ancestor.Number, "hash", ancestor.Hash(), "age", age, "ratio", ratio)
+	return nil
+}
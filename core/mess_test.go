@@ -0,0 +1,109 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the MESS antigravity curve is flat at both ends and caps out.
+func TestMESSAntigravity(t *testing.T) {
+	tests := []struct {
+		age  int64
+		want int64
+	}{
+		{0, 128},
+		{60, 128},
+		{600, 134},
+		{3600, 341},
+		{12566, 2048},
+		{20000, 3553},
+		{25132, 3968},
+		{1000000, 3968},
+	}
+	for _, tt := range tests {
+		if have := messAntigravity(big.NewInt(tt.age)); have.Cmp(big.NewInt(tt.want)) != 0 {
+			t.Errorf("age %d: antigravity mismatch: have %v, want %v", tt.age, have, tt.want)
+		}
+	}
+}
+
+// Tests that deep reorgs onto marginally heavier chains are rejected by MESS,
+// whilst shallow reorgs and overwhelmingly heavier chains are still accepted.
+func TestMESSReorgDisabled(t *testing.T) { testMESSReorg(t, false, 0, 21, true) }
+func TestMESSReorgDeep(t *testing.T)     { testMESSReorg(t, true, 0, 21, false) }
+func TestMESSReorgShallow(t *testing.T)  { testMESSReorg(t, true, 19, 2, true) }
+func TestMESSReorgHeavy(t *testing.T)    { testMESSReorg(t, true, 0, 600, true) }
+
+func testMESSReorg(t *testing.T, enabled bool, ancestor int, sideLength int, reorg bool) {
+	config := *params.TestChainConfig
+	if enabled {
+		config.ECBP1100FBlock = big.NewInt(0)
+	}
+	var (
+		engine  = ethash.NewFaker()
+		db      = ethdb.NewMemDatabase()
+		genesis = (&Genesis{Config: &config}).MustCommit(db)
+	)
+	// Create a canonical chain with large block times and a competing one forking
+	// off of it at the requested ancestor
+	canon, _ := GenerateChain(&config, genesis, engine, db, 20, func(i int, b *BlockGen) {
+		b.SetCoinbase(common.Address{0x01})
+		b.OffsetTime(990)
+	})
+	parent := genesis
+	if ancestor > 0 {
+		parent = canon[ancestor-1]
+	}
+	side, _ := GenerateChain(&config, parent, engine, db, sideLength, func(i int, b *BlockGen) {
+		b.SetCoinbase(common.Address{0x02})
+		if i < len(canon)-ancestor {
+			b.OffsetTime(990)
+		}
+	})
+	chain, err := NewBlockChain(db, nil, &config, engine, vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(canon); err != nil {
+		t.Fatalf("failed to insert canonical chain: %v", err)
+	}
+	if _, err := chain.InsertChain(side); err != nil {
+		t.Fatalf("failed to insert side chain: %v", err)
+	}
+	// Ensure the side chain is fully imported, but canonical only if accepted
+	sideHead := side[len(side)-1]
+	if chain.GetBlock(sideHead.Hash(), sideHead.NumberU64()) == nil {
+		t.Fatalf("side chain head missing")
+	}
+	want := canon[len(canon)-1]
+	if reorg {
+		want = sideHead
+	}
+	if head := chain.CurrentBlock(); head.Hash() != want.Hash() {
+		t.Errorf("head mismatch: have #%d [%x], want #%d [%x]", head.NumberU64(), head.Hash().Bytes()[:4], want.NumberU64(), want.Hash().Bytes()[:4])
+	}
+}
@@ -0,0 +1,275 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/olekukonko/tablewriter"
+)
+
+// freezerdb is a database wrapper that enables freezer data retrievals.
+type freezerdb struct {
+	ethdb.Database
+	*freezer
+}
+
+// Close implements ethdb.Database, closing both the fast key-value store as
+// well as the slow ancient tables. The freezer is closed first, stopping its
+// background migration which still reads from the key-value store.
+func (frdb *freezerdb) Close() {
+	if err := frdb.freezer.Close(); err != nil {
+		log.Error("Failed to close ancient database", "err", err)
+	}
+	frdb.Database.Close()
+}
+
+// NewDatabaseWithFreezer creates a high level database on top of a given key-
+// value data store with a freezer moving immutable chain segments into cold
+// storage. Chain data older than threshold blocks is migrated into the flat
+// files in the freezer directory and transparently served from there by the
+// chain accessors.
+//
+// If readonly is set, no chain data is migrated into the freezer. This is meant
+// for offline tools, which should not race the database contents they operate on
+// against a background migration.
+func NewDatabaseWithFreezer(db ethdb.Database, freezer string, namespace string, threshold uint64, readonly bool) (ethdb.Database, error) {
+	// Create the idle freezer instance
+	frdb, err := newFreezer(freezer, namespace, threshold)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkFreezerConsistency(db, frdb); err != nil {
+		frdb.Close()
+		return nil, err
+	}
+	// Freezer is consistent with the key-value database, permit combining the two
+	if !readonly {
+		frdb.wg.Add(1)
+		go frdb.freeze(db)
+	}
+
+	return &freezerdb{
+		Database: db,
+		freezer:  frdb,
+	}, nil
+}
+
+// checkFreezerConsistency ensures that the ancient store and the key-value
+// store contain the same chain, and that no frozen chain segment is missing.
+func checkFreezerConsistency(db ethdb.Database, frdb *freezer) error {
+	kvgenesis, _ := db.Get(headerHashKey(0))
+	frozen, _ := frdb.Ancients()
+
+	switch {
+	case frozen > 0 && len(kvgenesis) == 0:
+		// Key-value store empty, freezer not. Someone wiped the chain data but
+		// left the ancients around.
+		return errors.New("ancient chain segments already extracted, but key-value store is empty")
+
+	case frozen > 0:
+		// Both stores contain data, ensure they're from the same chain
+		frgenesis, err := frdb.Ancient(freezerHashTable, 0)
+		if err != nil {
+			return fmt.Errorf("failed to retrieve genesis from ancient %v", err)
+		}
+		if !bytes.Equal(kvgenesis, frgenesis) {
+			return fmt.Errorf("genesis mismatch: %#x (leveldb) != %#x (ancients)", kvgenesis, frgenesis)
+		}
+		// The key-value store must continue where the freezer left off
+		if kvhash, _ := db.Get(headerHashKey(frozen)); len(kvhash) == 0 {
+			if head := ReadHeadHeaderHash(db); head != (common.Hash{}) {
+				if number := ReadHeaderNumber(db, head); number != nil && *number >= frozen {
+					return fmt.Errorf("gap (#%d) in the chain between ancients and leveldb", frozen)
+				}
+			}
+		}
+
+	case len(kvgenesis) > 0:
+		// Freezer empty, ensure the key-value store didn't have its ancient
+		// chain segment extracted into a different freezer.
+		if kvblob, _ := db.Get(headerHashKey(1)); len(kvblob) == 0 {
+			if head := ReadHeadHeaderHash(db); head != (common.Hash{}) {
+				if number := ReadHeaderNumber(db, head); number != nil && *number > 0 {
+					return errors.New("ancient chain segments already extracted, please set the ancient directory to the correct path")
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// databaseStat is the aggregated size and item count of a single category of
+// data stored in the chain database.
+type databaseStat struct {
+	database string
+	category string
+	size     common.StorageSize
+	count    uint64
+}
+
+// add accounts an additional item of the given size to the category.
+func (s *databaseStat) add(size common.StorageSize) {
+	s.size += size
+	s.count++
+}
+
+// inspectDatabase walks over the entire key-value store, classifying every
+// entry by its key schema and aggregating the sizes and counts per category.
+// If the database has an ancient store attached, its tables are reported too.
+func inspectDatabase(db ethdb.Database) ([]*databaseStat, error) {
+	it := db.NewIterator()
+	defer it.Release()
+
+	var (
+		headers     = &databaseStat{database: "Key-Value store", category: "Headers"}
+		bodies      = &databaseStat{database: "Key-Value store", category: "Bodies"}
+		receipts    = &databaseStat{database: "Key-Value store", category: "Receipts"}
+		tds         = &databaseStat{database: "Key-Value store", category: "Difficulties"}
+		numHashes   = &databaseStat{database: "Key-Value store", category: "Block number->hash"}
+		hashNumbers = &databaseStat{database: "Key-Value store", category: "Block hash->number"}
+		txLookups   = &databaseStat{database: "Key-Value store", category: "Transaction index"}
+		bloomBits   = &databaseStat{database: "Key-Value store", category: "Bloombit index"}
+		tries       = &databaseStat{database: "Key-Value store", category: "Trie nodes"}
+		preimages   = &databaseStat{database: "Key-Value store", category: "Trie preimages"}
+		accountSnap = &databaseStat{database: "Key-Value store", category: "Account snapshot"}
+		storageSnap = &databaseStat{database: "Key-Value store", category: "Storage snapshot"}
+		metadata    = &databaseStat{database: "Key-Value store", category: "Singleton metadata"}
+		unaccounted = &databaseStat{database: "Key-Value store", category: "Unaccounted"}
+
+		count  int64
+		start  = time.Now()
+		logged = time.Now()
+	)
+	// Classify every key by the schema it belongs to
+	for it.Next() {
+		var (
+			key  = it.Key()
+			size = common.StorageSize(len(key) + len(it.Value()))
+		)
+		switch {
+		case bytes.HasPrefix(key, headerPrefix) && len(key) == len(headerPrefix)+8+common.HashLength:
+			headers.add(size)
+		case bytes.HasPrefix(key, headerPrefix) && bytes.HasSuffix(key, headerTDSuffix) && len(key) == len(headerPrefix)+8+common.HashLength+len(headerTDSuffix):
+			tds.add(size)
+		case bytes.HasPrefix(key, headerPrefix) && bytes.HasSuffix(key, headerHashSuffix) && len(key) == len(headerPrefix)+8+len(headerHashSuffix):
+			numHashes.add(size)
+		case bytes.HasPrefix(key, headerNumberPrefix) && len(key) == len(headerNumberPrefix)+common.HashLength:
+			hashNumbers.add(size)
+		case bytes.HasPrefix(key, blockBodyPrefix) && len(key) == len(blockBodyPrefix)+8+common.HashLength:
+			bodies.add(size)
+		case bytes.HasPrefix(key, blockReceiptsPrefix) && len(key) == len(blockReceiptsPrefix)+8+common.HashLength:
+			receipts.add(size)
+		case bytes.HasPrefix(key, txLookupPrefix) && len(key) == len(txLookupPrefix)+common.HashLength:
+			txLookups.add(size)
+		case bytes.HasPrefix(key, bloomBitsPrefix) && len(key) == len(bloomBitsPrefix)+10+common.HashLength:
+			bloomBits.add(size)
+		case bytes.HasPrefix(key, BloomBitsIndexPrefix):
+			bloomBits.add(size)
+		case bytes.HasPrefix(key, preimagePrefix) && len(key) == len(preimagePrefix)+common.HashLength:
+			preimages.add(size)
+		case bytes.HasPrefix(key, SnapshotAccountPrefix) && len(key) == len(SnapshotAccountPrefix)+common.HashLength:
+			accountSnap.add(size)
+		case bytes.HasPrefix(key, SnapshotStoragePrefix) && len(key) == len(SnapshotStoragePrefix)+2*common.HashLength:
+			storageSnap.add(size)
+		case len(key) == common.HashLength:
+			tries.add(size)
+		case bytes.HasPrefix(key, configPrefix) && len(key) == len(configPrefix)+common.HashLength:
+			metadata.add(size)
+		case bytes.Equal(key, databaseVerisionKey), bytes.Equal(key, headHeaderKey), bytes.Equal(key, headBlockKey),
+			bytes.Equal(key, headFastBlockKey), bytes.Equal(key, fastTrieProgressKey),
+			bytes.Equal(key, snapshotRootKey), bytes.Equal(key, snapshotGeneratorKey):
+			metadata.add(size)
+		default:
+			unaccounted.add(size)
+		}
+		count++
+		if count%1000 == 0 && time.Since(logged) > 8*time.Second {
+			log.Info("Inspecting database", "count", count, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+
+	stats := []*databaseStat{
+		headers, bodies, receipts, tds, numHashes, hashNumbers, txLookups,
+		bloomBits, tries, preimages, accountSnap, storageSnap, metadata, unaccounted,
+	}
+	// Append the sizes of the ancient tables if an ancient store is attached
+	if ancients, ok := db.(ethdb.AncientReader); ok {
+		items, err := ancients.Ancients()
+		if err != nil {
+			return nil, err
+		}
+		for _, table := range []struct {
+			kind, category string
+		}{
+			{freezerHeaderTable, "Headers"},
+			{freezerBodiesTable, "Bodies"},
+			{freezerReceiptTable, "Receipts"},
+			{freezerDifficultyTable, "Difficulties"},
+			{freezerHashTable, "Block number->hash"},
+		} {
+			size, err := ancients.AncientSize(table.kind)
+			if err != nil {
+				return nil, err
+			}
+			stats = append(stats, &databaseStat{
+				database: "Ancient store",
+				category: table.category,
+				size:     common.StorageSize(size),
+				count:    items,
+			})
+		}
+	}
+	log.Info("Inspected database", "count", count, "elapsed", common.PrettyDuration(time.Since(start)))
+	return stats, nil
+}
+
+// InspectDatabase traverses the entire database and prints a per-category
+// breakdown of the storage size and item count of the stored data.
+func InspectDatabase(db ethdb.Database) error {
+	stats, err := inspectDatabase(db)
+	if err != nil {
+		return err
+	}
+	var (
+		total common.StorageSize
+		rows  [][]string
+	)
+	for _, stat := range stats {
+		total += stat.size
+		rows = append(rows, []string{stat.database, stat.category, stat.size.String(), fmt.Sprintf("%d", stat.count)})
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetAutoFormatHeaders(false)
+	table.SetHeader([]string{"Database", "Category", "Size", "Items"})
+	table.SetFooter([]string{"", "Total", total.String(), ""})
+	table.AppendBulk(rows)
+	table.Render()
+
+	return nil
+}
@@ -0,0 +1,91 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// Tests that database inspection classifies every entry into the correct
+// category, accounting unknown entries separately.
+func TestInspectDatabase(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+
+	makeTestChain(db, 4)
+
+	// Index the transactions of a block, tracking the size of the entries
+	txs := []*types.Transaction{
+		types.NewTransaction(0, common.Address{0x11}, big.NewInt(1), 21000, big.NewInt(1), nil),
+		types.NewTransaction(1, common.Address{0x22}, big.NewInt(2), 21000, big.NewInt(1), nil),
+		types.NewTransaction(2, common.Address{0x33}, big.NewInt(3), 21000, big.NewInt(1), []byte{0x33}),
+	}
+	WriteTxLookupEntries(db, types.NewBlock(&types.Header{Number: big.NewInt(5)}, txs, nil, nil))
+
+	var lookupSize common.StorageSize
+	for _, tx := range txs {
+		entry, _ := db.Get(txLookupKey(tx.Hash()))
+		lookupSize += common.StorageSize(len(txLookupKey(tx.Hash())) + len(entry))
+	}
+	WritePreimages(db, map[common.Hash][]byte{{0x01}: {0x01}, {0x02}: {0x02}})
+	db.Put(common.Hash{0xff}.Bytes(), []byte{0xff}) // trie node
+	db.Put([]byte("unknown"), []byte{0x00})
+
+	WriteAccountSnapshot(db, common.Hash{0x01}, []byte{0x01})
+	WriteStorageSnapshot(db, common.Hash{0x01}, common.Hash{0x02}, []byte{0x02})
+	WriteStorageSnapshot(db, common.Hash{0x01}, common.Hash{0x03}, []byte{0x03})
+	WriteSnapshotRoot(db, common.Hash{0x04})
+
+	stats, err := inspectDatabase(db)
+	if err != nil {
+		t.Fatalf("failed to inspect database: %v", err)
+	}
+	want := map[string]uint64{
+		"Headers":            4,
+		"Bodies":             4,
+		"Receipts":           4,
+		"Difficulties":       4,
+		"Block number->hash": 4,
+		"Block hash->number": 4,
+		"Transaction index":  3,
+		"Bloombit index":     0,
+		"Trie nodes":         1,
+		"Trie preimages":     2,
+		"Account snapshot":   1,
+		"Storage snapshot":   2,
+		"Singleton metadata": 3,
+		"Unaccounted":        1,
+	}
+	if len(stats) != len(want) {
+		t.Fatalf("category count mismatch: have %d, want %d", len(stats), len(want))
+	}
+	for _, stat := range stats {
+		if stat.count != want[stat.category] {
+			t.Errorf("%s: item count mismatch: have %d, want %d", stat.category, stat.count, want[stat.category])
+		}
+		if stat.category == "Transaction index" && stat.size != lookupSize {
+			t.Errorf("%s: size mismatch: have %v, want %v", stat.category, stat.size, lookupSize)
+		}
+		if (stat.count == 0) != (stat.size == 0) {
+			t.Errorf("%s: size %v inconsistent with item count %d", stat.category, stat.size, stat.count)
+		}
+	}
+}
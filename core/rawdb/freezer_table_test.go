@@ -0,0 +1,208 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// getChunk returns a chunk of data, filled with the given byte b.
+func getChunk(size int, b int) []byte {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(b)
+	}
+	return data
+}
+
+// newTestTable creates a freezer table in a fresh temporary directory.
+func newTestTable(t *testing.T, dir string, name string, maxFileSize uint32, noCompression bool) *freezerTable {
+	rm, wm, sc := metrics.NewMeter(), metrics.NewMeter(), metrics.NewCounter()
+	table, err := newCustomTable(dir, name, rm, wm, sc, maxFileSize, noCompression)
+	if err != nil {
+		t.Fatalf("failed to open table: %v", err)
+	}
+	return table
+}
+
+// Tests that data can be appended to and retrieved from a freezer table, both
+// compressed and uncompressed, spanning multiple data files.
+func TestFreezerBasics(t *testing.T) {
+	for _, noCompression := range []bool{false, true} {
+		dir, err := ioutil.TempDir("", "freezer")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		// Set file size to 50, each item is 15 bytes, so 3 items per file
+		table := newTestTable(t, dir, fmt.Sprintf("basics-%v", noCompression), 50, noCompression)
+		for x := 0; x < 255; x++ {
+			if err := table.Append(uint64(x), getChunk(15, x)); err != nil {
+				t.Fatalf("failed to append item %d: %v", x, err)
+			}
+		}
+		// Appending out of order must fail
+		if err := table.Append(300, getChunk(15, 0)); err == nil {
+			t.Fatalf("out of order append succeeded")
+		}
+		for y := 0; y < 255; y++ {
+			exp := getChunk(15, y)
+			got, err := table.Retrieve(uint64(y))
+			if err != nil {
+				t.Fatalf("failed to retrieve item %d: %v", y, err)
+			}
+			if !bytes.Equal(got, exp) {
+				t.Fatalf("item %d mismatch: have %x, want %x", y, got, exp)
+			}
+		}
+		// Check that we cannot read too far
+		if _, err := table.Retrieve(uint64(255)); err != errOutOfBounds {
+			t.Fatalf("out of bounds retrieval error mismatch: have %v, want %v", err, errOutOfBounds)
+		}
+		table.Close()
+	}
+}
+
+// Tests that a freezer table can be reopened, and that it repairs itself if the
+// index or the data files were cut short.
+func TestFreezerRepair(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freezer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	table := newTestTable(t, dir, "repair", 50, true)
+	for x := 0; x < 255; x++ {
+		table.Append(uint64(x), getChunk(15, x))
+	}
+	table.Close()
+
+	// Reopen and check all items are still accessible
+	table = newTestTable(t, dir, "repair", 50, true)
+	if table.items != 255 {
+		t.Fatalf("item count mismatch after reopen: have %d, want %d", table.items, 255)
+	}
+	table.Close()
+
+	// Chop off the last bytes of the head data file
+	headFile := filepath.Join(dir, fmt.Sprintf("repair.%04d.rdat", 254/3))
+	stat, err := os.Stat(headFile)
+	if err != nil {
+		t.Fatalf("failed to stat head file: %v", err)
+	}
+	if err := os.Truncate(headFile, stat.Size()-4); err != nil {
+		t.Fatalf("failed to truncate head file: %v", err)
+	}
+	// The dangling index entry must be dropped on reopen
+	table = newTestTable(t, dir, "repair", 50, true)
+	if table.items != 254 {
+		t.Fatalf("item count mismatch after repair: have %d, want %d", table.items, 254)
+	}
+	if _, err := table.Retrieve(254); err != errOutOfBounds {
+		t.Fatalf("repaired item still retrievable: %v", err)
+	}
+	for y := 0; y < 254; y++ {
+		if got, err := table.Retrieve(uint64(y)); err != nil || !bytes.Equal(got, getChunk(15, y)) {
+			t.Fatalf("item %d mismatch after repair: have %x (%v), want %x", y, got, err, getChunk(15, y))
+		}
+	}
+	// Data can be appended after the repair
+	if err := table.Append(254, getChunk(15, 254)); err != nil {
+		t.Fatalf("failed to append after repair: %v", err)
+	}
+	table.Close()
+}
+
+// Tests that truncating a freezer table discards the data above the limit, even
+// if it spans across multiple data files.
+func TestFreezerTruncate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freezer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	table := newTestTable(t, dir, "truncation", 50, false)
+	for x := 0; x < 30; x++ {
+		table.Append(uint64(x), getChunk(15, x))
+	}
+	if err := table.truncate(10); err != nil {
+		t.Fatalf("failed to truncate table: %v", err)
+	}
+	if table.items != 10 {
+		t.Fatalf("item count mismatch after truncation: have %d, want %d", table.items, 10)
+	}
+	if _, err := table.Retrieve(10); err != errOutOfBounds {
+		t.Fatalf("truncated item still retrievable: %v", err)
+	}
+	// Refill the table with different data and ensure it's served
+	for x := 10; x < 20; x++ {
+		if err := table.Append(uint64(x), getChunk(15, 0xff-x)); err != nil {
+			t.Fatalf("failed to append item %d: %v", x, err)
+		}
+	}
+	table.Close()
+
+	table = newTestTable(t, dir, "truncation", 50, false)
+	defer table.Close()
+
+	for x := 0; x < 20; x++ {
+		exp := getChunk(15, x)
+		if x >= 10 {
+			exp = getChunk(15, 0xff-x)
+		}
+		if got, err := table.Retrieve(uint64(x)); err != nil || !bytes.Equal(got, exp) {
+			t.Fatalf("item %d mismatch: have %x (%v), want %x", x, got, err, exp)
+		}
+	}
+}
+
+// Tests that random sized items are stored and retrieved correctly.
+func TestFreezerRandomSizes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freezer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	table := newTestTable(t, dir, "random", 1024, false)
+	defer table.Close()
+
+	items := make([][]byte, 100)
+	for i := range items {
+		items[i] = make([]byte, rand.Intn(512))
+		rand.Read(items[i])
+		if err := table.Append(uint64(i), items[i]); err != nil {
+			t.Fatalf("failed to append item %d: %v", i, err)
+		}
+	}
+	for i, exp := range items {
+		if got, err := table.Retrieve(uint64(i)); err != nil || !bytes.Equal(got, exp) {
+			t.Fatalf("item %d mismatch: have %x (%v), want %x", i, got, err, exp)
+		}
+	}
+}
@@ -0,0 +1,301 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// makeTestChain writes a canonical chain of n blocks, along with their receipts
+// and total difficulties, into the database and returns the blocks.
+func makeTestChain(db ethdb.Database, n int) []*types.Block {
+	var (
+		blocks []*types.Block
+		parent common.Hash
+	)
+	for i := 0; i < n; i++ {
+		header := &types.Header{
+			ParentHash: parent,
+			Number:     big.NewInt(int64(i)),
+			Difficulty: big.NewInt(1),
+			Extra:      []byte("test block"),
+		}
+		block := types.NewBlockWithHeader(header).WithBody(nil, []*types.Header{{Number: big.NewInt(int64(i))}})
+		receipts := types.Receipts{{CumulativeGasUsed: uint64(i), Logs: []*types.Log{}}}
+
+		WriteBlock(db, block)
+		WriteReceipts(db, block.Hash(), block.NumberU64(), receipts)
+		WriteTd(db, block.Hash(), block.NumberU64(), big.NewInt(int64(i+1)))
+		WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+
+		blocks = append(blocks, block)
+		parent = block.Hash()
+	}
+	WriteHeadHeaderHash(db, parent)
+	WriteHeadBlockHash(db, parent)
+	return blocks
+}
+
+// Tests that ancient blocks are moved from the key-value store into the freezer
+// and are still transparently served by the chain accessors.
+func TestFreezerMigration(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freezer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	kvdb := ethdb.NewMemDatabase()
+	blocks := makeTestChain(kvdb, 64)
+
+	db, err := NewDatabaseWithFreezer(kvdb, dir, "", 16, false)
+	if err != nil {
+		t.Fatalf("failed to create freezer database: %v", err)
+	}
+	defer db.Close()
+
+	// Wait for the background freezer to migrate the ancient blocks
+	ancients := db.(ethdb.AncientReader)
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		if frozen, _ := ancients.Ancients(); frozen == 47 {
+			break
+		}
+		if time.Since(start) > 5*time.Second {
+			frozen, _ := ancients.Ancients()
+			t.Fatalf("ancient blocks not frozen: have %d, want %d", frozen, 47)
+		}
+	}
+	// Wait for the frozen blocks to be deleted from the key-value store
+	for start := time.Now(); HasBody(kvdb, blocks[46].Hash(), 46); time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("frozen blocks not removed from key-value store")
+		}
+	}
+	for i, block := range blocks {
+		hash, number := block.Hash(), block.NumberU64()
+
+		// Frozen blocks apart from the genesis must be gone from the key-value store
+		if frozen := i > 0 && i < 47; frozen == HasHeader(kvdb, hash, number) {
+			t.Errorf("block %d: key-value presence mismatch: frozen %v", i, frozen)
+		}
+		// All blocks must be accessible through the freezer database
+		if have := ReadCanonicalHash(db, number); have != hash {
+			t.Errorf("block %d: canonical hash mismatch: have %x, want %x", i, have, hash)
+		}
+		if !HasHeader(db, hash, number) || !HasBody(db, hash, number) || !HasReceipts(db, hash, number) {
+			t.Errorf("block %d: chain data missing", i)
+		}
+		if have := ReadBlock(db, hash, number); have == nil || have.Hash() != hash || len(have.Uncles()) != 1 {
+			t.Errorf("block %d: block mismatch: have %v", i, have)
+		}
+		if have := ReadTd(db, hash, number); have == nil || have.Int64() != int64(i+1) {
+			t.Errorf("block %d: total difficulty mismatch: have %v, want %d", i, have, i+1)
+		}
+		if have := ReadReceipts(db, hash, number); len(have) != 1 || have[0].CumulativeGasUsed != uint64(i) {
+			t.Errorf("block %d: receipts mismatch: have %v", i, have)
+		}
+		if number := ReadHeaderNumber(db, hash); number == nil || *number != uint64(i) {
+			t.Errorf("block %d: hash to number mapping mismatch: have %v", i, number)
+		}
+		// Lookups with a non-canonical hash must not be served from the freezer
+		if HasHeader(db, common.Hash{0xff}, number) || ReadHeaderRLP(db, common.Hash{0xff}, number) != nil {
+			t.Errorf("block %d: non-canonical header served", i)
+		}
+	}
+	// Truncate the ancients and ensure the data is gone
+	if err := db.(ethdb.AncientWriter).TruncateAncients(10); err != nil {
+		t.Fatalf("failed to truncate ancients: %v", err)
+	}
+	if HasHeader(db, blocks[10].Hash(), 10) {
+		t.Errorf("truncated block still available")
+	}
+	if !HasHeader(db, blocks[9].Hash(), 9) {
+		t.Errorf("non-truncated block missing")
+	}
+}
+
+// Tests that side chain blocks at frozen heights are removed from the key-value
+// store along with the canonical ones, while forks above the threshold are kept.
+func TestFreezerSideChainCleanup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freezer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	kvdb := ethdb.NewMemDatabase()
+	blocks := makeTestChain(kvdb, 64)
+
+	// Insert a fork block next to a soon frozen and a recent canonical block
+	var forks []*types.Block
+	for _, number := range []int{5, 60} {
+		header := &types.Header{
+			ParentHash: blocks[number-1].Hash(),
+			Number:     big.NewInt(int64(number)),
+			Difficulty: big.NewInt(1),
+			Extra:      []byte("side block"),
+		}
+		fork := types.NewBlockWithHeader(header)
+		WriteBlock(kvdb, fork)
+		WriteReceipts(kvdb, fork.Hash(), fork.NumberU64(), types.Receipts{})
+		WriteTd(kvdb, fork.Hash(), fork.NumberU64(), big.NewInt(int64(number+1)))
+		forks = append(forks, fork)
+	}
+	if hashes := ReadAllHashes(kvdb, 5); len(hashes) != 2 {
+		t.Fatalf("hash count mismatch at fork height: have %d, want 2", len(hashes))
+	}
+	db, err := NewDatabaseWithFreezer(kvdb, dir, "", 16, false)
+	if err != nil {
+		t.Fatalf("failed to create freezer database: %v", err)
+	}
+	defer db.Close()
+
+	// Wait for the frozen blocks to be deleted from the key-value store
+	for start := time.Now(); HasHeader(kvdb, forks[0].Hash(), 5); time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("frozen side chain block not removed from key-value store")
+		}
+	}
+	if hashes := ReadAllHashes(kvdb, 5); len(hashes) != 0 {
+		t.Errorf("headers left at frozen height: %x", hashes)
+	}
+	if HasBody(kvdb, forks[0].Hash(), 5) || ReadTdRLP(kvdb, forks[0].Hash(), 5) != nil || ReadHeaderNumber(kvdb, forks[0].Hash()) != nil {
+		t.Errorf("frozen side chain block data left in key-value store")
+	}
+	if !HasHeader(db, blocks[5].Hash(), 5) {
+		t.Errorf("frozen canonical block missing")
+	}
+	// The fork above the freezer threshold must be left alone
+	if !HasHeader(kvdb, forks[1].Hash(), 60) || !HasBody(kvdb, forks[1].Hash(), 60) {
+		t.Errorf("recent side chain block removed")
+	}
+	if hashes := ReadAllHashes(kvdb, 60); len(hashes) != 2 {
+		t.Errorf("hash count mismatch at recent fork height: have %d, want 2", len(hashes))
+	}
+}
+
+// Tests that a freezer belonging to a different chain is rejected.
+func TestFreezerGenesisMismatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freezer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	frdb, err := newFreezer(dir, "", 0)
+	if err != nil {
+		t.Fatalf("failed to create freezer: %v", err)
+	}
+	frdb.AppendAncient(0, common.Hash{0x01}.Bytes(), []byte{0xc0}, []byte{0xc0}, []byte{0xc0}, []byte{0x01})
+	frdb.Close()
+
+	kvdb := ethdb.NewMemDatabase()
+	makeTestChain(kvdb, 1)
+
+	if _, err := NewDatabaseWithFreezer(kvdb, dir, "", 0, false); err == nil {
+		t.Fatalf("mismatching freezer accepted")
+	}
+}
+
+// Tests that a read-only freezer database doesn't migrate any chain data.
+func TestFreezerReadonly(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freezer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	kvdb := ethdb.NewMemDatabase()
+	blocks := makeTestChain(kvdb, 64)
+
+	db, err := NewDatabaseWithFreezer(kvdb, dir, "", 16, true)
+	if err != nil {
+		t.Fatalf("failed to create freezer database: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if frozen, _ := db.(ethdb.AncientReader).Ancients(); frozen != 0 {
+		t.Errorf("read-only freezer migrated %d blocks", frozen)
+	}
+	if !HasBody(kvdb, blocks[1].Hash(), 1) {
+		t.Errorf("ancient block removed from key-value store")
+	}
+	db.Close()
+}
+
+// Tests that closing the database while the freezer is migrating a batch stops
+// the migration without losing or duplicating any chain data.
+func TestFreezerCloseDuringFreeze(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freezer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	kvdb := ethdb.NewMemDatabase()
+	blocks := makeTestChain(kvdb, 2048)
+
+	db, err := NewDatabaseWithFreezer(kvdb, dir, "", 16, false)
+	if err != nil {
+		t.Fatalf("failed to create freezer database: %v", err)
+	}
+	// Close the freezer (leaving the key-value store open) while it is migrating
+	frdb := db.(*freezerdb).freezer
+	for start := time.Now(); ; time.Sleep(time.Millisecond) {
+		if frozen, _ := frdb.Ancients(); frozen > 0 {
+			break
+		}
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("freezer didn't start migrating")
+		}
+	}
+	if err := frdb.Close(); err != nil {
+		t.Fatalf("failed to close freezer: %v", err)
+	}
+	if err := frdb.Close(); err != nil {
+		t.Fatalf("failed to close freezer twice: %v", err)
+	}
+	// Every block must be either in the freezer or in the key-value store
+	reopened, err := newFreezer(dir, "", 16)
+	if err != nil {
+		t.Fatalf("failed to reopen freezer: %v", err)
+	}
+	defer reopened.Close()
+
+	frozen, _ := reopened.Ancients()
+	for i, block := range blocks {
+		hash, number := block.Hash(), block.NumberU64()
+		if number > 0 && number < frozen {
+			if HasBody(kvdb, hash, number) {
+				t.Fatalf("block %d: frozen block left in key-value store", i)
+			}
+			if have, _ := reopened.Ancient(freezerHashTable, number); !bytes.Equal(have, hash[:]) {
+				t.Fatalf("block %d: frozen hash mismatch: have %x, want %x", i, have, hash)
+			}
+		} else if !HasBody(kvdb, hash, number) {
+			t.Fatalf("block %d: unfrozen block missing from key-value store", i)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pruner
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// stateBloomHashes is the number of hash functions used by the state bloom. The
+// keys inserted are all Keccak256 hashes, so instead of hashing them again, the
+// bloom uses non-overlapping 8 byte windows of the key as the hash functions.
+const stateBloomHashes = 4
+
+// stateBloomMagic is the header of a persisted state bloom, used to detect if
+// some unrelated or corrupted file was found in place of a bloom.
+var stateBloomMagic = []byte("statebloom-v1")
+
+// errStateBloomCorrupted is returned if a persisted state bloom cannot be loaded
+// because its content doesn't match its format.
+var errStateBloomCorrupted = errors.New("state bloom corrupted")
+
+// stateBloom is a bloom filter tracking the hashes of all the trie nodes and
+// contract codes that need to be retained during pruning. False positives are
+// acceptable as they only result in some stale data surviving a pruning run,
+// false negatives cannot happen.
+type stateBloom struct {
+	bits []uint64
+}
+
+// newStateBloom creates an empty state bloom of the given size in megabytes.
+func newStateBloom(size uint64) *stateBloom {
+	if size == 0 {
+		size = 1
+	}
+	return &stateBloom{
+		bits: make([]uint64, size*1024*1024/8),
+	}
+}
+
+// positions returns the bit positions in the bloom belonging to a key.
+func (b *stateBloom) positions(key []byte) [stateBloomHashes]uint64 {
+	var (
+		pos   [stateBloomHashes]uint64
+		nbits = uint64(len(b.bits)) * 64
+	)
+	for i := 0; i < stateBloomHashes; i++ {
+		pos[i] = binary.BigEndian.Uint64(key[i*8:]) % nbits
+	}
+	return pos
+}
+
+// Put inserts a 32 byte hash key into the bloom.
+func (b *stateBloom) Put(key []byte) {
+	for _, pos := range b.positions(key) {
+		b.bits[pos/64] |= 1 << (pos % 64)
+	}
+}
+
+// Contain returns whether a 32 byte hash key might have been inserted into the
+// bloom. A negative answer is definitive.
+func (b *stateBloom) Contain(key []byte) bool {
+	for _, pos := range b.positions(key) {
+		if b.bits[pos/64]&(1<<(pos%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Commit persists the bloom along with the state root it was generated for into
+// the given file. The bloom is written into a temporary file first and moved
+// to its final location only once fully flushed, so a persisted bloom is always
+// complete.
+func (b *stateBloom) Commit(filename string, root common.Hash) error {
+	tmp := filename + ".tmp"
+
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if err := b.write(f, root); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
+
+// write serializes the bloom into the given writer.
+func (b *stateBloom) write(w io.Writer, root common.Hash) error {
+	buf := bufio.NewWriter(w)
+
+	var word [8]byte
+	buf.Write(stateBloomMagic)
+	buf.Write(root.Bytes())
+	binary.BigEndian.PutUint64(word[:], uint64(len(b.bits)))
+	buf.Write(word[:])
+
+	for _, bits := range b.bits {
+		binary.BigEndian.PutUint64(word[:], bits)
+		if _, err := buf.Write(word[:]); err != nil {
+			return err
+		}
+	}
+	return buf.Flush()
+}
+
+// loadStateBloom reads a persisted state bloom from the given file, returning
+// the bloom itself and the state root it was generated for.
+func loadStateBloom(filename string) (*stateBloom, common.Hash, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, common.Hash{}, err
+	}
+	defer f.Close()
+
+	var (
+		buf    = bufio.NewReader(f)
+		header = make([]byte, len(stateBloomMagic)+common.HashLength+8)
+	)
+	if _, err := io.ReadFull(buf, header); err != nil {
+		return nil, common.Hash{}, errStateBloomCorrupted
+	}
+	if string(header[:len(stateBloomMagic)]) != string(stateBloomMagic) {
+		return nil, common.Hash{}, errStateBloomCorrupted
+	}
+	root := common.BytesToHash(header[len(stateBloomMagic) : len(stateBloomMagic)+common.HashLength])
+	words := binary.BigEndian.Uint64(header[len(stateBloomMagic)+common.HashLength:])
+
+	// Sanity check the bloom size before allocating any memory for it
+	if stat, err := f.Stat(); err != nil {
+		return nil, common.Hash{}, err
+	} else if uint64(stat.Size()) != uint64(len(header))+words*8 || words == 0 {
+		return nil, common.Hash{}, fmt.Errorf("%v: size mismatch", errStateBloomCorrupted)
+	}
+	bloom := &stateBloom{bits: make([]uint64, words)}
+
+	var word [8]byte
+	for i := range bloom.bits {
+		if _, err := io.ReadFull(buf, word[:]); err != nil {
+			return nil, common.Hash{}, errStateBloomCorrupted
+		}
+		bloom.bits[i] = binary.BigEndian.Uint64(word[:])
+	}
+	return bloom, root, nil
+}
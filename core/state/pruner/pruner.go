@@ -0,0 +1,300 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package pruner implements offline pruning of stale state data from the chain
+// database.
+package pruner
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// stateBloomFileName is the filename of the state bloom persisted into the
+	// data directory once all the live state was marked. Its presence signals
+	// that a pruning run was started but not finished.
+	stateBloomFileName = "statebloom.bf"
+
+	// defaultBloomSize is the default size of the state bloom in megabytes.
+	defaultBloomSize = 2048
+
+	// maxPruneDepth is the number of most recent canonical blocks whose states
+	// are permitted as pruning targets. It matches the number of tries a full
+	// node keeps in memory, the oldest of which is persisted on shutdown.
+	maxPruneDepth = 128
+)
+
+var (
+	// errNoHeadBlock is returned if the head block of the chain cannot be found.
+	errNoHeadBlock = errors.New("head block missing")
+
+	// errStaleRoot is returned if the pruning target is neither the state of the
+	// head block nor that of one of the recent canonical blocks.
+	errStaleRoot = fmt.Errorf("state root is neither the head state nor one of the last %d canonical states", maxPruneDepth)
+)
+
+// Pruner is an offline tool to prune the stale state data from the key-value
+// store. All trie nodes and contract codes reachable from the target state root
+// (and the genesis state) are marked in a bloom filter, after which everything
+// else which looks like state data is deleted.
+//
+// The pruning is resumable: once the marking is done, the bloom is persisted
+// into the data directory and only removed after the sweep has completed. If
+// the process is interrupted in between, the sweep is continued by calling
+// RecoverPruning before the database is used again.
+type Pruner struct {
+	db        ethdb.Database
+	datadir   string
+	bloomSize uint64 // Size of the state bloom in megabytes
+}
+
+// NewPruner creates a state pruner for the given database, storing its progress
+// marker in datadir. The bloomSize is the memory allowance of the state bloom in
+// megabytes, larger blooms leave less stale data behind.
+func NewPruner(db ethdb.Database, datadir string, bloomSize uint64) *Pruner {
+	if bloomSize == 0 {
+		bloomSize = defaultBloomSize
+	}
+	return &Pruner{
+		db:        db,
+		datadir:   datadir,
+		bloomSize: bloomSize,
+	}
+}
+
+// Prune deletes all the state data from the database which is not reachable from
+// the given state root or the genesis state. If the root is empty, the state of
+// the current head block is retained.
+//
+// Only the state of the head block or of one of the last maxPruneDepth canonical
+// blocks may be retained, anything else is rejected. If the state of an older
+// block is retained, the head of the chain is rewound to that block before any
+// data is deleted, as the states above it do not survive the pruning.
+func (p *Pruner) Prune(root common.Hash) error {
+	// Finish any previously interrupted pruning run first, the database might
+	// miss some data otherwise needed to iterate the new target.
+	if err := RecoverPruning(p.datadir, p.db); err != nil {
+		return err
+	}
+	head := rawdb.ReadHeadBlockHash(p.db)
+	if head == (common.Hash{}) {
+		return errNoHeadBlock
+	}
+	number := rawdb.ReadHeaderNumber(p.db, head)
+	if number == nil {
+		return errNoHeadBlock
+	}
+	header := rawdb.ReadHeader(p.db, head, *number)
+	if header == nil {
+		return errNoHeadBlock
+	}
+	if root == (common.Hash{}) {
+		root = header.Root
+	}
+	target, err := findTarget(p.db, header, root)
+	if err != nil {
+		return err
+	}
+	bloom := newStateBloom(p.bloomSize)
+
+	// Mark the target state, followed by the genesis state if available
+	start := time.Now()
+	if err := markState(p.db, bloom, root); err != nil {
+		return fmt.Errorf("failed to mark state %x: %v", root, err)
+	}
+	if genesis := rawdb.ReadCanonicalHash(p.db, 0); genesis != (common.Hash{}) {
+		if header := rawdb.ReadHeader(p.db, genesis, 0); header != nil && header.Root != root {
+			if err := markState(p.db, bloom, header.Root); err != nil {
+				log.Warn("Failed to mark genesis state", "root", header.Root, "err", err)
+			}
+		}
+	}
+	log.Info("Marked live state", "root", root, "elapsed", common.PrettyDuration(time.Since(start)))
+
+	// Rewind the chain to the block owning the retained state. This needs to be
+	// done before the bloom is persisted, as from there on the sweep will run to
+	// completion, even across restarts.
+	if target.Hash() != header.Hash() {
+		if err := rewindHead(p.db, header, target); err != nil {
+			return err
+		}
+	}
+	// Persist the bloom so the sweep can be resumed if interrupted
+	filename := filepath.Join(p.datadir, stateBloomFileName)
+	if err := bloom.Commit(filename, root); err != nil {
+		return err
+	}
+	return sweep(p.db, bloom, filename, root)
+}
+
+// findTarget walks the canonical chain back from the head block, looking for the
+// block owning the given state root within the last maxPruneDepth blocks.
+func findTarget(db ethdb.Database, head *types.Header, root common.Hash) (*types.Header, error) {
+	target := head
+	for target.Root != root {
+		number := target.Number.Uint64()
+		if number == 0 || head.Number.Uint64()-number+1 >= maxPruneDepth {
+			return nil, errStaleRoot
+		}
+		if target = rawdb.ReadHeader(db, target.ParentHash, number-1); target == nil {
+			return nil, fmt.Errorf("canonical header #%d missing", number-1)
+		}
+	}
+	return target, nil
+}
+
+// rewindHead sets the head of the chain back to the given ancestor of the current
+// head block, dropping the canonical mapping of the blocks above it.
+func rewindHead(db ethdb.Database, head *types.Header, target *types.Header) error {
+	log.Warn("Rewinding chain to retained state", "number", target.Number, "hash", target.Hash(), "dropped", head.Number.Uint64()-target.Number.Uint64())
+
+	batch := db.NewBatch()
+	rawdb.WriteHeadBlockHash(batch, target.Hash())
+	rawdb.WriteHeadFastBlockHash(batch, target.Hash())
+	rawdb.WriteHeadHeaderHash(batch, target.Hash())
+	for number := target.Number.Uint64() + 1; number <= head.Number.Uint64(); number++ {
+		rawdb.DeleteCanonicalHash(batch, number)
+	}
+	return batch.Write()
+}
+
+// RecoverPruning checks whether a previous pruning run was interrupted after the
+// live state was already marked, and if so, finishes sweeping the stale data.
+// It must be called before the database is opened for regular use, otherwise
+// state data written in the mean time would be deleted.
+func RecoverPruning(datadir string, db ethdb.Database) error {
+	if datadir == "" {
+		return nil
+	}
+	filename := filepath.Join(datadir, stateBloomFileName)
+	if !common.FileExist(filename) {
+		return nil
+	}
+	bloom, root, err := loadStateBloom(filename)
+	if err != nil {
+		return fmt.Errorf("failed to load state bloom %s: %v", filename, err)
+	}
+	log.Info("Resuming interrupted state pruning", "root", root)
+	return sweep(db, bloom, filename, root)
+}
+
+// markState iterates over all the trie nodes and contract codes reachable from
+// the given state root and inserts their hashes into the bloom.
+func markState(db ethdb.Database, bloom *stateBloom, root common.Hash) error {
+	statedb, err := state.New(root, state.NewDatabase(db))
+	if err != nil {
+		return err
+	}
+	var (
+		nodes  int
+		start  = time.Now()
+		logged = time.Now()
+		it     = state.NewNodeIterator(statedb)
+	)
+	for it.Next() {
+		// Embedded nodes have no hash, they are stored within their parents
+		if it.Hash == (common.Hash{}) {
+			continue
+		}
+		bloom.Put(it.Hash.Bytes())
+		nodes++
+
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Marking live state", "root", root, "nodes", nodes, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	return it.Error
+}
+
+// sweep deletes all the state data from the database not contained in the bloom
+// and compacts the database afterwards. The persisted bloom is only removed once
+// all the stale data is gone, so an interrupted sweep can be resumed.
+func sweep(db ethdb.Database, bloom *stateBloom, filename string, root common.Hash) error {
+	var (
+		count  int
+		size   common.StorageSize
+		start  = time.Now()
+		logged = time.Now()
+		batch  = db.NewBatch()
+		it     = db.NewIterator()
+	)
+	for it.Next() {
+		// Trie nodes and contract codes are keyed by their plain 32 byte hash,
+		// everything else in the database uses some prefixed schema.
+		key := it.Key()
+		if len(key) != common.HashLength || bloom.Contain(key) {
+			continue
+		}
+		count++
+		size += common.StorageSize(len(key) + len(it.Value()))
+		batch.Delete(key)
+
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				it.Release()
+				return err
+			}
+			batch.Reset()
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Pruning stale state", "root", root, "count", count, "size", size, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	it.Release()
+	if err := it.Error(); err != nil {
+		return err
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	log.Info("Pruned stale state", "root", root, "count", count, "size", size, "elapsed", common.PrettyDuration(time.Since(start)))
+
+	// All stale data deleted, the sweep need not be resumed any more
+	if err := os.Remove(filename); err != nil {
+		return err
+	}
+	// Compact the hash keyed part of the database to reclaim the disk space
+	cstart := time.Now()
+	for b := 0x00; b <= 0xf0; b += 0x10 {
+		var (
+			start = []byte{byte(b)}
+			end   = []byte{byte(b + 0x10)}
+		)
+		if b == 0xf0 {
+			end = nil
+		}
+		log.Info("Compacting database", "range", fmt.Sprintf("%#x-%#x", start, end), "elapsed", common.PrettyDuration(time.Since(cstart)))
+		if err := db.Compact(start, end); err != nil {
+			log.Error("Database compaction failed", "err", err)
+			return err
+		}
+	}
+	log.Info("Database compaction finished", "elapsed", common.PrettyDuration(time.Since(cstart)))
+	return nil
+}
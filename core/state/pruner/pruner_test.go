@@ -0,0 +1,305 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pruner
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// makeTestState creates a state with a number of accounts, some with code and
+// storage, commits it to disk and returns its root. The seed differentiates the
+// states so that they share only a part of their data.
+func makeTestState(t *testing.T, db ethdb.Database, seed byte) common.Hash {
+	sdb := state.NewDatabase(db)
+	statedb, _ := state.New(common.Hash{}, sdb)
+
+	for i := byte(0); i < 64; i++ {
+		addr := common.BytesToAddress([]byte{i})
+		statedb.SetBalance(addr, big.NewInt(int64(i)+int64(seed)))
+		if i%4 == 0 {
+			statedb.SetCode(addr, []byte{i, seed, 0x60, 0x00})
+			statedb.SetState(addr, common.Hash{i}, common.Hash{seed})
+		}
+	}
+	root, err := statedb.Commit(false)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	if err := sdb.TrieDB().Commit(root, false); err != nil {
+		t.Fatalf("failed to flush state: %v", err)
+	}
+	return root
+}
+
+// makeTestChain writes a canonical chain of headers on top of an empty genesis
+// state, one for each of the given state roots, and makes its last block the
+// head of the chain.
+func makeTestChain(db ethdb.Database, roots ...common.Hash) []*types.Header {
+	headers := []*types.Header{{Number: big.NewInt(0), Root: types.EmptyRootHash}}
+	for i, root := range roots {
+		headers = append(headers, &types.Header{
+			ParentHash: headers[i].Hash(),
+			Number:     big.NewInt(int64(i + 1)),
+			Root:       root,
+		})
+	}
+	for _, header := range headers {
+		rawdb.WriteHeader(db, header)
+		rawdb.WriteCanonicalHash(db, header.Hash(), header.Number.Uint64())
+	}
+	head := headers[len(headers)-1].Hash()
+	rawdb.WriteHeadBlockHash(db, head)
+	rawdb.WriteHeadFastBlockHash(db, head)
+	rawdb.WriteHeadHeaderHash(db, head)
+	return headers
+}
+
+// checkHeadState ensures that the state of the head block is fully available.
+func checkHeadState(db ethdb.Database) error {
+	head := rawdb.ReadHeadBlockHash(db)
+	number := rawdb.ReadHeaderNumber(db, head)
+	if number == nil {
+		return errNoHeadBlock
+	}
+	return checkState(db, rawdb.ReadHeader(db, head, *number).Root)
+}
+
+// checkState iterates over the entire state, failing if anything is missing.
+func checkState(db ethdb.Database, root common.Hash) error {
+	statedb, err := state.New(root, state.NewDatabase(db))
+	if err != nil {
+		return err
+	}
+	it := state.NewNodeIterator(statedb)
+	for it.Next() {
+	}
+	return it.Error
+}
+
+// countHashKeys returns the number of trie nodes and contract codes in the
+// database.
+func countHashKeys(db ethdb.Database) int {
+	it := db.NewIterator()
+	defer it.Release()
+
+	var count int
+	for it.Next() {
+		if len(it.Key()) == common.HashLength {
+			count++
+		}
+	}
+	return count
+}
+
+func TestPrune(t *testing.T) {
+	datadir, err := ioutil.TempDir("", "pruner-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(datadir)
+
+	db := ethdb.NewMemDatabase()
+	stale := makeTestState(t, db, 1)
+	before := countHashKeys(db)
+	live := makeTestState(t, db, 2)
+	makeTestChain(db, stale, live)
+
+	// Prune everything but the head state
+	if err := NewPruner(db, datadir, 1).Prune(common.Hash{}); err != nil {
+		t.Fatalf("failed to prune state: %v", err)
+	}
+	if err := checkHeadState(db); err != nil {
+		t.Fatalf("head state corrupted: %v", err)
+	}
+	if err := checkState(db, live); err != nil {
+		t.Fatalf("live state corrupted: %v", err)
+	}
+	if err := checkState(db, stale); err == nil {
+		t.Fatalf("stale state not pruned")
+	}
+	if after := countHashKeys(db); after > before {
+		t.Errorf("too much data retained: have %d nodes, want at most %d", after, before)
+	}
+	if common.FileExist(filepath.Join(datadir, stateBloomFileName)) {
+		t.Errorf("state bloom not removed after pruning")
+	}
+}
+
+// Tests that pruning to the state of a recent canonical block rewinds the head
+// of the chain to that block, keeping the head state readable.
+func TestPruneRewind(t *testing.T) {
+	datadir, err := ioutil.TempDir("", "pruner-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(datadir)
+
+	db := ethdb.NewMemDatabase()
+	live := makeTestState(t, db, 1)
+	stale := makeTestState(t, db, 2)
+	headers := makeTestChain(db, live, stale)
+
+	if err := NewPruner(db, datadir, 1).Prune(live); err != nil {
+		t.Fatalf("failed to prune state: %v", err)
+	}
+	if head := rawdb.ReadHeadBlockHash(db); head != headers[1].Hash() {
+		t.Fatalf("head block mismatch: have %x, want %x", head, headers[1].Hash())
+	}
+	if head := rawdb.ReadHeadHeaderHash(db); head != headers[1].Hash() {
+		t.Errorf("head header mismatch: have %x, want %x", head, headers[1].Hash())
+	}
+	if hash := rawdb.ReadCanonicalHash(db, 2); hash != (common.Hash{}) {
+		t.Errorf("rewound block still canonical: %x", hash)
+	}
+	if err := checkHeadState(db); err != nil {
+		t.Fatalf("head state corrupted: %v", err)
+	}
+	if err := checkState(db, stale); err == nil {
+		t.Fatalf("stale state not pruned")
+	}
+}
+
+// Tests that states which are not owned by any of the recent canonical blocks
+// are rejected as pruning targets, leaving the database untouched.
+func TestPruneNonCanonical(t *testing.T) {
+	datadir, err := ioutil.TempDir("", "pruner-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(datadir)
+
+	db := ethdb.NewMemDatabase()
+	head := makeTestState(t, db, 1)
+	old := makeTestState(t, db, 2)
+	side := makeTestState(t, db, 3)
+
+	// Build a chain with an old block owning the second state, followed by more
+	// blocks than permitted to rewind. The third state is owned by no block.
+	roots := []common.Hash{old}
+	for i := 0; i < maxPruneDepth; i++ {
+		roots = append(roots, head)
+	}
+	makeTestChain(db, roots...)
+
+	before := countHashKeys(db)
+	if err := NewPruner(db, datadir, 1).Prune(old); err != errStaleRoot {
+		t.Fatalf("old canonical root: error mismatch: have %v, want %v", err, errStaleRoot)
+	}
+	if err := NewPruner(db, datadir, 1).Prune(side); err != errStaleRoot {
+		t.Fatalf("side root: error mismatch: have %v, want %v", err, errStaleRoot)
+	}
+	if after := countHashKeys(db); after != before {
+		t.Errorf("rejected pruning deleted data: have %d nodes, want %d", after, before)
+	}
+	if err := checkHeadState(db); err != nil {
+		t.Fatalf("head state corrupted: %v", err)
+	}
+	for _, root := range []common.Hash{old, side} {
+		if err := checkState(db, root); err != nil {
+			t.Fatalf("state %x corrupted: %v", root, err)
+		}
+	}
+	if common.FileExist(filepath.Join(datadir, stateBloomFileName)) {
+		t.Errorf("state bloom persisted for rejected pruning")
+	}
+}
+
+func TestPruneRecovery(t *testing.T) {
+	datadir, err := ioutil.TempDir("", "pruner-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(datadir)
+
+	db := ethdb.NewMemDatabase()
+	stale := makeTestState(t, db, 1)
+	live := makeTestState(t, db, 2)
+
+	// Simulate a pruning interrupted right after the marking phase
+	bloom := newStateBloom(1)
+	if err := markState(db, bloom, live); err != nil {
+		t.Fatalf("failed to mark state: %v", err)
+	}
+	filename := filepath.Join(datadir, stateBloomFileName)
+	if err := bloom.Commit(filename, live); err != nil {
+		t.Fatalf("failed to persist bloom: %v", err)
+	}
+	// Recovery should finish the sweep and clean up after itself
+	if err := RecoverPruning(datadir, db); err != nil {
+		t.Fatalf("failed to recover pruning: %v", err)
+	}
+	if err := checkState(db, live); err != nil {
+		t.Fatalf("live state corrupted: %v", err)
+	}
+	if err := checkState(db, stale); err == nil {
+		t.Fatalf("stale state not pruned")
+	}
+	if common.FileExist(filename) {
+		t.Errorf("state bloom not removed after recovery")
+	}
+	// Recovering without an interrupted pruning should be a noop
+	if err := RecoverPruning(datadir, db); err != nil {
+		t.Fatalf("failed to recover without pruning: %v", err)
+	}
+}
+
+func TestStateBloomPersistence(t *testing.T) {
+	datadir, err := ioutil.TempDir("", "pruner-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(datadir)
+
+	bloom := newStateBloom(1)
+	for i := byte(0); i < 100; i++ {
+		bloom.Put(crypto.Keccak256([]byte{i}))
+	}
+	filename := filepath.Join(datadir, stateBloomFileName)
+	if err := bloom.Commit(filename, common.Hash{0xff}); err != nil {
+		t.Fatalf("failed to persist bloom: %v", err)
+	}
+	loaded, root, err := loadStateBloom(filename)
+	if err != nil {
+		t.Fatalf("failed to load bloom: %v", err)
+	}
+	if root != (common.Hash{0xff}) {
+		t.Errorf("root mismatch: have %x, want %x", root, common.Hash{0xff})
+	}
+	for i := byte(0); i < 100; i++ {
+		if !loaded.Contain(crypto.Keccak256([]byte{i})) {
+			t.Errorf("item %d missing from loaded bloom", i)
+		}
+	}
+	// Truncated blooms must be rejected
+	if err := os.Truncate(filename, 100); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := loadStateBloom(filename); err == nil {
+		t.Errorf("truncated bloom loaded")
+	}
+}
@@ -0,0 +1,290 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"sync"
+	"time"
+
+	"github.com/allegro/bigcache"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// diskLayer is a low level persistent snapshot built on top of a key-value store.
+type diskLayer struct {
+	diskdb ethdb.Database     // Key-value store containing the base snapshot
+	triedb *trie.Database     // Trie node cache for reconstruction purposes
+	cache  *bigcache.BigCache // Cache to avoid hitting the disk for direct access
+	size   int                // Megabytes allowance of the cache, kept for derived layers
+
+	root  common.Hash // Root hash of the base snapshot
+	stale bool        // Signals that the layer became stale (state progressed)
+
+	genMarker  []byte             // Marker for the state that's indexed during initial layer generation, nil if done
+	genPending chan struct{}      // Notification channel when generation is done (test synchronicity)
+	genAbort   chan chan struct{} // Notification channel to abort generating the snapshot in this layer
+
+	lock sync.RWMutex
+}
+
+// newDiskLayer creates a disk layer for the given root with the given generation
+// marker, nil meaning the layer is fully generated.
+func newDiskLayer(diskdb ethdb.Database, triedb *trie.Database, cache int, root common.Hash, marker []byte) *diskLayer {
+	var entries *bigcache.BigCache
+	if cache > 0 {
+		entries, _ = bigcache.NewBigCache(bigcache.Config{
+			Shards:             1024,
+			LifeWindow:         time.Hour,
+			MaxEntriesInWindow: cache * 1024,
+			MaxEntrySize:       512,
+			HardMaxCacheSize:   cache,
+		})
+	}
+	return &diskLayer{
+		diskdb:    diskdb,
+		triedb:    triedb,
+		cache:     entries,
+		size:      cache,
+		root:      root,
+		genMarker: marker,
+	}
+}
+
+// Root returns root hash for which this snapshot was made.
+func (dl *diskLayer) Root() common.Hash {
+	return dl.root
+}
+
+// Stale return whether this layer has become stale (was flattened across) or if
+// it's still live.
+func (dl *diskLayer) Stale() bool {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	return dl.stale
+}
+
+// markStale flags the layer as stale, failing all subsequent reads.
+func (dl *diskLayer) markStale() {
+	dl.lock.Lock()
+	defer dl.lock.Unlock()
+
+	dl.stale = true
+}
+
+// covered returns whether the given snapshot key is already generated.
+func (dl *diskLayer) covered(key []byte) bool {
+	return dl.genMarker == nil || bytes.Compare(key, dl.genMarker) <= 0
+}
+
+// Account directly retrieves the account RLP associated with a particular
+// hash in the snapshot, as stored in the account trie.
+func (dl *diskLayer) Account(hash common.Hash) ([]byte, error) {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	// If the layer was flattened into, consider it invalid (any live reference to
+	// the original should be marked as unusable).
+	if dl.stale {
+		return nil, ErrSnapshotStale
+	}
+	// If the layer is being generated, ensure the requested hash has already been
+	// covered by the generator.
+	if !dl.covered(hash[:]) {
+		return nil, ErrNotCoveredYet
+	}
+	return dl.read(hash[:], func() []byte { return rawdb.ReadAccountSnapshot(dl.diskdb, hash) }), nil
+}
+
+// Storage directly retrieves the storage data associated with a particular hash,
+// within a particular account.
+func (dl *diskLayer) Storage(accountHash, storageHash common.Hash) ([]byte, error) {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	// If the layer was flattened into, consider it invalid (any live reference to
+	// the original should be marked as unusable).
+	if dl.stale {
+		return nil, ErrSnapshotStale
+	}
+	key := append(accountHash[:], storageHash[:]...)
+
+	// If the layer is being generated, ensure the requested hash has already been
+	// covered by the generator.
+	if !dl.covered(key) {
+		return nil, ErrNotCoveredYet
+	}
+	return dl.read(key, func() []byte { return rawdb.ReadStorageSnapshot(dl.diskdb, accountHash, storageHash) }), nil
+}
+
+// read retrieves a snapshot entry, preferring the memory cache over the disk.
+// Missing entries are cached too, as empty blobs.
+func (dl *diskLayer) read(key []byte, load func() []byte) []byte {
+	if dl.cache != nil {
+		if blob, err := dl.cache.Get(string(key)); err == nil {
+			if len(blob) == 0 {
+				return nil
+			}
+			return blob
+		}
+	}
+	blob := load()
+	if dl.cache != nil {
+		dl.cache.Set(string(key), blob)
+	}
+	if len(blob) == 0 {
+		return nil
+	}
+	return blob
+}
+
+// stopGeneration aborts the background generator of the layer, if running,
+// waiting for it to persist its progress.
+func (dl *diskLayer) stopGeneration() {
+	dl.lock.RLock()
+	abort := dl.genAbort
+	dl.lock.RUnlock()
+
+	if abort == nil {
+		return
+	}
+	done := make(chan struct{})
+	abort <- done
+	<-done
+
+	dl.lock.Lock()
+	dl.genAbort = nil
+	dl.lock.Unlock()
+}
+
+// diffToDisk merges a chain of bottom-most diff layers (ordered from the oldest
+// to the newest) into the persistent disk layer underneath them, returning the
+// new disk layer. The original disk layer and the merged diffs are marked stale.
+func diffToDisk(diffs []*diffLayer) *diskLayer {
+	base := diffs[0].parentLayer().(*diskLayer)
+
+	// Stop any running generator, it will be resumed on top of the new layer
+	base.stopGeneration()
+
+	var (
+		batch = base.diskdb.NewBatch()
+		root  = base.root
+	)
+	base.lock.Lock()
+	marker := base.genMarker
+	base.stale = true
+
+	// Start by temporarily deleting the current snapshot block marker. This
+	// ensures that in the case of a crash, the entire snapshot is invalidated.
+	rawdb.DeleteSnapshotRoot(batch)
+
+	for _, diff := range diffs {
+		diff.markStale()
+		root = diff.root
+
+		// Destructed accounts lose their entire storage, even if recreated later.
+		// The storage is iterated from disk, so flush any earlier diffs first.
+		if len(diff.destructSet) > 0 {
+			if err := batch.Write(); err != nil {
+				log.Crit("Failed to write snapshot diffs", "err", err)
+			}
+			batch.Reset()
+		}
+		for hash := range diff.destructSet {
+			if marker != nil && bytes.Compare(hash[:], marker) > 0 {
+				continue // Not yet generated, the generator will pick up the new state
+			}
+			rawdb.DeleteAccountSnapshot(batch, hash)
+			if base.cache != nil {
+				base.cache.Set(string(hash[:]), nil)
+			}
+			it := rawdb.IterateStorageSnapshots(base.diskdb, hash)
+			for it.Next() {
+				if key := it.Key(); len(key) == len(rawdb.SnapshotStoragePrefix)+2*common.HashLength {
+					batch.Delete(key)
+					if base.cache != nil {
+						base.cache.Delete(string(key[len(rawdb.SnapshotStoragePrefix):]))
+					}
+				}
+			}
+			it.Release()
+		}
+		// Push all updated accounts into the database
+		for hash, data := range diff.accountData {
+			if marker != nil && bytes.Compare(hash[:], marker) > 0 {
+				continue // Not yet generated, the generator will pick up the new state
+			}
+			if len(data) == 0 {
+				rawdb.DeleteAccountSnapshot(batch, hash)
+			} else {
+				rawdb.WriteAccountSnapshot(batch, hash, data)
+			}
+			if base.cache != nil {
+				base.cache.Set(string(hash[:]), data)
+			}
+		}
+		// Push all the storage slots into the database
+		for accountHash, storage := range diff.storageData {
+			for storageHash, data := range storage {
+				key := append(accountHash[:], storageHash[:]...)
+				if marker != nil && bytes.Compare(key, marker) > 0 {
+					continue // Not yet generated, the generator will pick up the new state
+				}
+				if len(data) == 0 {
+					rawdb.DeleteStorageSnapshot(batch, accountHash, storageHash)
+				} else {
+					rawdb.WriteStorageSnapshot(batch, accountHash, storageHash, data)
+				}
+				if base.cache != nil {
+					base.cache.Set(string(key), data)
+				}
+			}
+		}
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				log.Crit("Failed to write snapshot diffs", "err", err)
+			}
+			batch.Reset()
+		}
+	}
+	// Update the snapshot block marker and write any remainder data
+	rawdb.WriteSnapshotRoot(batch, root)
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to write leftover snapshot", "err", err)
+	}
+	base.lock.Unlock()
+
+	res := &diskLayer{
+		diskdb:    base.diskdb,
+		triedb:    base.triedb,
+		cache:     base.cache,
+		size:      base.size,
+		root:      root,
+		genMarker: marker,
+	}
+	// If snapshot generation hasn't finished yet, port over all the starts and
+	// continue where the previous round left off.
+	if marker != nil {
+		res.startGeneration(time.Now())
+	}
+	return res
+}
@@ -0,0 +1,283 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// emptyRoot is the known root hash of an empty trie.
+var emptyRoot = common.HexToHash("56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421")
+
+// journalGenerator is a disk layer entry containing the generator progress marker.
+type journalGenerator struct {
+	Done   bool // Whether the generator finished creating the snapshot
+	Marker []byte
+}
+
+// loadGenerator retrieves the snapshot generator progress from the database.
+func loadGenerator(db ethdb.Database) (*journalGenerator, error) {
+	blob := rawdb.ReadSnapshotGenerator(db)
+	if len(blob) == 0 {
+		return nil, errors.New("missing snapshot generator")
+	}
+	var generator journalGenerator
+	if err := rlp.DecodeBytes(blob, &generator); err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot generator: %v", err)
+	}
+	if generator.Done {
+		generator.Marker = nil
+	} else if generator.Marker == nil {
+		generator.Marker = []byte{}
+	}
+	return &generator, nil
+}
+
+// journalProgress persists the generator progress into the database batch.
+func journalProgress(db ethdb.Putter, marker []byte) {
+	blob, err := rlp.EncodeToBytes(&journalGenerator{Done: marker == nil, Marker: marker})
+	if err != nil {
+		panic(err) // Cannot happen, here to catch dev errors
+	}
+	rawdb.WriteSnapshotGenerator(db, blob)
+}
+
+// generateSnapshot regenerates a brand new snapshot based on an existing state
+// database and head block asynchronously. The snapshot is returned immediately
+// and generation is continued in the background until done.
+func generateSnapshot(diskdb ethdb.Database, triedb *trie.Database, cache int, root common.Hash) *diskLayer {
+	// Mark the snapshot as empty and not yet generated, wiping any old data
+	batch := diskdb.NewBatch()
+	rawdb.WriteSnapshotRoot(batch, root)
+	journalProgress(batch, []byte{})
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to write initialized state marker", "err", err)
+	}
+	base := newDiskLayer(diskdb, triedb, cache, root, []byte{})
+	base.startGeneration(time.Now())
+	return base
+}
+
+// startGeneration launches the background generator of the disk layer.
+func (dl *diskLayer) startGeneration(start time.Time) {
+	dl.lock.Lock()
+	defer dl.lock.Unlock()
+
+	dl.genPending = make(chan struct{})
+	dl.genAbort = make(chan chan struct{})
+	go dl.generate(start)
+}
+
+// generatorStats is a collection of statistics gathered by the snapshot generator
+// for logging purposes.
+type generatorStats struct {
+	start    time.Time // Timestamp when generation started
+	logged   time.Time // Timestamp when progress was last reported
+	accounts uint64    // Number of accounts indexed
+	slots    uint64    // Number of storage slots indexed
+	storage  common.StorageSize
+}
+
+// report logs the generation progress if enough time passed since the last one.
+func (gs *generatorStats) report(root common.Hash, marker []byte) {
+	if time.Since(gs.logged) < 8*time.Second {
+		return
+	}
+	log.Info("Generating state snapshot", "root", root, "at", fmt.Sprintf("%x", marker), "accounts", gs.accounts, "slots", gs.slots,
+		"storage", gs.storage, "elapsed", common.PrettyDuration(time.Since(gs.start)))
+	gs.logged = time.Now()
+}
+
+// generate is a background thread that iterates over the state and storage tries,
+// constructing the state snapshot. All the arguments are purely for statistics
+// gathering and logging, since the method surfs the blocks as they arrive, often
+// being restarted.
+func (dl *diskLayer) generate(start time.Time) {
+	stats := &generatorStats{start: start, logged: time.Now()}
+
+	// A fresh generation needs to wipe any leftover data from an older snapshot
+	if len(dl.genMarker) == 0 {
+		if !dl.wipe() {
+			return
+		}
+	}
+	accTrie, err := trie.New(dl.root, dl.triedb)
+	if err != nil {
+		log.Error("Generator failed to access account trie", "root", dl.root, "err", err)
+		dl.waitAbort()
+		return
+	}
+	var (
+		batch     = dl.diskdb.NewBatch()
+		accMarker []byte
+	)
+	if len(dl.genMarker) > 0 {
+		accMarker = dl.genMarker[:common.HashLength]
+	}
+	accIt := trie.NewIterator(accTrie.NodeIterator(accMarker))
+	for accIt.Next() {
+		accountHash := common.BytesToHash(accIt.Key)
+
+		var acc account
+		if err := rlp.DecodeBytes(accIt.Value, &acc); err != nil {
+			log.Crit("Invalid account encountered during snapshot creation", "err", err)
+		}
+		rawdb.WriteAccountSnapshot(batch, accountHash, accIt.Value)
+		stats.storage += common.StorageSize(1 + common.HashLength + len(accIt.Value))
+		stats.accounts++
+
+		// If the batch is large enough, persist it and check for abort requests
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			if dl.checkpoint(batch, accountHash[:], stats) {
+				return
+			}
+		}
+		// If the account has storage, iterate over that too
+		if acc.Root != emptyRoot {
+			storeTrie, err := trie.New(acc.Root, dl.triedb)
+			if err != nil {
+				log.Error("Generator failed to access storage trie", "account", accountHash, "root", acc.Root, "err", err)
+				dl.waitAbort()
+				return
+			}
+			var storeMarker []byte
+			if len(dl.genMarker) > common.HashLength && bytes.Equal(accountHash[:], dl.genMarker[:common.HashLength]) {
+				storeMarker = dl.genMarker[common.HashLength:]
+			}
+			storeIt := trie.NewIterator(storeTrie.NodeIterator(storeMarker))
+			for storeIt.Next() {
+				rawdb.WriteStorageSnapshot(batch, accountHash, common.BytesToHash(storeIt.Key), storeIt.Value)
+				stats.storage += common.StorageSize(1 + 2*common.HashLength + len(storeIt.Value))
+				stats.slots++
+
+				if batch.ValueSize() > ethdb.IdealBatchSize {
+					if dl.checkpoint(batch, append(accountHash[:], storeIt.Key...), stats) {
+						return
+					}
+				}
+			}
+			if storeIt.Err != nil {
+				log.Error("Generator failed to iterate storage trie", "account", accountHash, "root", acc.Root, "err", storeIt.Err)
+				dl.waitAbort()
+				return
+			}
+		}
+	}
+	if accIt.Err != nil {
+		log.Error("Generator failed to iterate account trie", "root", dl.root, "err", accIt.Err)
+		dl.waitAbort()
+		return
+	}
+	// Snapshot fully generated, set the marker to nil
+	journalProgress(batch, nil)
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to write generated snapshot", "err", err)
+	}
+	log.Info("Generated state snapshot", "accounts", stats.accounts, "slots", stats.slots,
+		"storage", stats.storage, "elapsed", common.PrettyDuration(time.Since(stats.start)))
+
+	dl.lock.Lock()
+	dl.genMarker = nil
+	close(dl.genPending)
+	dl.lock.Unlock()
+
+	// Someone will be looking for us, wait it out
+	dl.waitAbort()
+}
+
+// checkpoint flushes the generated data along with the progress marker and
+// publishes the marker to readers. It returns whether generation was aborted.
+func (dl *diskLayer) checkpoint(batch ethdb.Batch, marker []byte, stats *generatorStats) bool {
+	marker = common.CopyBytes(marker)
+
+	journalProgress(batch, marker)
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to write snapshot progress", "err", err)
+	}
+	batch.Reset()
+
+	dl.lock.Lock()
+	dl.genMarker = marker
+	dl.lock.Unlock()
+
+	stats.report(dl.root, marker)
+
+	select {
+	case abort := <-dl.genAbort:
+		close(abort)
+		return true
+	default:
+		return false
+	}
+}
+
+// wipe deletes all the snapshot data left over from a previous snapshot. It
+// returns whether the wiping completed, or was aborted in the mean time.
+func (dl *diskLayer) wipe() bool {
+	batch := dl.diskdb.NewBatch()
+	for _, prefix := range []struct {
+		prefix []byte
+		keylen int
+	}{
+		{rawdb.SnapshotAccountPrefix, len(rawdb.SnapshotAccountPrefix) + common.HashLength},
+		{rawdb.SnapshotStoragePrefix, len(rawdb.SnapshotStoragePrefix) + 2*common.HashLength},
+	} {
+		it := dl.diskdb.NewIteratorWithPrefix(prefix.prefix)
+		for it.Next() {
+			// Trie nodes may share the prefix, filter them out by key length
+			if len(it.Key()) != prefix.keylen {
+				continue
+			}
+			batch.Delete(it.Key())
+			if batch.ValueSize() > ethdb.IdealBatchSize {
+				if err := batch.Write(); err != nil {
+					log.Crit("Failed to wipe snapshot", "err", err)
+				}
+				batch.Reset()
+
+				select {
+				case abort := <-dl.genAbort:
+					it.Release()
+					close(abort)
+					return false
+				default:
+				}
+			}
+		}
+		it.Release()
+	}
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to wipe snapshot", "err", err)
+	}
+	return true
+}
+
+// waitAbort blocks until the generator is requested to terminate.
+func (dl *diskLayer) waitAbort() {
+	abort := <-dl.genAbort
+	close(abort)
+}
@@ -0,0 +1,103 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// Tests that a snapshot generated from a state trie contains exactly the accounts
+// and storage slots of the trie, and that stale leftovers are wiped.
+func TestGeneration(t *testing.T) {
+	var (
+		diskdb = ethdb.NewMemDatabase()
+		triedb = trie.NewDatabase(diskdb)
+	)
+	// Create a storage trie shared by two of the accounts
+	stTrie, _ := trie.NewSecure(common.Hash{}, triedb, 0)
+	stTrie.Update([]byte("key-1"), []byte("val-1"))
+	stTrie.Update([]byte("key-2"), []byte("val-2"))
+	stRoot, _ := stTrie.Commit(nil)
+
+	accTrie, _ := trie.NewSecure(common.Hash{}, triedb, 0)
+	accounts := map[string]*account{
+		"acc-1": {Balance: big.NewInt(1), Root: stRoot, CodeHash: crypto.Keccak256(nil)},
+		"acc-2": {Balance: big.NewInt(2), Root: emptyRoot, CodeHash: crypto.Keccak256(nil)},
+		"acc-3": {Balance: big.NewInt(3), Root: stRoot, CodeHash: crypto.Keccak256(nil)},
+	}
+	for key, acc := range accounts {
+		blob, _ := rlp.EncodeToBytes(acc)
+		accTrie.Update([]byte(key), blob)
+	}
+	root, _ := accTrie.Commit(nil)
+	triedb.Commit(root, false)
+
+	// Inject some junk that the generator needs to wipe
+	rawdb.WriteAccountSnapshot(diskdb, common.Hash{0xff}, []byte{0x01})
+	rawdb.WriteStorageSnapshot(diskdb, common.Hash{0xff}, common.Hash{0x01}, []byte{0x01})
+
+	snap := generateSnapshot(diskdb, triedb, 16, root)
+	select {
+	case <-snap.genPending:
+		// Snapshot generation succeeded
+	case <-time.After(3 * time.Second):
+		t.Fatalf("snapshot generation timed out")
+	}
+	defer snap.stopGeneration()
+
+	// Ensure the snapshot contains exactly the trie contents
+	for key, acc := range accounts {
+		hash := crypto.Keccak256Hash([]byte(key))
+		want, _ := rlp.EncodeToBytes(acc)
+		if have, err := snap.Account(hash); err != nil || !bytes.Equal(have, want) {
+			t.Errorf("account %s: have %x/%v, want %x", key, have, err, want)
+		}
+		for i, slot := range []string{"key-1", "key-2"} {
+			var want []byte
+			if acc.Root == stRoot {
+				want = []byte("val-" + string('1'+rune(i)))
+			}
+			if have, err := snap.Storage(hash, crypto.Keccak256Hash([]byte(slot))); err != nil || !bytes.Equal(have, want) {
+				t.Errorf("account %s slot %s: have %x/%v, want %x", key, slot, have, err, want)
+			}
+		}
+	}
+	if blob := rawdb.ReadAccountSnapshot(diskdb, common.Hash{0xff}); len(blob) != 0 {
+		t.Errorf("stale account not wiped: %x", blob)
+	}
+	if blob := rawdb.ReadStorageSnapshot(diskdb, common.Hash{0xff}, common.Hash{0x01}); len(blob) != 0 {
+		t.Errorf("stale slot not wiped: %x", blob)
+	}
+	// Ensure the generator persisted its completion
+	gen, err := loadGenerator(diskdb)
+	if err != nil {
+		t.Fatalf("failed to load generator progress: %v", err)
+	}
+	if !gen.Done || gen.Marker != nil {
+		t.Errorf("generator progress mismatch: have done=%v marker=%x, want done", gen.Done, gen.Marker)
+	}
+}
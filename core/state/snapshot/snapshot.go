@@ -0,0 +1,311 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package snapshot implements a flat, hash keyed view of the Ethereum state.
+//
+// The snapshot consists of a persistent disk layer, containing the accounts and
+// storage slots of an older state, with in-memory diff layers on top, each one
+// containing the changes introduced by a single block. Reads are served from
+// the topmost layer that knows about an item, falling through to the layers
+// below, which makes account and storage reads constant-time, instead of
+// walking the Merkle-Patricia trie.
+package snapshot
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+var (
+	// ErrSnapshotStale is returned from data accessors if the underlying snapshot
+	// layer had been invalidated due to the chain progressing forward far enough
+	// to not maintain the layer's original state.
+	ErrSnapshotStale = errors.New("snapshot stale")
+
+	// ErrNotCoveredYet is returned from data accessors if the underlying snapshot
+	// is being generated currently and the requested data item is not yet in the
+	// range of accounts covered.
+	ErrNotCoveredYet = errors.New("not covered yet")
+
+	// errSnapshotCycle is returned if a snapshot is attempted to be inserted
+	// that forms a cycle in the snapshot tree.
+	errSnapshotCycle = errors.New("snapshot cycle")
+)
+
+// Snapshot represents the functionality supported by a snapshot storage layer.
+type Snapshot interface {
+	// Root returns the root hash for which this snapshot was made.
+	Root() common.Hash
+
+	// Account directly retrieves the account RLP associated with a particular
+	// hash in the snapshot, as stored in the account trie. A nil blob is
+	// returned if the account does not exist.
+	Account(hash common.Hash) ([]byte, error)
+
+	// Storage directly retrieves the storage data associated with a particular
+	// hash, within a particular account, as stored in the storage trie. A nil
+	// blob is returned if the slot is empty.
+	Storage(accountHash, storageHash common.Hash) ([]byte, error)
+}
+
+// snapshot is the internal version of the snapshot data layer that supports some
+// additional methods compared to the public API.
+type snapshot interface {
+	Snapshot
+
+	// Stale return whether this layer has become stale (was flattened across) or
+	// if it's still live.
+	Stale() bool
+}
+
+// account is the Ethereum consensus representation of accounts, as stored in
+// the account trie and the account snapshot.
+type account struct {
+	Nonce    uint64
+	Balance  *big.Int
+	Root     common.Hash
+	CodeHash []byte
+}
+
+// Tree is an Ethereum state snapshot tree. It consists of one persistent base
+// layer backed by a key-value store, on top of which arbitrarily many in-memory
+// diff layers are topped. The memory diffs can form a tree with branching, but
+// the disk layer is singleton and common to all. If a reorg goes deeper than the
+// disk layer, everything needs to be deleted and regenerated.
+//
+// The goal of a state snapshot is twofold: to allow direct access to account and
+// storage data to avoid expensive multi-level trie lookups; and to allow sorted,
+// cheap iteration of the account/storage tries for sync aid.
+type Tree struct {
+	diskdb ethdb.Database           // Persistent database to store the snapshot
+	triedb *trie.Database           // In-memory cache to access the trie through
+	cache  int                      // Megabytes permitted to use for read caches
+	layers map[common.Hash]snapshot // Collection of all known layers
+	lock   sync.RWMutex
+}
+
+// New attempts to load an already existing snapshot from a persistent key-value
+// store (with a number of memory layers from a journal), ensuring that the head
+// of the snapshot matches the expected one.
+//
+// If the snapshot is missing or inconsistent, the entirety is deleted and will
+// be reconstructed from scratch based on the tries in the key-value store, on a
+// background thread.
+func New(diskdb ethdb.Database, triedb *trie.Database, cache int, root common.Hash) *Tree {
+	snap := &Tree{
+		diskdb: diskdb,
+		triedb: triedb,
+		cache:  cache,
+		layers: make(map[common.Hash]snapshot),
+	}
+	base, err := loadSnapshot(diskdb, triedb, cache, root)
+	if err != nil {
+		log.Warn("Failed to load snapshot, regenerating", "err", err)
+		base = generateSnapshot(diskdb, triedb, cache, root)
+	}
+	snap.layers[base.root] = base
+	return snap
+}
+
+// Snapshot retrieves a snapshot belonging to the given block root, or nil if no
+// snapshot is maintained for that block.
+func (t *Tree) Snapshot(blockRoot common.Hash) Snapshot {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if layer, ok := t.layers[blockRoot]; ok {
+		return layer
+	}
+	return nil
+}
+
+// Update adds a new snapshot into the tree, if that can be linked to an existing
+// old parent. It is disallowed to insert a disk layer (the origin of all).
+func (t *Tree) Update(blockRoot common.Hash, parentRoot common.Hash, destructs map[common.Hash]struct{}, accounts map[common.Hash][]byte, storage map[common.Hash]map[common.Hash][]byte) error {
+	// Reject noop updates to avoid self-loops in the snapshot tree. This is a
+	// special case that can only happen for Clique networks where empty blocks
+	// don't modify the state (0 block subsidy).
+	if blockRoot == parentRoot {
+		return errSnapshotCycle
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	// The same state might be reached by multiple blocks, keep the first layer
+	if _, ok := t.layers[blockRoot]; ok {
+		return nil
+	}
+	parent, ok := t.layers[parentRoot]
+	if !ok {
+		return fmt.Errorf("parent [%#x] snapshot missing", parentRoot)
+	}
+	t.layers[blockRoot] = newDiffLayer(parent, blockRoot, destructs, accounts, storage)
+	return nil
+}
+
+// Cap traverses downwards the snapshot tree from a head block hash until the
+// number of allowed layers are crossed. All layers beyond the permitted number
+// are flattened downwards into the disk layer. Layers on side branches which
+// do not descend from the new disk layer any more are dropped.
+func (t *Tree) Cap(root common.Hash, layers int) error {
+	// Retrieve the head snapshot to cap from
+	snap := t.Snapshot(root)
+	if snap == nil {
+		return fmt.Errorf("snapshot [%#x] missing", root)
+	}
+	diff, ok := snap.(*diffLayer)
+	if !ok {
+		return nil // Disk layer, nothing to flatten
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	// Find the lowest diff layer to retain, flattening everything below it. If
+	// no layers are to be retained, the head itself is flattened.
+	var (
+		bottom *diffLayer // Topmost layer to flatten into the disk
+		keep   *diffLayer // Lowest layer to retain, nil if none
+	)
+	if layers == 0 {
+		bottom = diff
+	} else {
+		keep = diff
+		for i := 0; i < layers-1; i++ {
+			parent, ok := keep.parentLayer().(*diffLayer)
+			if !ok {
+				return nil // Not enough layers to flatten
+			}
+			keep = parent
+		}
+		if bottom, ok = keep.parentLayer().(*diffLayer); !ok {
+			return nil // Not enough layers to flatten
+		}
+	}
+	// Collect the layers to flatten in application order and push them to disk
+	var diffs []*diffLayer
+	for layer := bottom; ; {
+		diffs = append([]*diffLayer{layer}, diffs...)
+		parent, ok := layer.parentLayer().(*diffLayer)
+		if !ok {
+			break
+		}
+		layer = parent
+	}
+	base := diffToDisk(diffs)
+	if keep != nil {
+		keep.setParent(base)
+	}
+	// Rebuild the layer set, dropping anything not descending from the new base
+	remaining := map[common.Hash]snapshot{base.root: base}
+	for root, layer := range t.layers {
+		if descendsFrom(layer, base) {
+			remaining[root] = layer
+			continue
+		}
+		if diff, ok := layer.(*diffLayer); ok {
+			diff.markStale()
+		}
+	}
+	t.layers = remaining
+	return nil
+}
+
+// descendsFrom returns whether the layer is the base disk layer or is linked to
+// it through non-stale diff layers.
+func descendsFrom(layer snapshot, base *diskLayer) bool {
+	for {
+		switch l := layer.(type) {
+		case *diskLayer:
+			return l == base
+		case *diffLayer:
+			if l.Stale() {
+				return false
+			}
+			layer = l.parentLayer()
+		default:
+			return false
+		}
+	}
+}
+
+// Rebuild wipes all available snapshot data from the persistent database and
+// discards all caches and diff layers. Afterwards, it starts a new snapshot
+// generator with the given root hash.
+func (t *Tree) Rebuild(root common.Hash) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	// Invalidate all the layers, stopping any running generator
+	for _, layer := range t.layers {
+		switch layer := layer.(type) {
+		case *diskLayer:
+			layer.stopGeneration()
+			layer.markStale()
+		case *diffLayer:
+			layer.markStale()
+		}
+	}
+	// Start generating a new snapshot from scratch on a background thread
+	log.Info("Rebuilding state snapshot", "root", root)
+	base := generateSnapshot(t.diskdb, t.triedb, t.cache, root)
+	t.layers = map[common.Hash]snapshot{root: base}
+}
+
+// Close terminates any running snapshot generator, persisting its progress so
+// it can be resumed later. The tree must not be used afterwards.
+func (t *Tree) Close() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for _, layer := range t.layers {
+		if layer, ok := layer.(*diskLayer); ok {
+			layer.stopGeneration()
+		}
+	}
+}
+
+// loadSnapshot loads a pre-existing state snapshot backed by a key-value store,
+// resuming its generation if it was interrupted.
+func loadSnapshot(diskdb ethdb.Database, triedb *trie.Database, cache int, root common.Hash) (*diskLayer, error) {
+	// Retrieve the block number and hash of the snapshot, failing if no snapshot
+	// is present in the database (or crashed mid-update).
+	baseRoot := rawdb.ReadSnapshotRoot(diskdb)
+	if baseRoot == (common.Hash{}) {
+		return nil, errors.New("missing or corrupted snapshot")
+	}
+	if baseRoot != root {
+		return nil, fmt.Errorf("head doesn't match snapshot: have %#x, want %#x", baseRoot, root)
+	}
+	generator, err := loadGenerator(diskdb)
+	if err != nil {
+		return nil, err
+	}
+	base := newDiskLayer(diskdb, triedb, cache, baseRoot, generator.Marker)
+	if !generator.Done {
+		log.Info("Resuming state snapshot generation", "root", root, "at", fmt.Sprintf("%x", generator.Marker))
+		base.startGeneration(time.Now())
+	}
+	return base, nil
+}
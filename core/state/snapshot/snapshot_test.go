@@ -0,0 +1,459 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// newTestTree creates a snapshot tree on top of a fully generated disk layer
+// containing the given accounts and storage slots.
+func newTestTree(accounts map[common.Hash][]byte, storage map[common.Hash]map[common.Hash][]byte) (*Tree, ethdb.Database) {
+	db := ethdb.NewMemDatabase()
+	for hash, data := range accounts {
+		rawdb.WriteAccountSnapshot(db, hash, data)
+	}
+	for accountHash, slots := range storage {
+		for storageHash, data := range slots {
+			rawdb.WriteStorageSnapshot(db, accountHash, storageHash, data)
+		}
+	}
+	base := newDiskLayer(db, nil, 1, common.Hash{0x01}, nil)
+	rawdb.WriteSnapshotRoot(db, base.root)
+
+	return &Tree{diskdb: db, cache: 1, layers: map[common.Hash]snapshot{base.root: base}}, db
+}
+
+// Tests that diff layers shadow their parents, fall through to them for unknown
+// items and hide the storage of destructed accounts.
+func TestDiffLayerReads(t *testing.T) {
+	var (
+		acc1, acc2, acc3 = common.Hash{0xa1}, common.Hash{0xa2}, common.Hash{0xa3}
+		slot1, slot2     = common.Hash{0xb1}, common.Hash{0xb2}
+	)
+	tree, _ := newTestTree(
+		map[common.Hash][]byte{acc1: {0x01}, acc2: {0x02}},
+		map[common.Hash]map[common.Hash][]byte{acc1: {slot1: {0x11}, slot2: {0x12}}, acc2: {slot1: {0x21}}},
+	)
+	// Modify an account and a slot, destruct another account and create a new one
+	err := tree.Update(common.Hash{0x02}, common.Hash{0x01},
+		map[common.Hash]struct{}{acc2: {}},
+		map[common.Hash][]byte{acc1: {0x03}, acc3: {0x04}},
+		map[common.Hash]map[common.Hash][]byte{acc1: {slot2: nil}},
+	)
+	if err != nil {
+		t.Fatalf("failed to update snapshot tree: %v", err)
+	}
+	snap := tree.Snapshot(common.Hash{0x02})
+	if snap == nil {
+		t.Fatalf("snapshot missing after update")
+	}
+	accounts := map[common.Hash][]byte{acc1: {0x03}, acc2: nil, acc3: {0x04}, {0xff}: nil}
+	for hash, want := range accounts {
+		if have, err := snap.Account(hash); err != nil || !bytes.Equal(have, want) {
+			t.Errorf("account %x: have %x/%v, want %x", hash, have, err, want)
+		}
+	}
+	slots := []struct {
+		account, slot common.Hash
+		want          []byte
+	}{
+		{acc1, slot1, []byte{0x11}},
+		{acc1, slot2, nil},
+		{acc2, slot1, nil},
+		{acc3, slot1, nil},
+	}
+	for _, tt := range slots {
+		if have, err := snap.Storage(tt.account, tt.slot); err != nil || !bytes.Equal(have, tt.want) {
+			t.Errorf("slot %x/%x: have %x/%v, want %x", tt.account, tt.slot, have, err, tt.want)
+		}
+	}
+	// The parent layer must remain unaffected
+	if have, _ := tree.Snapshot(common.Hash{0x01}).Account(acc2); !bytes.Equal(have, []byte{0x02}) {
+		t.Errorf("parent account modified: have %x, want %x", have, []byte{0x02})
+	}
+	// Updates on unknown parents must be rejected
+	if err := tree.Update(common.Hash{0x04}, common.Hash{0x03}, nil, nil, nil); err == nil {
+		t.Errorf("update on missing parent succeeded")
+	}
+}
+
+// Tests that capping the snapshot tree flattens the bottom diff layers into the
+// disk layer, invalidating the flattened and orphaned layers.
+func TestTreeCap(t *testing.T) {
+	var (
+		acc1, acc2 = common.Hash{0xa1}, common.Hash{0xa2}
+		slot       = common.Hash{0xb1}
+	)
+	tree, db := newTestTree(
+		map[common.Hash][]byte{acc1: {0x01}, acc2: {0x02}},
+		map[common.Hash]map[common.Hash][]byte{acc2: {slot: {0x21}}},
+	)
+	// Stack three layers on the disk and a side branch on the first one
+	updates := []struct {
+		root, parent common.Hash
+		destructs    map[common.Hash]struct{}
+		accounts     map[common.Hash][]byte
+	}{
+		{common.Hash{0x02}, common.Hash{0x01}, nil, map[common.Hash][]byte{acc1: {0x03}}},
+		{common.Hash{0x03}, common.Hash{0x02}, map[common.Hash]struct{}{acc2: {}}, nil},
+		{common.Hash{0x04}, common.Hash{0x03}, nil, map[common.Hash][]byte{acc1: {0x05}}},
+		{common.Hash{0x12}, common.Hash{0x01}, nil, map[common.Hash][]byte{acc1: {0x06}}},
+	}
+	for _, u := range updates {
+		if err := tree.Update(u.root, u.parent, u.destructs, u.accounts, nil); err != nil {
+			t.Fatalf("failed to add layer %x: %v", u.root, err)
+		}
+	}
+	orphan := tree.Snapshot(common.Hash{0x12})
+	flattened := tree.Snapshot(common.Hash{0x02})
+
+	// Retain only the head layer, flattening the two below it
+	if err := tree.Cap(common.Hash{0x04}, 1); err != nil {
+		t.Fatalf("failed to cap snapshot tree: %v", err)
+	}
+	if n := len(tree.layers); n != 2 {
+		t.Fatalf("layer count mismatch: have %d, want %d", n, 2)
+	}
+	if root := rawdb.ReadSnapshotRoot(db); root != (common.Hash{0x03}) {
+		t.Errorf("persisted root mismatch: have %x, want %x", root, common.Hash{0x03})
+	}
+	if blob := rawdb.ReadAccountSnapshot(db, acc1); !bytes.Equal(blob, []byte{0x03}) {
+		t.Errorf("flattened account mismatch: have %x, want %x", blob, []byte{0x03})
+	}
+	if blob := rawdb.ReadAccountSnapshot(db, acc2); len(blob) != 0 {
+		t.Errorf("destructed account present: %x", blob)
+	}
+	if blob := rawdb.ReadStorageSnapshot(db, acc2, slot); len(blob) != 0 {
+		t.Errorf("destructed storage present: %x", blob)
+	}
+	// The head must still resolve correctly through the new disk layer
+	head := tree.Snapshot(common.Hash{0x04})
+	if blob, err := head.Account(acc1); err != nil || !bytes.Equal(blob, []byte{0x05}) {
+		t.Errorf("head account mismatch: have %x/%v, want %x", blob, err, []byte{0x05})
+	}
+	if blob, err := head.Account(acc2); err != nil || blob != nil {
+		t.Errorf("head destructed account mismatch: have %x/%v, want nil", blob, err)
+	}
+	// Flattened and orphaned layers must refuse to serve data
+	if _, err := flattened.Account(acc1); err != ErrSnapshotStale {
+		t.Errorf("flattened layer error mismatch: have %v, want %v", err, ErrSnapshotStale)
+	}
+	if _, err := orphan.Account(acc1); err != ErrSnapshotStale {
+		t.Errorf("orphaned layer error mismatch: have %v, want %v", err, ErrSnapshotStale)
+	}
+	// Fully flattening the tree leaves only the disk layer
+	if err := tree.Cap(common.Hash{0x04}, 0); err != nil {
+		t.Fatalf("failed to flatten snapshot tree: %v", err)
+	}
+	if n := len(tree.layers); n != 1 {
+		t.Fatalf("layer count mismatch: have %d, want %d", n, 1)
+	}
+	if blob, err := tree.Snapshot(common.Hash{0x04}).Account(acc1); err != nil || !bytes.Equal(blob, []byte{0x05}) {
+		t.Errorf("disk account mismatch: have %x/%v, want %x", blob, err, []byte{0x05})
+	}
+}
+
+// testAccount is the content of an account in a test state: its balance and
+// the raw values of its storage slots.
+type testAccount struct {
+	balance int64
+	storage map[string]string
+}
+
+// testStateRoot commits a test state into secure tries, returning the state root
+// along with the flat accounts and storage slots a snapshot of it must hold.
+func testStateRoot(triedb *trie.Database, state map[string]testAccount) (common.Hash, map[common.Hash][]byte, map[common.Hash]map[common.Hash][]byte) {
+	var (
+		accounts   = make(map[common.Hash][]byte)
+		storage    = make(map[common.Hash]map[common.Hash][]byte)
+		accTrie, _ = trie.NewSecure(common.Hash{}, triedb, 0)
+	)
+	for name, acc := range state {
+		hash := crypto.Keccak256Hash([]byte(name))
+
+		stRoot := emptyRoot
+		if len(acc.storage) > 0 {
+			stTrie, _ := trie.NewSecure(common.Hash{}, triedb, 0)
+			storage[hash] = make(map[common.Hash][]byte)
+			for key, val := range acc.storage {
+				stTrie.Update([]byte(key), []byte(val))
+				storage[hash][crypto.Keccak256Hash([]byte(key))] = []byte(val)
+			}
+			stRoot, _ = stTrie.Commit(nil)
+		}
+		blob, _ := rlp.EncodeToBytes(&account{Balance: big.NewInt(acc.balance), Root: stRoot, CodeHash: crypto.Keccak256(nil)})
+		accTrie.Update([]byte(name), blob)
+		accounts[hash] = blob
+	}
+	root, _ := accTrie.Commit(nil)
+	triedb.Commit(root, false)
+	return root, accounts, storage
+}
+
+// testStateDiff assembles the snapshot diff transitioning between two flat states.
+func testStateDiff(parentAccounts, accounts map[common.Hash][]byte, parentStorage, storage map[common.Hash]map[common.Hash][]byte) (map[common.Hash]struct{}, map[common.Hash][]byte, map[common.Hash]map[common.Hash][]byte) {
+	var (
+		destructs   = make(map[common.Hash]struct{})
+		accountDiff = make(map[common.Hash][]byte)
+		storageDiff = make(map[common.Hash]map[common.Hash][]byte)
+	)
+	for hash := range parentAccounts {
+		if _, ok := accounts[hash]; !ok {
+			destructs[hash] = struct{}{}
+		}
+	}
+	for hash, blob := range accounts {
+		if !bytes.Equal(parentAccounts[hash], blob) {
+			accountDiff[hash] = blob
+		}
+		slots := make(map[common.Hash][]byte)
+		for slot := range parentStorage[hash] {
+			if _, ok := storage[hash][slot]; !ok {
+				slots[slot] = nil
+			}
+		}
+		for slot, val := range storage[hash] {
+			if !bytes.Equal(parentStorage[hash][slot], val) {
+				slots[slot] = val
+			}
+		}
+		if len(slots) > 0 {
+			storageDiff[hash] = slots
+		}
+	}
+	return destructs, accountDiff, storageDiff
+}
+
+// checkSnapshotTrie iterates the account trie of a state root and all the storage
+// tries hanging off it, checking that the snapshot layer serves every account and
+// slot, and that accounts and slots missing from the tries are absent.
+func checkSnapshotTrie(t *testing.T, snap Snapshot, triedb *trie.Database, root common.Hash, absent map[common.Hash][]common.Hash) {
+	t.Helper()
+
+	accTrie, err := trie.New(root, triedb)
+	if err != nil {
+		t.Fatalf("failed to open account trie %x: %v", root, err)
+	}
+	accIt := trie.NewIterator(accTrie.NodeIterator(nil))
+	for accIt.Next() {
+		hash := common.BytesToHash(accIt.Key)
+		if have, err := snap.Account(hash); err != nil || !bytes.Equal(have, accIt.Value) {
+			t.Errorf("root %x: account %x: have %x/%v, want %x", root, hash, have, err, accIt.Value)
+		}
+		var acc account
+		if err := rlp.DecodeBytes(accIt.Value, &acc); err != nil {
+			t.Fatalf("root %x: invalid account %x: %v", root, hash, err)
+		}
+		stTrie, err := trie.New(acc.Root, triedb)
+		if err != nil {
+			t.Fatalf("root %x: failed to open storage trie of %x: %v", root, hash, err)
+		}
+		stIt := trie.NewIterator(stTrie.NodeIterator(nil))
+		for stIt.Next() {
+			slot := common.BytesToHash(stIt.Key)
+			if have, err := snap.Storage(hash, slot); err != nil || !bytes.Equal(have, stIt.Value) {
+				t.Errorf("root %x: slot %x/%x: have %x/%v, want %x", root, hash, slot, have, err, stIt.Value)
+			}
+		}
+		if stIt.Err != nil {
+			t.Fatalf("root %x: failed to iterate storage trie of %x: %v", root, hash, stIt.Err)
+		}
+	}
+	if accIt.Err != nil {
+		t.Fatalf("root %x: failed to iterate account trie: %v", root, accIt.Err)
+	}
+	for hash, slots := range absent {
+		if len(slots) == 0 {
+			if have, err := snap.Account(hash); err != nil || len(have) != 0 {
+				t.Errorf("root %x: deleted account %x: have %x/%v, want nil", root, hash, have, err)
+			}
+		}
+		for _, slot := range slots {
+			if have, err := snap.Storage(hash, slot); err != nil || len(have) != 0 {
+				t.Errorf("root %x: deleted slot %x/%x: have %x/%v, want nil", root, hash, slot, have, err)
+			}
+		}
+	}
+}
+
+// checkSnapshotDisk iterates the entire persisted snapshot, checking that it holds
+// exactly the accounts and storage slots of the given flat state.
+func checkSnapshotDisk(t *testing.T, db ethdb.Database, root common.Hash, accounts map[common.Hash][]byte, storage map[common.Hash]map[common.Hash][]byte) {
+	t.Helper()
+
+	if have := rawdb.ReadSnapshotRoot(db); have != root {
+		t.Errorf("persisted root mismatch: have %x, want %x", have, root)
+	}
+	var nacc, nslot int
+	it := db.NewIteratorWithPrefix(rawdb.SnapshotAccountPrefix)
+	for it.Next() {
+		key := it.Key()
+		if len(key) != len(rawdb.SnapshotAccountPrefix)+common.HashLength {
+			continue
+		}
+		hash := common.BytesToHash(key[len(rawdb.SnapshotAccountPrefix):])
+		if want := accounts[hash]; !bytes.Equal(it.Value(), want) {
+			t.Errorf("persisted account %x: have %x, want %x", hash, it.Value(), want)
+		}
+		nacc++
+	}
+	it.Release()
+
+	it = db.NewIteratorWithPrefix(rawdb.SnapshotStoragePrefix)
+	for it.Next() {
+		key := it.Key()
+		if len(key) != len(rawdb.SnapshotStoragePrefix)+2*common.HashLength {
+			continue
+		}
+		key = key[len(rawdb.SnapshotStoragePrefix):]
+		hash, slot := common.BytesToHash(key[:common.HashLength]), common.BytesToHash(key[common.HashLength:])
+		if want := storage[hash][slot]; !bytes.Equal(it.Value(), want) {
+			t.Errorf("persisted slot %x/%x: have %x, want %x", hash, slot, it.Value(), want)
+		}
+		nslot++
+	}
+	it.Release()
+
+	var want int
+	for _, slots := range storage {
+		want += len(slots)
+	}
+	if nacc != len(accounts) || nslot != want {
+		t.Errorf("persisted item count mismatch: have %d accounts, %d slots, want %d accounts, %d slots", nacc, nslot, len(accounts), want)
+	}
+}
+
+// Tests that a snapshot generated from the state tries follows a chain reorg
+// across its diff layers: both branches serve exactly their own tries until the
+// tree is capped, after which the abandoned branch is dropped and the persisted
+// snapshot holds exactly the contents of the new canonical tries.
+func TestSnapshotReorg(t *testing.T) {
+	var (
+		diskdb = ethdb.NewMemDatabase()
+		triedb = trie.NewDatabase(diskdb)
+	)
+	// Generate the base snapshot from the genesis state tries
+	genesis := map[string]testAccount{
+		"acc-1": {1, map[string]string{"key-1": "val-1", "key-2": "val-2"}},
+		"acc-2": {2, map[string]string{"key-1": "val-1"}},
+		"acc-3": {3, nil},
+	}
+	rootA, accountsA, storageA := testStateRoot(triedb, genesis)
+
+	base := generateSnapshot(diskdb, triedb, 16, rootA)
+	select {
+	case <-base.genPending:
+		// Snapshot generation succeeded
+	case <-time.After(3 * time.Second):
+		t.Fatalf("snapshot generation timed out")
+	}
+	tree := &Tree{diskdb: diskdb, triedb: triedb, cache: 16, layers: map[common.Hash]snapshot{rootA: base}}
+	defer tree.Close()
+
+	checkSnapshotTrie(t, base, triedb, rootA, nil)
+	checkSnapshotDisk(t, diskdb, rootA, accountsA, storageA)
+
+	// Extend the genesis with the original canonical block and a side chain of
+	// two blocks, modifying, deleting, destructing and creating state
+	states := []struct {
+		parent string
+		name   string
+		state  map[string]testAccount
+	}{
+		{"A", "B", map[string]testAccount{
+			"acc-1": {1, map[string]string{"key-1": "val-3", "key-3": "val-3"}},
+			"acc-3": {3, nil},
+			"acc-4": {4, map[string]string{"key-4": "val-4"}},
+		}},
+		{"A", "B'", map[string]testAccount{
+			"acc-1": {5, map[string]string{"key-1": "val-1", "key-2": "val-5"}},
+			"acc-3": {3, nil},
+		}},
+		{"B'", "C'", map[string]testAccount{
+			"acc-1": {5, map[string]string{"key-2": "val-5"}},
+			"acc-2": {6, map[string]string{"key-2": "val-6"}},
+			"acc-3": {7, map[string]string{"key-3": "val-7"}},
+			"acc-5": {8, map[string]string{"key-5": "val-8"}},
+		}},
+	}
+	var (
+		roots    = map[string]common.Hash{"A": rootA}
+		accounts = map[string]map[common.Hash][]byte{"A": accountsA}
+		storage  = map[string]map[common.Hash]map[common.Hash][]byte{"A": storageA}
+	)
+	for _, s := range states {
+		root, accs, slots := testStateRoot(triedb, s.state)
+		destructs, accountDiff, storageDiff := testStateDiff(accounts[s.parent], accs, storage[s.parent], slots)
+
+		if err := tree.Update(root, roots[s.parent], destructs, accountDiff, storageDiff); err != nil {
+			t.Fatalf("failed to add layer %s: %v", s.name, err)
+		}
+		roots[s.name], accounts[s.name], storage[s.name] = root, accs, slots
+	}
+	// absent collects the accounts and slots of the genesis missing from a state
+	absent := func(name string) map[common.Hash][]common.Hash {
+		missing := make(map[common.Hash][]common.Hash)
+		for hash := range accountsA {
+			if _, ok := accounts[name][hash]; !ok {
+				missing[hash] = nil
+				continue
+			}
+			for slot := range storageA[hash] {
+				if _, ok := storage[name][hash][slot]; !ok {
+					missing[hash] = append(missing[hash], slot)
+				}
+			}
+		}
+		return missing
+	}
+	// Both branches must serve their own state before the reorg
+	for _, name := range []string{"B", "B'", "C'"} {
+		checkSnapshotTrie(t, tree.Snapshot(roots[name]), triedb, roots[name], absent(name))
+	}
+	// Reorg onto the side chain, flattening its first block into the disk layer
+	orphan := tree.Snapshot(roots["B"])
+	if err := tree.Cap(roots["C'"], 1); err != nil {
+		t.Fatalf("failed to cap snapshot tree: %v", err)
+	}
+	if tree.Snapshot(roots["B"]) != nil {
+		t.Errorf("orphaned layer retained after reorg")
+	}
+	if _, err := orphan.Account(crypto.Keccak256Hash([]byte("acc-4"))); err != ErrSnapshotStale {
+		t.Errorf("orphaned layer error mismatch: have %v, want %v", err, ErrSnapshotStale)
+	}
+	checkSnapshotTrie(t, tree.Snapshot(roots["B'"]), triedb, roots["B'"], absent("B'"))
+	checkSnapshotTrie(t, tree.Snapshot(roots["C'"]), triedb, roots["C'"], absent("C'"))
+	checkSnapshotDisk(t, diskdb, roots["B'"], accounts["B'"], storage["B'"])
+
+	// Flatten the new head too, the disk must hold exactly its tries
+	if err := tree.Cap(roots["C'"], 0); err != nil {
+		t.Fatalf("failed to flatten snapshot tree: %v", err)
+	}
+	checkSnapshotTrie(t, tree.Snapshot(roots["C'"]), triedb, roots["C'"], absent("C'"))
+	checkSnapshotDisk(t, diskdb, roots["C'"], accounts["C'"], storage["C'"])
+}
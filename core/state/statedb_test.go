@@ -444,3 +444,36 @@ func TestCopyOfCopy(t *testing.T) {
 		t.Fatalf("2nd copy fail, expected 42, got %v", got)
 	}
 }
+
+// TestSetStorage tests that replacing the storage of an account shadows all of
+// its committed slots.
+func TestSetStorage(t *testing.T) {
+	sdb, _ := New(common.Hash{}, NewDatabase(ethdb.NewMemDatabase()))
+	addr := common.HexToAddress("aaaa")
+
+	var (
+		key1 = common.HexToHash("01")
+		key2 = common.HexToHash("02")
+		val1 = common.HexToHash("11")
+		val2 = common.HexToHash("22")
+	)
+	sdb.SetState(addr, key1, val1)
+	root, _ := sdb.Commit(false)
+	sdb, _ = New(root, sdb.Database())
+
+	sdb.SetStorage(addr, map[common.Hash]common.Hash{key2: val2})
+	if got := sdb.GetState(addr, key1); got != (common.Hash{}) {
+		t.Errorf("overridden slot still visible: have %x, want empty", got)
+	}
+	if got := sdb.GetCommittedState(addr, key1); got != (common.Hash{}) {
+		t.Errorf("overridden committed slot still visible: have %x, want empty", got)
+	}
+	if got := sdb.GetState(addr, key2); got != val2 {
+		t.Errorf("fake slot mismatch: have %x, want %x", got, val2)
+	}
+	// Writes after the override land in the fake storage only
+	sdb.SetState(addr, key1, val2)
+	if got := sdb.GetState(addr, key1); got != val2 {
+		t.Errorf("fake slot write mismatch: have %x, want %x", got, val2)
+	}
+}
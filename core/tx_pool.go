@@ -229,7 +229,8 @@ type TxPool struct {
 
 	wg sync.WaitGroup // for shutdown sync
 
-	eip2f bool
+	eip2f    bool
+	eip2028f bool
 }
 
 // NewTxPool creates a new transaction pool to gather, sort and filter inbound
@@ -308,9 +309,6 @@ func (pool *TxPool) loop() {
 		case ev := <-pool.chainHeadCh:
 			if ev.Block != nil {
 				pool.mu.Lock()
-				if pool.chainconfig.IsEIP2F(ev.Block.Number()) {
-					pool.eip2f = true
-				}
 				pool.reset(head.Header(), ev.Block.Header())
 				head = ev.Block
 
@@ -434,6 +432,11 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	pool.pendingState = state.ManageState(statedb)
 	pool.currentMaxGas = newHead.GasLimit
 
+	// Update the fork flags to the rules of the pending block on top of the new head
+	next := new(big.Int).Add(newHead.Number, big.NewInt(1))
+	pool.eip2f = pool.chainconfig.IsEIP2F(next)
+	pool.eip2028f = pool.chainconfig.IsEIP2028F(next)
+
 	// Inject any transactions discarded due to reorgs
 	log.Debug("Reinjecting stale transactions", "count", len(reinject))
 	senderCacher.recover(pool.signer, reinject)
@@ -618,7 +621,7 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	if pool.currentState.GetBalance(from).Cmp(tx.Cost()) < 0 {
 		return ErrInsufficientFunds
 	}
-	intrGas, err := IntrinsicGas(tx.Data(), tx.To() == nil, pool.eip2f)
+	intrGas, err := IntrinsicGas(tx.Data(), tx.To() == nil, pool.eip2f, pool.eip2028f)
 	if err != nil {
 		return err
 	}
@@ -887,6 +890,12 @@ func (pool *TxPool) Status(hashes []common.Hash) []TxStatus {
 	return status
 }
 
+// Has returns an indicator whether txpool has a transaction cached with the
+// given hash.
+func (pool *TxPool) Has(hash common.Hash) bool {
+	return pool.all.Get(hash) != nil
+}
+
 // Get returns a transaction if it is contained in the pool
 // and nil otherwise.
 func (pool *TxPool) Get(hash common.Hash) *types.Transaction {
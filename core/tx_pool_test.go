@@ -17,6 +17,7 @@
 package core
 
 import (
+	"bytes"
 	"crypto/ecdsa"
 	"fmt"
 	"io/ioutil"
@@ -259,6 +260,46 @@ func TestInvalidTransactions(t *testing.T) {
 	}
 }
 
+// Tests that the calldata repricing of EIP-2028 is applied to the intrinsic gas
+// of transactions according to the pending block on top of the head the pool was
+// last reset to.
+func TestTransactionIntrinsicGasEIP2028(t *testing.T) {
+	t.Parallel()
+
+	config := *params.TestChainConfig
+	config.EIP2028FBlock = big.NewInt(2)
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	pool := NewTxPool(testTxPoolConfig, &config, blockchain)
+	defer pool.Stop()
+
+	key, _ := crypto.GenerateKey()
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+
+	// 100 bytes of non-zero calldata cost 27800 gas before and 22600 after EIP-2028
+	data := bytes.Repeat([]byte{0xff}, 100)
+	tx := func(nonce uint64) *types.Transaction {
+		tx, _ := types.SignTx(types.NewTransaction(nonce, common.Address{}, big.NewInt(100), 25000, big.NewInt(1), data), types.HomesteadSigner{}, key)
+		return tx
+	}
+	pool.lockedReset(nil, &types.Header{Number: big.NewInt(0), GasLimit: 1000000})
+	if err := pool.AddRemote(tx(0)); err != ErrIntrinsicGas {
+		t.Errorf("pre-fork error mismatch: have %v, want %v", err, ErrIntrinsicGas)
+	}
+	// The block before the fork already validates for the repriced pending block
+	pool.lockedReset(nil, &types.Header{Number: big.NewInt(1), GasLimit: 1000000})
+	if err := pool.AddRemote(tx(0)); err != nil {
+		t.Errorf("post-fork transaction rejected: %v", err)
+	}
+	// Reorging back before the fork must restore the old pricing
+	pool.lockedReset(nil, &types.Header{Number: big.NewInt(0), GasLimit: 1000000})
+	if err := pool.AddRemote(tx(1)); err != ErrIntrinsicGas {
+		t.Errorf("reorged pre-fork error mismatch: have %v, want %v", err, ErrIntrinsicGas)
+	}
+}
+
 func TestTransactionQueue(t *testing.T) {
 	t.Parallel()
 
@@ -18,12 +18,14 @@ package vm
 
 import (
 	"crypto/sha256"
+	"encoding/binary"
 	"errors"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/blake2b"
 	"github.com/ethereum/go-ethereum/crypto/bn256"
 	"github.com/ethereum/go-ethereum/params"
 	"golang.org/x/crypto/ripemd160"
@@ -57,11 +59,14 @@ func PrecompiledContractsForConfig(config *params.ChainConfig, bn *big.Int) map[
 		precompileds[common.BytesToAddress([]byte{5})] = &bigModExp{}
 	}
 	if config.IsEIP213F(bn) {
-		precompileds[common.BytesToAddress([]byte{6})] = &bn256Add{}
-		precompileds[common.BytesToAddress([]byte{7})] = &bn256ScalarMul{}
+		precompileds[common.BytesToAddress([]byte{6})] = &bn256Add{eip1108: config.IsEIP1108F(bn)}
+		precompileds[common.BytesToAddress([]byte{7})] = &bn256ScalarMul{eip1108: config.IsEIP1108F(bn)}
 	}
 	if config.IsEIP212F(bn) {
-		precompileds[common.BytesToAddress([]byte{8})] = &bn256Pairing{}
+		precompileds[common.BytesToAddress([]byte{8})] = &bn256Pairing{eip1108: config.IsEIP1108F(bn)}
+	}
+	if config.IsEIP152F(bn) {
+		precompileds[common.BytesToAddress([]byte{9})] = &blake2F{}
 	}
 
 	return precompileds
@@ -280,10 +285,15 @@ func newTwistPoint(blob []byte) (*bn256.G2, error) {
 }
 
 // bn256Add implements a native elliptic curve point addition.
-type bn256Add struct{}
+type bn256Add struct {
+	eip1108 bool // Whether the EIP-1108 reduced gas prices apply
+}
 
 // RequiredGas returns the gas required to execute the pre-compiled contract.
 func (c *bn256Add) RequiredGas(input []byte) uint64 {
+	if c.eip1108 {
+		return params.Bn256AddGasEIP1108
+	}
 	return params.Bn256AddGas
 }
 
@@ -302,10 +312,15 @@ func (c *bn256Add) Run(input []byte) ([]byte, error) {
 }
 
 // bn256ScalarMul implements a native elliptic curve scalar multiplication.
-type bn256ScalarMul struct{}
+type bn256ScalarMul struct {
+	eip1108 bool // Whether the EIP-1108 reduced gas prices apply
+}
 
 // RequiredGas returns the gas required to execute the pre-compiled contract.
 func (c *bn256ScalarMul) RequiredGas(input []byte) uint64 {
+	if c.eip1108 {
+		return params.Bn256ScalarMulGasEIP1108
+	}
 	return params.Bn256ScalarMulGas
 }
 
@@ -331,10 +346,15 @@ var (
 )
 
 // bn256Pairing implements a pairing pre-compile for the bn256 curve
-type bn256Pairing struct{}
+type bn256Pairing struct {
+	eip1108 bool // Whether the EIP-1108 reduced gas prices apply
+}
 
 // RequiredGas returns the gas required to execute the pre-compiled contract.
 func (c *bn256Pairing) RequiredGas(input []byte) uint64 {
+	if c.eip1108 {
+		return params.Bn256PairingBaseGasEIP1108 + uint64(len(input)/192)*params.Bn256PairingPerPointGasEIP1108
+	}
 	return params.Bn256PairingBaseGas + uint64(len(input)/192)*params.Bn256PairingPerPointGas
 }
 
@@ -366,3 +386,64 @@ func (c *bn256Pairing) Run(input []byte) ([]byte, error) {
 	}
 	return false32Byte, nil
 }
+
+const blake2FInputLength = 213
+
+var (
+	// errBlake2FInvalidInputLength is returned if the BLAKE2b F input is not
+	// exactly 213 bytes long.
+	errBlake2FInvalidInputLength = errors.New("invalid input length")
+
+	// errBlake2FInvalidFinalFlag is returned if the BLAKE2b F final block
+	// indicator is neither 0 nor 1.
+	errBlake2FInvalidFinalFlag = errors.New("invalid final flag")
+)
+
+// blake2F implements the BLAKE2b F compression function pre-compile (EIP-152).
+type blake2F struct{}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *blake2F) RequiredGas(input []byte) uint64 {
+	// If the input is malformed, we can't calculate the gas, return 0 and let the
+	// actual call choke and fault.
+	if len(input) != blake2FInputLength {
+		return 0
+	}
+	return uint64(binary.BigEndian.Uint32(input[0:4])) * params.Blake2FRoundGas
+}
+
+func (c *blake2F) Run(input []byte) ([]byte, error) {
+	// Make sure the input is valid (correct length and final flag)
+	if len(input) != blake2FInputLength {
+		return nil, errBlake2FInvalidInputLength
+	}
+	if input[212] != 0 && input[212] != 1 {
+		return nil, errBlake2FInvalidFinalFlag
+	}
+	// Parse the input into the BLAKE2b call parameters
+	var (
+		rounds = binary.BigEndian.Uint32(input[0:4])
+		final  = input[212] == 1
+
+		h [8]uint64
+		m [16]uint64
+		t [2]uint64
+	)
+	for i := 0; i < 8; i++ {
+		h[i] = binary.LittleEndian.Uint64(input[4+i*8:])
+	}
+	for i := 0; i < 16; i++ {
+		m[i] = binary.LittleEndian.Uint64(input[68+i*8:])
+	}
+	t[0] = binary.LittleEndian.Uint64(input[196:204])
+	t[1] = binary.LittleEndian.Uint64(input[204:212])
+
+	// Execute the compression function, extract and return the result
+	blake2b.F(&h, m, t, final, rounds)
+
+	output := make([]byte, 64)
+	for i := 0; i < 8; i++ {
+		binary.LittleEndian.PutUint64(output[i*8:], h[i])
+	}
+	return output, nil
+}
@@ -338,6 +338,60 @@ var bn256PairingTests = []precompiledTest{
 	},
 }
 
+// blake2FTests are the test data for the BLAKE2b F compression precompiled
+// contract, taken from EIP 152.
+var blake2FTests = []precompiledTest{
+	{
+		input:    "0000000c48c9bdf267e6096a3ba7ca8485ae67bb2bf894fe72f36e3cf1361d5f3af54fa5d182e6ad7f520e511f6c3e2b8c68059b6bbd41fbabd9831f79217e1319cde05b61626300000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000300000000000000000000000000000001",
+		expected: "ba80a53f981c4d0d6a2797b69f12f6e94c212f14685ac4b74b12bb6fdbffa2d17d87c5392aab792dc252d5de4533cc9518d38aa8dbf1925ab92386edd4009923",
+		name:     "vector 4",
+	},
+	{
+		input:    "0000000c48c9bdf267e6096a3ba7ca8485ae67bb2bf894fe72f36e3cf1361d5f3af54fa5d182e6ad7f520e511f6c3e2b8c68059b6bbd41fbabd9831f79217e1319cde05b61626300000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000300000000000000000000000000000000",
+		expected: "75ab69d3190a562c51aef8d88f1c2775876944407270c42c9844252c26d2875298743e7f6d5ea2f2d3e8d226039cd31b4e426ac4f2d3d666a610c2116fde4735",
+		name:     "vector 5",
+	},
+	{
+		input:    "0000000148c9bdf267e6096a3ba7ca8485ae67bb2bf894fe72f36e3cf1361d5f3af54fa5d182e6ad7f520e511f6c3e2b8c68059b6bbd41fbabd9831f79217e1319cde05b61626300000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000300000000000000000000000000000001",
+		expected: "b63a380cb2897d521994a85234ee2c181b5f844d2c624c002677e9703449d2fba551b3a8333bcdf5f2f7e08993d53923de3d64fcc68c034e717b9293fed7a421",
+		name:     "vector 6",
+	},
+	{
+		input:    "0000000048c9bdf267e6096a3ba7ca8485ae67bb2bf894fe72f36e3cf1361d5f3af54fa5d182e6ad7f520e511f6c3e2b8c68059b6bbd41fbabd9831f79217e1319cde05b61626300000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000300000000000000000000000000000001",
+		expected: "08c9bcf367e6096a3ba7ca8485ae67bb2bf894fe72f36e3cf1361d5f3af54fa5d282e6ad7f520e511f6c3e2b8c68059b9442be0454267ce079217e1319cde05b",
+		name:     "vector 7",
+	},
+}
+
+// blake2FMalformedTests are the malformed inputs that the BLAKE2b F precompiled
+// contract must reject, taken from EIP 152.
+var blake2FMalformedTests = []struct {
+	input string
+	err   error
+	name  string
+}{
+	{
+		input: "",
+		err:   errBlake2FInvalidInputLength,
+		name:  "vector 0: empty input",
+	},
+	{
+		input: "00000c48c9bdf267e6096a3ba7ca8485ae67bb2bf894fe72f36e3cf1361d5f3af54fa5d182e6ad7f520e511f6c3e2b8c68059b6bbd41fbabd9831f79217e1319cde05b61626300000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000300000000000000000000000000000001",
+		err:   errBlake2FInvalidInputLength,
+		name:  "vector 1: less rounds length",
+	},
+	{
+		input: "000000000c48c9bdf267e6096a3ba7ca8485ae67bb2bf894fe72f36e3cf1361d5f3af54fa5d182e6ad7f520e511f6c3e2b8c68059b6bbd41fbabd9831f79217e1319cde05b61626300000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000300000000000000000000000000000001",
+		err:   errBlake2FInvalidInputLength,
+		name:  "vector 2: more rounds length",
+	},
+	{
+		input: "0000000c48c9bdf267e6096a3ba7ca8485ae67bb2bf894fe72f36e3cf1361d5f3af54fa5d182e6ad7f520e511f6c3e2b8c68059b6bbd41fbabd9831f79217e1319cde05b61626300000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000300000000000000000000000000000002",
+		err:   errBlake2FInvalidFinalFlag,
+		name:  "vector 3: malformed final block indicator flag",
+	},
+}
+
 func testPrecompiled(addr string, test precompiledTest, t *testing.T) {
 	p := PrecompiledContractsForConfig(params.AllEthashProtocolChanges, big.NewInt(0))[common.HexToAddress(addr)]
 	in := common.Hex2Bytes(test.input)
@@ -573,3 +627,54 @@ func BenchmarkPrecompiledBn256Pairing(bench *testing.B) {
 		benchmarkPrecompiled("08", test, bench)
 	}
 }
+
+// Tests the sample inputs from the BLAKE2b F compression EIP 152.
+func TestPrecompiledBlake2F(t *testing.T) {
+	config := *params.AllEthashProtocolChanges
+	config.EIP152FBlock = big.NewInt(0)
+
+	p := PrecompiledContractsForConfig(&config, big.NewInt(0))[common.BytesToAddress([]byte{9})]
+	for _, test := range blake2FTests {
+		in := common.Hex2Bytes(test.input)
+		contract := NewContract(AccountRef(common.HexToAddress("1337")), nil, new(big.Int), p.RequiredGas(in))
+
+		if res, err := RunPrecompiledContract(p, in, contract); err != nil {
+			t.Errorf("%s: %v", test.name, err)
+		} else if common.Bytes2Hex(res) != test.expected {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, common.Bytes2Hex(res))
+		}
+	}
+	for _, test := range blake2FMalformedTests {
+		in := common.Hex2Bytes(test.input)
+		contract := NewContract(AccountRef(common.HexToAddress("1337")), nil, new(big.Int), p.RequiredGas(in))
+
+		if _, err := RunPrecompiledContract(p, in, contract); err != test.err {
+			t.Errorf("%s: expected error %v, got %v", test.name, test.err, err)
+		}
+	}
+}
+
+// Tests that the alt_bn128 precompiled contracts are repriced by EIP 1108.
+func TestPrecompiledBn256EIP1108(t *testing.T) {
+	config := *params.AllEthashProtocolChanges
+	config.EIP1108FBlock = big.NewInt(10)
+
+	tests := []struct {
+		addr          byte
+		input         []byte
+		before, after uint64
+	}{
+		{6, nil, params.Bn256AddGas, params.Bn256AddGasEIP1108},
+		{7, nil, params.Bn256ScalarMulGas, params.Bn256ScalarMulGasEIP1108},
+		{8, make([]byte, 2*192), params.Bn256PairingBaseGas + 2*params.Bn256PairingPerPointGas, params.Bn256PairingBaseGasEIP1108 + 2*params.Bn256PairingPerPointGasEIP1108},
+	}
+	for _, tt := range tests {
+		addr := common.BytesToAddress([]byte{tt.addr})
+		if gas := PrecompiledContractsForConfig(&config, big.NewInt(9))[addr].RequiredGas(tt.input); gas != tt.before {
+			t.Errorf("contract %x: gas mismatch before EIP 1108: have %d, want %d", tt.addr, gas, tt.before)
+		}
+		if gas := PrecompiledContractsForConfig(&config, big.NewInt(10))[addr].RequiredGas(tt.input); gas != tt.after {
+			t.Errorf("contract %x: gas mismatch after EIP 1108: have %d, want %d", tt.addr, gas, tt.after)
+		}
+	}
+}
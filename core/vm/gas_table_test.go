@@ -16,7 +16,16 @@
 
 package vm
 
-import "testing"
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
 
 func TestMemoryGasCost(t *testing.T) {
 	//size := uint64(math.MaxUint64 - 64)
@@ -34,3 +43,66 @@ func TestMemoryGasCost(t *testing.T) {
 		t.Error("expected error")
 	}
 }
+
+var eip2200Tests = []struct {
+	original byte
+	gaspool  uint64
+	input    string
+	used     uint64
+	refund   uint64
+	failure  error
+}{
+	{0, 100000, "0x60006000556000600055", 1612, 0, nil},                // 0 -> 0 -> 0
+	{0, 100000, "0x60006000556001600055", 20812, 0, nil},               // 0 -> 0 -> 1
+	{0, 100000, "0x60016000556000600055", 20812, 19200, nil},           // 0 -> 1 -> 0
+	{0, 100000, "0x60016000556002600055", 20812, 0, nil},               // 0 -> 1 -> 2
+	{0, 100000, "0x60016000556001600055", 20812, 0, nil},               // 0 -> 1 -> 1
+	{1, 100000, "0x60006000556000600055", 5812, 15000, nil},            // 1 -> 0 -> 0
+	{1, 100000, "0x60006000556001600055", 5812, 4200, nil},             // 1 -> 0 -> 1
+	{1, 100000, "0x60006000556002600055", 5812, 0, nil},                // 1 -> 0 -> 2
+	{1, 100000, "0x60026000556000600055", 5812, 15000, nil},            // 1 -> 2 -> 0
+	{1, 100000, "0x60026000556003600055", 5812, 0, nil},                // 1 -> 2 -> 3
+	{1, 100000, "0x60026000556001600055", 5812, 4200, nil},             // 1 -> 2 -> 1
+	{1, 100000, "0x60026000556002600055", 5812, 0, nil},                // 1 -> 2 -> 2
+	{1, 100000, "0x60016000556000600055", 5812, 15000, nil},            // 1 -> 1 -> 0
+	{1, 100000, "0x60016000556002600055", 5812, 0, nil},                // 1 -> 1 -> 2
+	{1, 100000, "0x60016000556001600055", 1612, 0, nil},                // 1 -> 1 -> 1
+	{0, 100000, "0x600160005560006000556001600055", 40818, 19200, nil}, // 0 -> 1 -> 0 -> 1
+	{1, 100000, "0x600060005560016000556000600055", 10818, 19200, nil}, // 1 -> 0 -> 1 -> 0
+	{1, 2306, "0x6001600055", 2306, 0, ErrOutOfGas},                    // 1 -> 1 (2300 sentry + 2xPUSH)
+	{1, 2307, "0x6001600055", 806, 0, nil},                             // 1 -> 1 (2301 sentry + 2xPUSH)
+}
+
+// Tests the SSTORE gas metering and refunds of EIP-2200.
+func TestEIP2200(t *testing.T) {
+	config := *params.AllEthashProtocolChanges
+	config.EIP2200FBlock = big.NewInt(0)
+
+	for i, tt := range eip2200Tests {
+		address := common.BytesToAddress([]byte("contract"))
+
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+		statedb.CreateAccount(address)
+		statedb.SetCode(address, hexutil.MustDecode(tt.input))
+		statedb.SetState(address, common.Hash{}, common.BytesToHash([]byte{tt.original}))
+		statedb.Finalise(true) // Push the state into the "original" slot
+
+		vmctx := Context{
+			CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+			Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+			BlockNumber: new(big.Int),
+		}
+		vmenv := NewEVM(vmctx, statedb, &config, Config{})
+
+		_, left, err := vmenv.Call(AccountRef(common.Address{}), address, nil, tt.gaspool, new(big.Int))
+		if err != tt.failure {
+			t.Errorf("test %d: failure mismatch: have %v, want %v", i, err, tt.failure)
+		}
+		if used := tt.gaspool - left; used != tt.used {
+			t.Errorf("test %d: gas used mismatch: have %v, want %v", i, used, tt.used)
+		}
+		if refund := vmenv.StateDB.GetRefund(); refund != tt.refund {
+			t.Errorf("test %d: gas refund mismatch: have %v, want %v", i, refund, tt.refund)
+		}
+	}
+}
@@ -30,7 +30,10 @@ type (
 	memorySizeFunc      func(*Stack) *big.Int
 )
 
-var errGasUintOverflow = errors.New("gas uint64 overflow")
+var (
+	errGasUintOverflow = errors.New("gas uint64 overflow")
+	errSStoreSentry    = errors.New("not enough gas for reentrancy sentry")
+)
 
 type operation struct {
 	// execute is the operation function
@@ -145,6 +148,24 @@ func instructionSetForConfig(config *params.ChainConfig, bn *big.Int) [256]opera
 			valid:         true,
 		}
 	}
+
+	// Istanbul
+	if config.IsEIP1344F(bn) {
+		instructionSet[CHAINID] = operation{
+			execute:       opChainID,
+			gasCost:       constGasFunc(GasQuickStep),
+			validateStack: makeStackFunc(0, 1),
+			valid:         true,
+		}
+	}
+	if config.IsEIP1884F(bn) {
+		instructionSet[SELFBALANCE] = operation{
+			execute:       opSelfBalance,
+			gasCost:       constGasFunc(GasFastStep),
+			validateStack: makeStackFunc(0, 1),
+			valid:         true,
+		}
+	}
 	return instructionSet
 }
 
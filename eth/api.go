@@ -248,6 +248,38 @@ func (api *PrivateAdminAPI) ImportChain(file string) (bool, error) {
 	return true, nil
 }
 
+// ForkScheduleEntry is a single fork or fork parameter of the chain configuration.
+type ForkScheduleEntry struct {
+	Name       string   `json:"name"`       // Name of the fork or fork parameter
+	Field      string   `json:"field"`      // Field name in the JSON chain configuration
+	Kind       string   `json:"kind"`       // "block" for forks, "param" for fork parameters or "policy" for local chain policies
+	Value      *big.Int `json:"value"`      // Configured fork block or parameter value
+	Activation *big.Int `json:"activation"` // Block from which the field affects consensus
+	Active     bool     `json:"active"`     // Whether the field is in effect at the current head
+}
+
+// ForkSchedule returns every fork and fork parameter configured for the chain,
+// along with whether it is already in effect at the current head block.
+func (api *PrivateAdminAPI) ForkSchedule() []ForkScheduleEntry {
+	var (
+		config = api.eth.BlockChain().Config()
+		head   = api.eth.BlockChain().CurrentBlock().Number()
+	)
+	schedule := make([]ForkScheduleEntry, 0)
+	for _, field := range config.ForkSchedule() {
+		activation := config.Activation(field)
+		schedule = append(schedule, ForkScheduleEntry{
+			Name:       field.Name,
+			Field:      field.JSON,
+			Kind:       field.Kind.String(),
+			Value:      config.Value(field),
+			Activation: activation,
+			Active:     activation != nil && activation.Cmp(head) <= 0,
+		})
+	}
+	return schedule
+}
+
 // PublicDebugAPI is the collection of Ethereum full node APIs exposed
 // over the public debugging endpoint.
 type PublicDebugAPI struct {
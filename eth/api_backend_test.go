@@ -0,0 +1,209 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// returnCode assembles a contract returning the word pushed by the check code.
+func returnCode(check ...byte) hexutil.Bytes {
+	return append(check, 0x60, 0x00, 0x52, 0x60, 0x20, 0x60, 0x00, 0xf3) // MSTORE(0), RETURN(0, 32)
+}
+
+// assertCode assembles a contract which only succeeds if the check code pushes
+// the wanted word, executing an invalid opcode otherwise.
+func assertCode(want common.Hash, check ...byte) hexutil.Bytes {
+	code := append(append(check, 0x7f), want[:]...) // PUSH32 want
+	dest := byte(len(code) + 5)
+	return append(code, 0x14, 0x60, dest, 0x57, 0xfe, 0x5b, 0x00) // EQ, JUMPI(dest), INVALID, JUMPDEST, STOP
+}
+
+// estimateGas runs the gas estimation of eth_estimateGas against the latest block,
+// as the test node doesn't mine pending blocks.
+func estimateGas(eth *Ethereum, args ethapi.CallArgs, overrides *ethapi.StateOverride) (hexutil.Uint64, error) {
+	latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	return ethapi.DoEstimateGas(context.Background(), eth.APIBackend, args, latest, overrides, eth.APIBackend.RPCGasCap())
+}
+
+// Tests that the balance, nonce, code and storage overrides of eth_call and
+// eth_estimateGas are applied to the state the call is executed against.
+func TestStateOverride(t *testing.T) {
+	eth, _ := newTestEthereum(t, 3)
+	defer eth.blockchain.Stop()
+
+	var (
+		api    = ethapi.NewPublicBlockChainAPI(eth.APIBackend)
+		latest = rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+		from   = common.Address{0xaa}
+		probe  = common.Address{0xbb}
+		rich   = common.Address{0xcc}
+
+		balance = (*hexutil.Big)(big.NewInt(0x1234))
+		nonce   = hexutil.Uint64(5)
+		slots   = map[common.Hash]common.Hash{common.BigToHash(big.NewInt(1)): common.BigToHash(big.NewInt(7))}
+	)
+	tests := []struct {
+		name     string
+		to       common.Address
+		check    []byte                                    // Code pushing the overridden value
+		override ethapi.OverrideAccount                    // Override of the called account, sans code
+		extra    map[common.Address]ethapi.OverrideAccount // Overrides of other accounts
+		want     common.Hash
+	}{
+		{
+			name:  "balance",
+			to:    probe,
+			check: append(append([]byte{0x73}, rich[:]...), 0x31), // BALANCE(rich)
+			extra: map[common.Address]ethapi.OverrideAccount{rich: {Balance: &balance}},
+			want:  common.BigToHash(big.NewInt(0x1234)),
+		},
+		{
+			name:     "nonce",
+			to:       probe,
+			check:    []byte{0x60, 0x00, 0x80, 0x80, 0xf0}, // CREATE(0, 0, 0)
+			override: ethapi.OverrideAccount{Nonce: &nonce},
+			want:     common.BytesToHash(crypto.CreateAddress(probe, 5).Bytes()),
+		},
+		{
+			name:     "state",
+			to:       testStorageContract,
+			check:    []byte{0x60, 0x00, 0x54, 0x60, 0x01, 0x54, 0x01}, // SLOAD(0) + SLOAD(1)
+			override: ethapi.OverrideAccount{State: &slots},
+			want:     common.BigToHash(big.NewInt(7)),
+		},
+		{
+			name:     "stateDiff",
+			to:       testStorageContract,
+			check:    []byte{0x60, 0x00, 0x54, 0x60, 0x01, 0x54, 0x01}, // SLOAD(0) + SLOAD(1)
+			override: ethapi.OverrideAccount{StateDiff: &slots},
+			want:     common.BigToHash(big.NewInt(3 + 7)),
+		},
+	}
+	for _, tt := range tests {
+		args := ethapi.CallArgs{From: &from, To: &tt.to}
+
+		// Assemble the overrides with and without the tested one, the code of the
+		// called account is always replaced
+		overrides := func(code hexutil.Bytes, full bool) *ethapi.StateOverride {
+			set := ethapi.StateOverride{tt.to: {Code: &code}}
+			if full {
+				override := tt.override
+				override.Code = &code
+				set[tt.to] = override
+				for addr, account := range tt.extra {
+					set[addr] = account
+				}
+			}
+			return &set
+		}
+		// Ensure eth_call sees the overridden value, but only if overridden
+		res, err := api.Call(context.Background(), args, latest, overrides(returnCode(tt.check...), true))
+		if err != nil {
+			t.Errorf("%s: call failed: %v", tt.name, err)
+		} else if common.BytesToHash(res) != tt.want {
+			t.Errorf("%s: call result mismatch: have %x, want %x", tt.name, common.BytesToHash(res), tt.want)
+		}
+		res, err = api.Call(context.Background(), args, latest, overrides(returnCode(tt.check...), false))
+		if err != nil {
+			t.Errorf("%s: call without override failed: %v", tt.name, err)
+		} else if common.BytesToHash(res) == tt.want {
+			t.Errorf("%s: call without override matches overridden result %x", tt.name, common.BytesToHash(res))
+		}
+		// Ensure eth_estimateGas only succeeds if the value is overridden
+		if _, err := estimateGas(eth, args, overrides(assertCode(tt.want, tt.check...), true)); err != nil {
+			t.Errorf("%s: gas estimation failed: %v", tt.name, err)
+		}
+		if _, err := estimateGas(eth, args, overrides(assertCode(tt.want, tt.check...), false)); err == nil {
+			t.Errorf("%s: gas estimation without override succeeded", tt.name)
+		}
+	}
+}
+
+// Tests that the code override replaces the code of deployed contracts in both
+// eth_call and eth_estimateGas.
+func TestStateOverrideCode(t *testing.T) {
+	eth, _ := newTestEthereum(t, 3)
+	defer eth.blockchain.Stop()
+
+	var (
+		api    = ethapi.NewPublicBlockChainAPI(eth.APIBackend)
+		latest = rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+		from   = common.Address{0xaa}
+		args   = ethapi.CallArgs{From: &from, To: &testStorageContract}
+	)
+	override := func(code hexutil.Bytes) *ethapi.StateOverride {
+		return &ethapi.StateOverride{testStorageContract: {Code: &code}}
+	}
+	res, err := api.Call(context.Background(), args, latest, nil)
+	if err != nil || common.BytesToHash(res) != common.BigToHash(big.NewInt(3)) {
+		t.Errorf("deployed code result mismatch: have %x/%v, want %x", common.BytesToHash(res), err, common.BigToHash(big.NewInt(3)))
+	}
+	res, err = api.Call(context.Background(), args, latest, override(returnCode(0x60, 0x2a)))
+	if err != nil || common.BytesToHash(res) != common.BigToHash(big.NewInt(0x2a)) {
+		t.Errorf("overridden code result mismatch: have %x/%v, want %x", common.BytesToHash(res), err, common.BigToHash(big.NewInt(0x2a)))
+	}
+	if _, err := estimateGas(eth, args, nil); err != nil {
+		t.Errorf("gas estimation of deployed code failed: %v", err)
+	}
+	if _, err := estimateGas(eth, args, override(hexutil.Bytes{0xfe})); err == nil {
+		t.Errorf("gas estimation of invalid overridden code succeeded")
+	}
+}
+
+// Tests that overriding both the full storage and a storage diff of an account
+// is rejected before any of the overrides is applied.
+func TestStateOverrideConflict(t *testing.T) {
+	eth, _ := newTestEthereum(t, 3)
+	defer eth.blockchain.Stop()
+
+	var (
+		api    = ethapi.NewPublicBlockChainAPI(eth.APIBackend)
+		latest = rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+		from   = common.Address{0xaa}
+		args   = ethapi.CallArgs{From: &from, To: &testStorageContract}
+
+		nonce     = hexutil.Uint64(5)
+		slots     = map[common.Hash]common.Hash{{0x01}: {0x07}}
+		overrides = &ethapi.StateOverride{testStorageContract: {Nonce: &nonce, State: &slots, StateDiff: &slots}}
+	)
+	if _, err := api.Call(context.Background(), args, latest, overrides); err == nil {
+		t.Errorf("call with conflicting overrides succeeded")
+	}
+	if _, err := estimateGas(eth, args, overrides); err == nil {
+		t.Errorf("gas estimation with conflicting overrides succeeded")
+	}
+	statedb, err := eth.blockchain.State()
+	if err != nil {
+		t.Fatalf("failed to retrieve head state: %v", err)
+	}
+	before := statedb.GetNonce(testStorageContract)
+	if err := overrides.Apply(statedb); err == nil {
+		t.Errorf("conflicting overrides applied")
+	}
+	if after := statedb.GetNonce(testStorageContract); after != before {
+		t.Errorf("nonce overridden before rejection: have %d, want %d", after, before)
+	}
+}
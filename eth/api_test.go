@@ -17,13 +17,18 @@
 package eth
 
 import (
+	"math/big"
 	"reflect"
 	"testing"
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
 )
 
 var dumper = spew.ConfigState{Indent: "    "}
@@ -88,3 +93,69 @@ func TestStorageRangeAt(t *testing.T) {
 		}
 	}
 }
+
+// newForkScheduleAPI creates an admin API on top of a chain of the given length
+// running with the given chain configuration.
+func newForkScheduleAPI(t *testing.T, config *params.ChainConfig, blocks int) (*PrivateAdminAPI, *core.BlockChain) {
+	var (
+		db      = ethdb.NewMemDatabase()
+		engine  = ethash.NewFaker()
+		genesis = (&core.Genesis{Config: config}).MustCommit(db)
+	)
+	chain, _ := core.GenerateChain(config, genesis, engine, db, blocks, nil)
+
+	blockchain, err := core.NewBlockChain(db, nil, config, engine, vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create blockchain: %v", err)
+	}
+	if _, err := blockchain.InsertChain(chain); err != nil {
+		t.Fatalf("failed to import chain: %v", err)
+	}
+	return NewPrivateAdminAPI(&Ethereum{blockchain: blockchain}), blockchain
+}
+
+// Tests that the fork schedule lists the configured forks and parameters in
+// declaration order, along with their activation blocks.
+func TestForkSchedule(t *testing.T) {
+	api, blockchain := newForkScheduleAPI(t, params.ClassicChainConfig, 0)
+	defer blockchain.Stop()
+
+	want := []ForkScheduleEntry{
+		{Name: "Homestead", Field: "homesteadBlock", Kind: "block", Value: big.NewInt(1150000), Activation: big.NewInt(1150000)},
+		{Name: "DAOFork", Field: "daoForkBlock", Kind: "block", Value: big.NewInt(1920000), Activation: big.NewInt(1920000)},
+		{Name: "EIP150", Field: "eip150Block", Kind: "block", Value: big.NewInt(2500000), Activation: big.NewInt(2500000)},
+		{Name: "EIP155", Field: "eip155Block", Kind: "block", Value: big.NewInt(3000000), Activation: big.NewInt(3000000)},
+		{Name: "EIP160F", Field: "eip160Block", Kind: "block", Value: big.NewInt(3000000), Activation: big.NewInt(3000000)},
+		{Name: "ECIP1010Pause", Field: "ecip1010PauseBlock", Kind: "block", Value: big.NewInt(3000000), Activation: big.NewInt(3000000)},
+		{Name: "ECIP1010Length", Field: "ecip1010Length", Kind: "param", Value: big.NewInt(2000000), Activation: big.NewInt(3000000)},
+		{Name: "ECIP1017EraRounds", Field: "ecip1017EraRounds", Kind: "param", Value: big.NewInt(5000000), Activation: big.NewInt(5000000)},
+		{Name: "Disposal", Field: "disposalBlock", Kind: "block", Value: big.NewInt(5900000), Activation: big.NewInt(5900000)},
+	}
+	if have := api.ForkSchedule(); !reflect.DeepEqual(have, want) {
+		t.Errorf("fork schedule mismatch:\nhave %v\nwant %v", dumper.Sdump(have), dumper.Sdump(want))
+	}
+}
+
+// Tests that the fork schedule reports the forks activated up to the current
+// head block as active.
+func TestForkScheduleActive(t *testing.T) {
+	config := *params.ClassicChainConfig
+	config.HomesteadBlock = big.NewInt(1)
+	config.EIP150Block = big.NewInt(2)
+	config.EIP150Hash = common.Hash{} // Skip the fork block hash check
+	config.EIP155Block = big.NewInt(3)
+
+	api, blockchain := newForkScheduleAPI(t, &config, 2)
+	defer blockchain.Stop()
+
+	active := make(map[string]bool)
+	for _, entry := range api.ForkSchedule() {
+		active[entry.Name] = entry.Active
+	}
+	want := map[string]bool{"Homestead": true, "EIP150": true, "EIP155": false, "DAOFork": false, "Disposal": false}
+	for name, wantActive := range want {
+		if have, ok := active[name]; !ok || have != wantActive {
+			t.Errorf("%s: activity mismatch: have %v (listed %v), want %v", name, have, ok, wantActive)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Tests that calls can be traced against the state of the latest block, of
+// historical blocks selected by number or hash, and that missing blocks are
+// reported as errors.
+func TestTraceCall(t *testing.T) {
+	eth, blocks := newTestEthereum(t, 3)
+	defer eth.blockchain.Stop()
+
+	var (
+		api  = NewPrivateDebugAPI(eth.chainConfig, eth)
+		gas  = hexutil.Uint64(100000)
+		args = ethapi.CallArgs{To: &testStorageContract, Gas: &gas}
+	)
+
+	tests := []struct {
+		block rpc.BlockNumberOrHash
+		slot  int64
+		err   bool
+	}{
+		{rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), 3, false},
+		{rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(1)), 1, false},
+		{rpc.BlockNumberOrHashWithNumber(rpc.EarliestBlockNumber), 0, false},
+		{rpc.BlockNumberOrHashWithHash(blocks[2].Hash(), false), 2, false},
+		{rpc.BlockNumberOrHashWithHash(blocks[2].Hash(), true), 2, false},
+		{rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(len(blocks))), 0, true},
+		{rpc.BlockNumberOrHashWithHash(common.Hash{0xff}, false), 0, true},
+	}
+	for i, tt := range tests {
+		res, err := api.TraceCall(context.Background(), args, tt.block, nil)
+		if tt.err {
+			if err == nil {
+				t.Errorf("test %d: missing block traced: %v", i, res)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: failed to trace call: %v", i, err)
+			continue
+		}
+		result, ok := res.(*ethapi.ExecutionResult)
+		if !ok {
+			t.Errorf("test %d: result type mismatch: have %T, want %T", i, res, &ethapi.ExecutionResult{})
+			continue
+		}
+		if want := common.Bytes2Hex(common.BigToHash(big.NewInt(tt.slot)).Bytes()); result.Failed || result.ReturnValue != want {
+			t.Errorf("test %d: return mismatch: have %s (failed %v), want %s", i, result.ReturnValue, result.Failed, want)
+		}
+		var ops []string
+		for _, log := range result.StructLogs {
+			ops = append(ops, log.Op)
+		}
+		want := []string{"CALLDATASIZE", "ISZERO", "PUSH1", "JUMPI", "JUMPDEST", "PUSH1", "SLOAD", "PUSH1", "MSTORE", "PUSH1", "PUSH1", "RETURN"}
+		if !reflect.DeepEqual(ops, want) {
+			t.Errorf("test %d: struct log mismatch: have %v, want %v", i, ops, want)
+		}
+	}
+}
+
+// Tests that calls traced with a JavaScript tracer return its result, executed
+// against the selected historical state.
+func TestTraceCallJSTracer(t *testing.T) {
+	eth, _ := newTestEthereum(t, 2)
+	defer eth.blockchain.Stop()
+
+	var (
+		api    = NewPrivateDebugAPI(eth.chainConfig, eth)
+		gas    = hexutil.Uint64(100000)
+		args   = ethapi.CallArgs{To: &testStorageContract, Gas: &gas}
+		tracer = `{data: [], step: function(log, db) { if (log.op.toString() == "SLOAD") { this.data.push(log.stack.peek(0).toString()); } }, fault: function(log, db) {}, result: function(ctx, db) { return {output: toHex(ctx.output), loads: this.data}; }}`
+	)
+	res, err := api.TraceCall(context.Background(), args, rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(1)), &TraceConfig{Tracer: &tracer})
+	if err != nil {
+		t.Fatalf("failed to trace call: %v", err)
+	}
+	blob, ok := res.(json.RawMessage)
+	if !ok {
+		t.Fatalf("result type mismatch: have %T, want %T", res, json.RawMessage{})
+	}
+	var result struct {
+		Output string   `json:"output"`
+		Loads  []string `json:"loads"`
+	}
+	if err := json.Unmarshal(blob, &result); err != nil {
+		t.Fatalf("failed to decode tracer result %s: %v", blob, err)
+	}
+	if want := hexutil.Encode(common.BigToHash(big.NewInt(1)).Bytes()); result.Output != want {
+		t.Errorf("output mismatch: have %s, want %s", result.Output, want)
+	}
+	if want := []string{"0"}; !reflect.DeepEqual(result.Loads, want) {
+		t.Errorf("loaded slots mismatch: have %v, want %v", result.Loads, want)
+	}
+}
@@ -54,6 +54,8 @@ var DefaultConfig = Config{
 	MinerGasPrice:  big.NewInt(params.GWei),
 	MinerRecommit:  3 * time.Second,
 
+	DatabaseFreezerThreshold: params.ImmutabilityThreshold,
+
 	TxPool: core.DefaultTxPoolConfig,
 	GPO: gasprice.Config{
 		Blocks:     20,
@@ -90,6 +92,10 @@ type Config struct {
 	// Whitelist of required block number -> hash values to accept
 	Whitelist map[uint64]common.Hash `toml:"-"`
 
+	// DiscoveryURLs are the enrtree:// URLs of DNS node lists (EIP-1459)
+	// used to find eth protocol peers.
+	DiscoveryURLs []string `toml:",omitempty"`
+
 	// Light client options
 	LightServ    int  `toml:",omitempty"` // Maximum percentage of time allowed for serving LES requests
 	LightPeers   int  `toml:",omitempty"` // Maximum number of LES client peers
@@ -102,9 +108,15 @@ type Config struct {
 	SkipBcVersionCheck bool `toml:"-"`
 	DatabaseHandles    int  `toml:"-"`
 	DatabaseCache      int
+	DatabaseFreezer    string // Directory of the ancient chain store, disabled if empty
 	TrieCleanCache     int
 	TrieDirtyCache     int
 	TrieTimeout        time.Duration
+	SnapshotCache      int // Megabytes of memory for the flat state snapshot, disabled if zero
+
+	// DatabaseFreezerThreshold is the number of recent blocks kept in the key-value
+	// store before being moved into the ancient chain store.
+	DatabaseFreezerThreshold uint64
 
 	// Mining-related options
 	Etherbase      common.Address `toml:",omitempty"`
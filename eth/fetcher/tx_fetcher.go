@@ -0,0 +1,447 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package fetcher
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// maxTxAnnounces is the maximum number of unique transactions a peer may
+	// have announced and not yet delivered. Announcements beyond this are dropped
+	// to prevent a peer from bloating our memory.
+	maxTxAnnounces = 4096
+
+	// maxTxRetrievals is the maximum number of transactions that can be fetched
+	// in one request.
+	maxTxRetrievals = 256
+
+	// txArriveTimeout is the time allowance before an announced transaction is
+	// explicitly requested, giving a chance for a direct broadcast to arrive.
+	txArriveTimeout = 500 * time.Millisecond
+
+	// txGatherSlack is the interval used to collate almost-expired announces
+	// with network fetches.
+	txGatherSlack = 100 * time.Millisecond
+
+	// txFetchTimeout is the maximum allotted time to return an explicitly
+	// requested transaction.
+	txFetchTimeout = 5 * time.Second
+)
+
+// txRequesterFn is a callback type for requesting a batch of transactions from
+// a remote peer.
+type txRequesterFn func(peer string, hashes []common.Hash) error
+
+// txAnnounce is the notification of the availability of a batch of new
+// transactions in the network.
+type txAnnounce struct {
+	origin string        // Identifier of the peer originating the notification
+	hashes []common.Hash // Batch of transaction hashes being announced
+}
+
+// txDelivery is the notification that a batch of transactions have been added
+// to the pool and should be untracked.
+type txDelivery struct {
+	origin string        // Identifier of the peer originating the notification
+	hashes []common.Hash // Batch of transaction hashes having been delivered
+	direct bool          // Whether this is a direct reply or a broadcast
+}
+
+// txRequest represents an in-flight transaction retrieval request destined to
+// a specific peer.
+type txRequest struct {
+	hashes []common.Hash  // Transactions having been requested
+	time   mclock.AbsTime // Timestamp of the request
+}
+
+// TxFetcher is responsible for retrieving new transactions based on
+// announcements.
+//
+// The fetcher operates in three stages:
+//   - Transactions that are newly discovered are moved into a wait list.
+//   - After ~500ms passes, transactions from the wait list that have not been
+//     broadcast to us in whole are moved into a queueing area.
+//   - When a connected peer doesn't have in-flight retrieval requests, any
+//     transaction queued up (and announced by the peer) are allocated to the
+//     peer and moved into a fetching status until it's fulfilled or fails.
+//
+// The invariants of the fetcher are:
+//   - Each tracked transaction (hash) must only be present in one of the three
+//     stages. This ensures that the fetcher operates akin to a finite state
+//     automaton and there's no data leak.
+//   - Each peer that announced transactions may be scheduled retrievals, but
+//     only ever one concurrently. This ensures we can immediately know what is
+//     missing from a reply and reschedule it.
+type TxFetcher struct {
+	notify  chan *txAnnounce
+	cleanup chan *txDelivery
+	drop    chan string
+	quit    chan struct{}
+
+	// Stage 1: Waiting lists for newly discovered transactions that might be
+	// broadcast without needing explicit request/reply round trips.
+	waitlist map[common.Hash]map[string]struct{} // Transactions waiting for a potential broadcast
+	waittime map[common.Hash]mclock.AbsTime      // Timestamps when transactions were added to the waitlist
+
+	// Stage 2: Queue of transactions that are waiting to be allocated to some
+	// peer to be retrieved directly. Transactions being fetched (stage 3) are
+	// also tracked here, to know which alternate peers can deliver them.
+	announced map[common.Hash]map[string]struct{} // Set of announcers of each queued or fetching transaction
+
+	// Stage 3: Set of transactions currently being retrieved, some of which may
+	// be fulfilled and some rescheduled.
+	fetching map[common.Hash]string // Transaction set currently being retrieved, mapped to the peer asked
+	requests map[string]*txRequest  // In-flight transaction retrievals
+
+	announces map[string]map[common.Hash]struct{} // Set of tracked announcements of each peer, across all stages
+
+	// Callbacks
+	hasTx    func(common.Hash) bool             // Retrieves a tx from the local txpool
+	addTxs   func([]*types.Transaction) []error // Insert a batch of transactions into local txpool
+	fetchTxs txRequesterFn                      // Retrieves a set of txs from a remote peer
+
+	step  chan struct{} // Notification channel when the fetcher loop iterates
+	clock mclock.Clock  // Time wrapper to simulate in tests
+}
+
+// NewTxFetcher creates a transaction fetcher to retrieve transaction
+// based on hash announcements.
+func NewTxFetcher(hasTx func(common.Hash) bool, addTxs func([]*types.Transaction) []error, fetchTxs txRequesterFn) *TxFetcher {
+	return NewTxFetcherForTests(hasTx, addTxs, fetchTxs, mclock.System{})
+}
+
+// NewTxFetcherForTests is a testing method to mock out the realtime clock with
+// a simulated version.
+func NewTxFetcherForTests(hasTx func(common.Hash) bool, addTxs func([]*types.Transaction) []error, fetchTxs txRequesterFn, clock mclock.Clock) *TxFetcher {
+	return &TxFetcher{
+		notify:    make(chan *txAnnounce),
+		cleanup:   make(chan *txDelivery),
+		drop:      make(chan string),
+		quit:      make(chan struct{}),
+		waitlist:  make(map[common.Hash]map[string]struct{}),
+		waittime:  make(map[common.Hash]mclock.AbsTime),
+		announced: make(map[common.Hash]map[string]struct{}),
+		fetching:  make(map[common.Hash]string),
+		requests:  make(map[string]*txRequest),
+		announces: make(map[string]map[common.Hash]struct{}),
+		hasTx:     hasTx,
+		addTxs:    addTxs,
+		fetchTxs:  fetchTxs,
+		clock:     clock,
+	}
+}
+
+// Notify announces the fetcher of the potential availability of a new batch of
+// transactions in the network.
+func (f *TxFetcher) Notify(peer string, hashes []common.Hash) error {
+	txAnnounceInMeter.Mark(int64(len(hashes)))
+
+	// Skip any transaction announcements that we already know of. This check is
+	// racy, but it's only an optimisation to avoid needlessly tracking them.
+	unknowns := make([]common.Hash, 0, len(hashes))
+	for _, hash := range hashes {
+		if !f.hasTx(hash) {
+			unknowns = append(unknowns, hash)
+		}
+	}
+	txAnnounceKnownMeter.Mark(int64(len(hashes) - len(unknowns)))
+
+	if len(unknowns) == 0 {
+		return nil
+	}
+	select {
+	case f.notify <- &txAnnounce{origin: peer, hashes: unknowns}:
+		return nil
+	case <-f.quit:
+		return errTerminated
+	}
+}
+
+// Enqueue imports a batch of received transactions into the transaction pool
+// and the fetcher. This method may be called by both transaction broadcasts and
+// direct request replies. The differentiation is important so the fetcher can
+// re-schedule missing transactions as soon as possible.
+func (f *TxFetcher) Enqueue(peer string, txs []*types.Transaction, direct bool) error {
+	if direct {
+		txReplyInMeter.Mark(int64(len(txs)))
+	} else {
+		txBroadcastInMeter.Mark(int64(len(txs)))
+	}
+	// Push all the transactions into the pool, the fetcher only needs to know
+	// that they arrived, whether the pool accepted them or not.
+	hashes := make([]common.Hash, len(txs))
+	for i, tx := range txs {
+		hashes[i] = tx.Hash()
+	}
+	f.addTxs(txs)
+
+	select {
+	case f.cleanup <- &txDelivery{origin: peer, hashes: hashes, direct: direct}:
+		return nil
+	case <-f.quit:
+		return errTerminated
+	}
+}
+
+// Drop should be called when a peer disconnects. It cleans up all the internal
+// data structures of the given node.
+func (f *TxFetcher) Drop(peer string) error {
+	select {
+	case f.drop <- peer:
+		return nil
+	case <-f.quit:
+		return errTerminated
+	}
+}
+
+// Start boots up the announcement based synchroniser, accepting and processing
+// hash notifications and transaction fetches until termination requested.
+func (f *TxFetcher) Start() {
+	go f.loop()
+}
+
+// Stop terminates the announcement based synchroniser, canceling all pending
+// operations.
+func (f *TxFetcher) Stop() {
+	close(f.quit)
+}
+
+func (f *TxFetcher) loop() {
+	// Iterate the transaction fetching until a quit is requested. Time based
+	// events are collated into ticks of txGatherSlack to avoid tracking timers
+	// for each individual announcement and request.
+	var tick <-chan time.Time
+
+	for {
+		select {
+		case ann := <-f.notify:
+			f.announce(ann)
+
+		case delivery := <-f.cleanup:
+			f.deliver(delivery)
+
+		case peer := <-f.drop:
+			f.forget(peer)
+
+		case <-tick:
+			tick = nil
+			f.expire()
+
+		case <-f.quit:
+			return
+		}
+		// Allocate any queued up transactions to idle peers
+		f.schedule()
+
+		// Keep ticking while there are announcements or requests waiting on time
+		if tick == nil && (len(f.waitlist) > 0 || len(f.requests) > 0) {
+			tick = f.clock.After(txGatherSlack)
+		}
+		// If we're in a test, notify the tester that the loop iterated
+		if f.step != nil {
+			f.step <- struct{}{}
+		}
+	}
+}
+
+// announce tracks a batch of transactions announced by a peer, either adding
+// the peer as an alternate source of transactions already queued, or starting
+// the wait for a broadcast of newly discovered ones.
+func (f *TxFetcher) announce(ann *txAnnounce) {
+	announces := f.announces[ann.origin]
+	if announces == nil {
+		announces = make(map[common.Hash]struct{})
+		f.announces[ann.origin] = announces
+	}
+	for i, hash := range ann.hashes {
+		if _, ok := announces[hash]; ok {
+			continue // Duplicate announcement from the same peer
+		}
+		if len(announces) >= maxTxAnnounces {
+			txAnnounceDOSMeter.Mark(int64(len(ann.hashes) - i))
+			log.Debug("Peer exceeded transaction announcement limit", "peer", ann.origin, "limit", maxTxAnnounces)
+			break
+		}
+		announces[hash] = struct{}{}
+
+		// If the transaction is already queued or being fetched, just add a new
+		// source for it
+		if announcers := f.announced[hash]; announcers != nil {
+			announcers[ann.origin] = struct{}{}
+			continue
+		}
+		// Otherwise wait for a broadcast, tracking the peer as a source
+		if announcers := f.waitlist[hash]; announcers != nil {
+			announcers[ann.origin] = struct{}{}
+			continue
+		}
+		f.waitlist[hash] = map[string]struct{}{ann.origin: {}}
+		f.waittime[hash] = f.clock.Now()
+	}
+}
+
+// deliver untracks a batch of transactions imported into the pool. If they are
+// the reply to a request, any transactions missing from it are rescheduled to
+// be retrieved from other announcers.
+func (f *TxFetcher) deliver(delivery *txDelivery) {
+	for _, hash := range delivery.hashes {
+		f.untrack(hash)
+	}
+	if !delivery.direct {
+		return
+	}
+	req := f.requests[delivery.origin]
+	if req == nil {
+		return // Request already timed out, or an unsolicited reply
+	}
+	delete(f.requests, delivery.origin)
+
+	// Anything the peer didn't deliver it doesn't have, don't ask it again
+	for _, hash := range req.hashes {
+		if f.fetching[hash] != delivery.origin {
+			continue // Delivered, possibly by someone else
+		}
+		delete(f.fetching, hash)
+		f.unannounce(delivery.origin, hash)
+	}
+}
+
+// expire moves the transactions waiting for a broadcast for too long into the
+// fetch queue, and reschedules the retrievals of requests which timed out.
+func (f *TxFetcher) expire() {
+	now := f.clock.Now()
+
+	for hash, added := range f.waittime {
+		if time.Duration(now-added) < txArriveTimeout {
+			continue
+		}
+		f.announced[hash] = f.waitlist[hash]
+		delete(f.waitlist, hash)
+		delete(f.waittime, hash)
+	}
+	for peer, req := range f.requests {
+		if time.Duration(now-req.time) < txFetchTimeout {
+			continue
+		}
+		txRequestTimeoutMeter.Mark(int64(len(req.hashes)))
+		log.Debug("Transaction retrieval timed out", "peer", peer, "count", len(req.hashes))
+
+		// The peer failed to deliver in time, let the alternates try instead
+		for _, hash := range req.hashes {
+			if f.fetching[hash] != peer {
+				continue
+			}
+			delete(f.fetching, hash)
+			f.unannounce(peer, hash)
+		}
+		delete(f.requests, peer)
+	}
+}
+
+// schedule allocates the queued transactions to the idle peers which announced
+// them, requesting a batch from each.
+func (f *TxFetcher) schedule() {
+	for peer, announces := range f.announces {
+		if f.requests[peer] != nil {
+			continue // Peer busy with a retrieval, only one at a time
+		}
+		var hashes []common.Hash
+		for hash := range announces {
+			if _, ok := f.announced[hash]; !ok {
+				continue // Still waiting for a broadcast
+			}
+			if _, ok := f.fetching[hash]; ok {
+				continue // Already being retrieved from someone else
+			}
+			f.fetching[hash] = peer
+			if hashes = append(hashes, hash); len(hashes) >= maxTxRetrievals {
+				break
+			}
+		}
+		if len(hashes) == 0 {
+			continue
+		}
+		f.requests[peer] = &txRequest{hashes: hashes, time: f.clock.Now()}
+		txRequestOutMeter.Mark(int64(len(hashes)))
+
+		go func(peer string, hashes []common.Hash) {
+			// Try to fetch the transactions, but in case of a request failure
+			// (e.g. peer disconnected), reschedule the hashes.
+			if err := f.fetchTxs(peer, hashes); err != nil {
+				txRequestFailMeter.Mark(int64(len(hashes)))
+				f.Drop(peer)
+			}
+		}(peer, hashes)
+	}
+}
+
+// forget removes all traces of a disconnected peer, rescheduling any of its
+// in-flight retrievals to alternate announcers.
+func (f *TxFetcher) forget(peer string) {
+	for hash := range f.announces[peer] {
+		if f.fetching[hash] == peer {
+			delete(f.fetching, hash)
+		}
+		f.unannounce(peer, hash)
+	}
+	delete(f.requests, peer)
+	delete(f.announces, peer)
+}
+
+// unannounce removes a peer as the source of a transaction, untracking the
+// transaction altogether if no other peer announced it.
+func (f *TxFetcher) unannounce(peer string, hash common.Hash) {
+	if announces := f.announces[peer]; announces != nil {
+		delete(announces, hash)
+		if len(announces) == 0 {
+			delete(f.announces, peer)
+		}
+	}
+	for _, stage := range []map[common.Hash]map[string]struct{}{f.waitlist, f.announced} {
+		if announcers := stage[hash]; announcers != nil {
+			delete(announcers, peer)
+			if len(announcers) == 0 {
+				delete(stage, hash)
+				delete(f.waittime, hash)
+				delete(f.fetching, hash)
+			}
+		}
+	}
+}
+
+// untrack removes a transaction from all the stages of the fetcher.
+func (f *TxFetcher) untrack(hash common.Hash) {
+	for _, stage := range []map[common.Hash]map[string]struct{}{f.waitlist, f.announced} {
+		for peer := range stage[hash] {
+			if announces := f.announces[peer]; announces != nil {
+				delete(announces, hash)
+				if len(announces) == 0 {
+					delete(f.announces, peer)
+				}
+			}
+		}
+		delete(stage, hash)
+	}
+	delete(f.waittime, hash)
+	delete(f.fetching, hash)
+}
@@ -0,0 +1,247 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package fetcher
+
+import (
+	"math/big"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// testTxs is a set of transactions to use during testing.
+var testTxs = []*types.Transaction{
+	types.NewTransaction(5577006791947779410, common.Address{0x0f}, new(big.Int), 0, new(big.Int), nil),
+	types.NewTransaction(15352856648520921629, common.Address{0xbb}, new(big.Int), 0, new(big.Int), nil),
+	types.NewTransaction(3916589616287113937, common.Address{0x86}, new(big.Int), 0, new(big.Int), nil),
+}
+
+// txFetch is a transaction retrieval request made by the fetcher.
+type txFetch struct {
+	peer   string
+	hashes []common.Hash
+}
+
+// newTestTxFetcher creates a transaction fetcher running on a simulated clock,
+// which considers the given transactions already known and reports all of its
+// retrieval requests on the returned channel.
+func newTestTxFetcher(known ...common.Hash) (*TxFetcher, *mclock.Simulated, chan *txFetch) {
+	var (
+		clock   = new(mclock.Simulated)
+		fetches = make(chan *txFetch, 16)
+	)
+	hasTx := func(hash common.Hash) bool {
+		for _, k := range known {
+			if k == hash {
+				return true
+			}
+		}
+		return false
+	}
+	addTxs := func(txs []*types.Transaction) []error {
+		return make([]error, len(txs))
+	}
+	fetchTxs := func(peer string, hashes []common.Hash) error {
+		fetches <- &txFetch{peer: peer, hashes: hashes}
+		return nil
+	}
+	fetcher := NewTxFetcherForTests(hasTx, addTxs, fetchTxs, clock)
+	fetcher.step = make(chan struct{})
+	fetcher.Start()
+
+	return fetcher, clock, fetches
+}
+
+// verifyFetch checks that the next retrieval request is the expected one.
+func verifyFetch(t *testing.T, fetches chan *txFetch, peer string, hashes ...common.Hash) {
+	t.Helper()
+
+	select {
+	case fetch := <-fetches:
+		if fetch.peer != peer {
+			t.Fatalf("fetch peer mismatch: have %s, want %s", fetch.peer, peer)
+		}
+		have, want := sortHashes(fetch.hashes), sortHashes(hashes)
+		if len(have) != len(want) {
+			t.Fatalf("fetch hashes mismatch: have %x, want %x", have, want)
+		}
+		for i := range have {
+			if have[i] != want[i] {
+				t.Fatalf("fetch hashes mismatch: have %x, want %x", have, want)
+			}
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("fetch to %s timeout", peer)
+	}
+}
+
+// verifyNoFetch checks that no retrieval request is made.
+func verifyNoFetch(t *testing.T, fetches chan *txFetch) {
+	t.Helper()
+
+	select {
+	case fetch := <-fetches:
+		t.Fatalf("unexpected fetch from %s: %x", fetch.peer, fetch.hashes)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func sortHashes(hashes []common.Hash) []common.Hash {
+	sorted := append([]common.Hash{}, hashes...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Big().Cmp(sorted[j].Big()) < 0
+	})
+	return sorted
+}
+
+// Tests that announced transactions are only retrieved after waiting for a
+// potential broadcast, and that known transactions are not tracked at all.
+func TestTxFetcherWaiting(t *testing.T) {
+	fetcher, clock, fetches := newTestTxFetcher(testTxs[2].Hash())
+	defer fetcher.Stop()
+
+	fetcher.Notify("A", []common.Hash{testTxs[0].Hash(), testTxs[1].Hash(), testTxs[2].Hash()})
+	<-fetcher.step
+	verifyNoFetch(t, fetches)
+
+	clock.Run(txArriveTimeout / 2)
+	<-fetcher.step
+	verifyNoFetch(t, fetches)
+
+	clock.Run(txArriveTimeout / 2)
+	<-fetcher.step
+	verifyFetch(t, fetches, "A", testTxs[0].Hash(), testTxs[1].Hash())
+}
+
+// Tests that transactions broadcast during the wait period are not retrieved.
+func TestTxFetcherBroadcastArrival(t *testing.T) {
+	fetcher, clock, fetches := newTestTxFetcher()
+	defer fetcher.Stop()
+
+	fetcher.Notify("A", []common.Hash{testTxs[0].Hash(), testTxs[1].Hash()})
+	<-fetcher.step
+	fetcher.Enqueue("B", []*types.Transaction{testTxs[0]}, false)
+	<-fetcher.step
+
+	clock.Run(txArriveTimeout)
+	<-fetcher.step
+	verifyFetch(t, fetches, "A", testTxs[1].Hash())
+}
+
+// Tests that transactions missing from a reply are rescheduled to alternate
+// announcers, but not re-requested from the replying peer.
+func TestTxFetcherPartialReply(t *testing.T) {
+	fetcher, clock, fetches := newTestTxFetcher()
+	defer fetcher.Stop()
+
+	fetcher.Notify("A", []common.Hash{testTxs[0].Hash(), testTxs[1].Hash()})
+	<-fetcher.step
+	clock.Run(txArriveTimeout)
+	<-fetcher.step
+	verifyFetch(t, fetches, "A", testTxs[0].Hash(), testTxs[1].Hash())
+
+	// An alternate announcer must not be asked while the retrieval is in flight
+	fetcher.Notify("B", []common.Hash{testTxs[1].Hash()})
+	<-fetcher.step
+	verifyNoFetch(t, fetches)
+
+	fetcher.Enqueue("A", []*types.Transaction{testTxs[0]}, true)
+	<-fetcher.step
+	verifyFetch(t, fetches, "B", testTxs[1].Hash())
+
+	if _, ok := fetcher.announces["A"]; ok {
+		t.Errorf("replying peer still tracked as announcer")
+	}
+}
+
+// Tests that timed out retrievals are rescheduled to alternate announcers.
+func TestTxFetcherTimeout(t *testing.T) {
+	fetcher, clock, fetches := newTestTxFetcher()
+	defer fetcher.Stop()
+
+	fetcher.Notify("A", []common.Hash{testTxs[0].Hash()})
+	<-fetcher.step
+	clock.Run(txArriveTimeout)
+	<-fetcher.step
+	verifyFetch(t, fetches, "A", testTxs[0].Hash())
+
+	fetcher.Notify("B", []common.Hash{testTxs[0].Hash()})
+	<-fetcher.step
+
+	clock.Run(txFetchTimeout)
+	<-fetcher.step
+	verifyFetch(t, fetches, "B", testTxs[0].Hash())
+
+	// A late reply from the timed out peer still delivers the transaction
+	fetcher.Enqueue("A", []*types.Transaction{testTxs[0]}, true)
+	<-fetcher.step
+	if len(fetcher.announced) != 0 || len(fetcher.fetching) != 0 {
+		t.Errorf("delivered transaction still tracked: announced %d, fetching %d", len(fetcher.announced), len(fetcher.fetching))
+	}
+}
+
+// Tests that dropping a peer reschedules its retrievals to alternate announcers
+// and cleans up all state once no announcers remain.
+func TestTxFetcherDrop(t *testing.T) {
+	fetcher, clock, fetches := newTestTxFetcher()
+	defer fetcher.Stop()
+
+	fetcher.Notify("A", []common.Hash{testTxs[0].Hash(), testTxs[1].Hash()})
+	<-fetcher.step
+	clock.Run(txArriveTimeout)
+	<-fetcher.step
+	verifyFetch(t, fetches, "A", testTxs[0].Hash(), testTxs[1].Hash())
+
+	fetcher.Notify("B", []common.Hash{testTxs[0].Hash()})
+	<-fetcher.step
+
+	fetcher.Drop("A")
+	<-fetcher.step
+	verifyFetch(t, fetches, "B", testTxs[0].Hash())
+
+	fetcher.Drop("B")
+	<-fetcher.step
+	if len(fetcher.waitlist) != 0 || len(fetcher.waittime) != 0 || len(fetcher.announced) != 0 ||
+		len(fetcher.fetching) != 0 || len(fetcher.requests) != 0 || len(fetcher.announces) != 0 {
+		t.Errorf("state leaked after dropping all peers")
+	}
+}
+
+// Tests that a peer cannot make the fetcher track an unbounded number of
+// announcements.
+func TestTxFetcherAnnounceLimit(t *testing.T) {
+	fetcher, _, _ := newTestTxFetcher()
+	defer fetcher.Stop()
+
+	hashes := make([]common.Hash, maxTxAnnounces+16)
+	for i := range hashes {
+		hashes[i] = common.BigToHash(big.NewInt(int64(i + 1)))
+	}
+	fetcher.Notify("A", hashes)
+	<-fetcher.step
+
+	if have := len(fetcher.announces["A"]); have != maxTxAnnounces {
+		t.Errorf("tracked announcements mismatch: have %d, want %d", have, maxTxAnnounces)
+	}
+	if have := len(fetcher.waitlist); have != maxTxAnnounces {
+		t.Errorf("waitlist size mismatch: have %d, want %d", have, maxTxAnnounces)
+	}
+}
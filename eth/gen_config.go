@@ -19,41 +19,46 @@ var _ = (*configMarshaling)(nil)
 // MarshalTOML marshals as TOML.
 func (c Config) MarshalTOML() (interface{}, error) {
 	type Config struct {
-		Genesis                 *core.Genesis `toml:",omitempty"`
-		NetworkId               uint64
-		SyncMode                downloader.SyncMode
-		NoPruning               bool
-		LightServ               int `toml:",omitempty"`
-		LightPeers              int `toml:",omitempty"`
-		OnlyAnnounce            bool
-		ULC                     *ULCConfig `toml:",omitempty"`
-		SkipBcVersionCheck      bool       `toml:"-"`
-		DatabaseHandles         int        `toml:"-"`
-		DatabaseCache           int
-		TrieCleanCache          int
-		TrieDirtyCache          int
-		TrieTimeout             time.Duration
-		Etherbase               common.Address `toml:",omitempty"`
-		MinerNotify             []string       `toml:",omitempty"`
-		MinerExtraData          hexutil.Bytes  `toml:",omitempty"`
-		MinerGasFloor           uint64
-		MinerGasCeil            uint64
-		MinerGasPrice           *big.Int
-		MinerRecommit           time.Duration
-		MinerNoverify           bool
-		Ethash                  ethash.Config
-		TxPool                  core.TxPoolConfig
-		GPO                     gasprice.Config
-		EnablePreimageRecording bool
-		DocRoot                 string `toml:"-"`
-		EWASMInterpreter        string
-		EVMInterpreter          string
+		Genesis                  *core.Genesis `toml:",omitempty"`
+		NetworkId                uint64
+		SyncMode                 downloader.SyncMode
+		NoPruning                bool
+		DiscoveryURLs            []string `toml:",omitempty"`
+		LightServ                int      `toml:",omitempty"`
+		LightPeers               int      `toml:",omitempty"`
+		OnlyAnnounce             bool
+		ULC                      *ULCConfig `toml:",omitempty"`
+		SkipBcVersionCheck       bool       `toml:"-"`
+		DatabaseHandles          int        `toml:"-"`
+		DatabaseCache            int
+		DatabaseFreezer          string
+		TrieCleanCache           int
+		TrieDirtyCache           int
+		TrieTimeout              time.Duration
+		SnapshotCache            int
+		DatabaseFreezerThreshold uint64
+		Etherbase                common.Address `toml:",omitempty"`
+		MinerNotify              []string       `toml:",omitempty"`
+		MinerExtraData           hexutil.Bytes  `toml:",omitempty"`
+		MinerGasFloor            uint64
+		MinerGasCeil             uint64
+		MinerGasPrice            *big.Int
+		MinerRecommit            time.Duration
+		MinerNoverify            bool
+		Ethash                   ethash.Config
+		TxPool                   core.TxPoolConfig
+		GPO                      gasprice.Config
+		EnablePreimageRecording  bool
+		DocRoot                  string `toml:"-"`
+		EWASMInterpreter         string
+		EVMInterpreter           string
 	}
 	var enc Config
 	enc.Genesis = c.Genesis
 	enc.NetworkId = c.NetworkId
 	enc.SyncMode = c.SyncMode
 	enc.NoPruning = c.NoPruning
+	enc.DiscoveryURLs = c.DiscoveryURLs
 	enc.LightServ = c.LightServ
 	enc.LightPeers = c.LightPeers
 	enc.OnlyAnnounce = c.OnlyAnnounce
@@ -61,9 +66,12 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.SkipBcVersionCheck = c.SkipBcVersionCheck
 	enc.DatabaseHandles = c.DatabaseHandles
 	enc.DatabaseCache = c.DatabaseCache
+	enc.DatabaseFreezer = c.DatabaseFreezer
 	enc.TrieCleanCache = c.TrieCleanCache
 	enc.TrieDirtyCache = c.TrieDirtyCache
 	enc.TrieTimeout = c.TrieTimeout
+	enc.SnapshotCache = c.SnapshotCache
+	enc.DatabaseFreezerThreshold = c.DatabaseFreezerThreshold
 	enc.Etherbase = c.Etherbase
 	enc.MinerNotify = c.MinerNotify
 	enc.MinerExtraData = c.MinerExtraData
@@ -86,35 +94,39 @@ func (c Config) MarshalTOML() (interface{}, error) {
 // UnmarshalTOML unmarshals from TOML.
 func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	type Config struct {
-		Genesis                 *core.Genesis `toml:",omitempty"`
-		NetworkId               *uint64
-		SyncMode                *downloader.SyncMode
-		NoPruning               *bool
-		LightServ               *int `toml:",omitempty"`
-		LightPeers              *int `toml:",omitempty"`
-		OnlyAnnounce            *bool
-		ULC                     *ULCConfig `toml:",omitempty"`
-		SkipBcVersionCheck      *bool      `toml:"-"`
-		DatabaseHandles         *int       `toml:"-"`
-		DatabaseCache           *int
-		TrieCleanCache          *int
-		TrieDirtyCache          *int
-		TrieTimeout             *time.Duration
-		Etherbase               *common.Address `toml:",omitempty"`
-		MinerNotify             []string        `toml:",omitempty"`
-		MinerExtraData          *hexutil.Bytes  `toml:",omitempty"`
-		MinerGasFloor           *uint64
-		MinerGasCeil            *uint64
-		MinerGasPrice           *big.Int
-		MinerRecommit           *time.Duration
-		MinerNoverify           *bool
-		Ethash                  *ethash.Config
-		TxPool                  *core.TxPoolConfig
-		GPO                     *gasprice.Config
-		EnablePreimageRecording *bool
-		DocRoot                 *string `toml:"-"`
-		EWASMInterpreter        *string
-		EVMInterpreter          *string
+		Genesis                  *core.Genesis `toml:",omitempty"`
+		NetworkId                *uint64
+		SyncMode                 *downloader.SyncMode
+		NoPruning                *bool
+		DiscoveryURLs            []string `toml:",omitempty"`
+		LightServ                *int     `toml:",omitempty"`
+		LightPeers               *int     `toml:",omitempty"`
+		OnlyAnnounce             *bool
+		ULC                      *ULCConfig `toml:",omitempty"`
+		SkipBcVersionCheck       *bool      `toml:"-"`
+		DatabaseHandles          *int       `toml:"-"`
+		DatabaseCache            *int
+		DatabaseFreezer          *string
+		TrieCleanCache           *int
+		TrieDirtyCache           *int
+		TrieTimeout              *time.Duration
+		SnapshotCache            *int
+		DatabaseFreezerThreshold *uint64
+		Etherbase                *common.Address `toml:",omitempty"`
+		MinerNotify              []string        `toml:",omitempty"`
+		MinerExtraData           *hexutil.Bytes  `toml:",omitempty"`
+		MinerGasFloor            *uint64
+		MinerGasCeil             *uint64
+		MinerGasPrice            *big.Int
+		MinerRecommit            *time.Duration
+		MinerNoverify            *bool
+		Ethash                   *ethash.Config
+		TxPool                   *core.TxPoolConfig
+		GPO                      *gasprice.Config
+		EnablePreimageRecording  *bool
+		DocRoot                  *string `toml:"-"`
+		EWASMInterpreter         *string
+		EVMInterpreter           *string
 	}
 	var dec Config
 	if err := unmarshal(&dec); err != nil {
@@ -132,6 +144,9 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.NoPruning != nil {
 		c.NoPruning = *dec.NoPruning
 	}
+	if dec.DiscoveryURLs != nil {
+		c.DiscoveryURLs = dec.DiscoveryURLs
+	}
 	if dec.LightServ != nil {
 		c.LightServ = *dec.LightServ
 	}
@@ -153,6 +168,9 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.DatabaseCache != nil {
 		c.DatabaseCache = *dec.DatabaseCache
 	}
+	if dec.DatabaseFreezer != nil {
+		c.DatabaseFreezer = *dec.DatabaseFreezer
+	}
 	if dec.TrieCleanCache != nil {
 		c.TrieCleanCache = *dec.TrieCleanCache
 	}
@@ -162,6 +180,12 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.TrieTimeout != nil {
 		c.TrieTimeout = *dec.TrieTimeout
 	}
+	if dec.SnapshotCache != nil {
+		c.SnapshotCache = *dec.SnapshotCache
+	}
+	if dec.DatabaseFreezerThreshold != nil {
+		c.DatabaseFreezerThreshold = *dec.DatabaseFreezerThreshold
+	}
 	if dec.Etherbase != nil {
 		c.Etherbase = *dec.Etherbase
 	}
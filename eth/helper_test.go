@@ -22,6 +22,7 @@ package eth
 import (
 	"crypto/ecdsa"
 	"crypto/rand"
+	"fmt"
 	"math/big"
 	"sort"
 	"sync"
@@ -30,6 +31,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/forkid"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -46,6 +48,55 @@ var (
 	testBank       = crypto.PubkeyToAddress(testBankKey.PublicKey)
 )
 
+var (
+	// testStorageContract is a contract deployed in the genesis of the chains
+	// created by newTestEthereum. Called with calldata, it stores its first word
+	// into slot 0, otherwise it returns the content of slot 0.
+	testStorageContract = common.Address{0xc0}
+	testStorageCode     = common.FromHex("0x3615600c57600035600055005b60005460005260206000f3")
+)
+
+// newTestEthereum creates a minimal Ethereum service on top of a chain of the
+// given number of blocks, each of which stores its own number into slot 0 of the
+// test storage contract. The blocks of the chain are returned too, starting with
+// the genesis.
+func newTestEthereum(t *testing.T, blocks int) (*Ethereum, []*types.Block) {
+	var (
+		db     = ethdb.NewMemDatabase()
+		engine = ethash.NewFaker()
+		gspec  = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: core.GenesisAlloc{
+				testBank:            {Balance: big.NewInt(params.Ether)},
+				testStorageContract: {Balance: new(big.Int), Code: testStorageCode},
+			},
+		}
+		genesis = gspec.MustCommit(db)
+	)
+	chain, _ := core.GenerateChain(gspec.Config, genesis, engine, db, blocks, func(i int, b *core.BlockGen) {
+		data := common.BigToHash(big.NewInt(int64(i + 1))).Bytes()
+		tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(testBank), testStorageContract, new(big.Int), 100000, big.NewInt(1), data), types.HomesteadSigner{}, testBankKey)
+		b.AddTx(tx)
+	})
+	blockchain, err := core.NewBlockChain(db, nil, gspec.Config, engine, vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create blockchain: %v", err)
+	}
+	if _, err := blockchain.InsertChain(chain); err != nil {
+		t.Fatalf("Failed to import chain: %v", err)
+	}
+	eth := &Ethereum{
+		config:      &Config{RPCGasCap: big.NewInt(1000000)},
+		chainConfig: gspec.Config,
+		chainDb:     db,
+		blockchain:  blockchain,
+		engine:      engine,
+	}
+	eth.APIBackend = &EthAPIBackend{eth: eth}
+
+	return eth, append([]*types.Block{genesis}, chain...)
+}
+
 // newTestProtocolManager creates a new protocol manager for testing purposes,
 // with the given number of blocks already known, and potential notification
 // channels for different events.
@@ -95,6 +146,26 @@ type testTxPool struct {
 	lock sync.RWMutex // Protects the transaction pool
 }
 
+// Has returns an indicator whether txpool has a transaction
+// cached with the given hash.
+func (p *testTxPool) Has(hash common.Hash) bool {
+	return p.Get(hash) != nil
+}
+
+// Get retrieves the transaction from local txpool with given
+// tx hash.
+func (p *testTxPool) Get(hash common.Hash) *types.Transaction {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	for _, tx := range p.pool {
+		if tx.Hash() == hash {
+			return tx
+		}
+	}
+	return nil
+}
+
 // AddRemotes appends a batch of transactions to the pool, and notifies any
 // listeners if the addition channel is non nil
 func (p *testTxPool) AddRemotes(txs []*types.Transaction) []error {
@@ -171,20 +242,35 @@ func newTestPeer(name string, version int, pm *ProtocolManager, shake bool) (*te
 			head    = pm.blockchain.CurrentHeader()
 			td      = pm.blockchain.GetTd(head.Hash(), head.Number.Uint64())
 		)
-		tp.handshake(nil, td, head.Hash(), genesis.Hash())
+		tp.handshake(nil, td, head.Hash(), genesis.Hash(), forkid.NewID(pm.blockchain), forkid.NewFilter(pm.blockchain))
 	}
 	return tp, errc
 }
 
 // handshake simulates a trivial handshake that expects the same state from the
 // remote side as we are simulating locally.
-func (p *testPeer) handshake(t *testing.T, td *big.Int, head common.Hash, genesis common.Hash) {
-	msg := &statusData{
-		ProtocolVersion: uint32(p.version),
-		NetworkId:       DefaultConfig.NetworkId,
-		TD:              td,
-		CurrentBlock:    head,
-		GenesisBlock:    genesis,
+func (p *testPeer) handshake(t *testing.T, td *big.Int, head common.Hash, genesis common.Hash, forkID forkid.ID, forkFilter forkid.Filter) {
+	var msg interface{}
+	switch {
+	case p.version == eth63 || p.version == eth62:
+		msg = &statusData63{
+			ProtocolVersion: uint32(p.version),
+			NetworkId:       DefaultConfig.NetworkId,
+			TD:              td,
+			CurrentBlock:    head,
+			GenesisBlock:    genesis,
+		}
+	case p.version >= eth64:
+		msg = &statusData{
+			ProtocolVersion: uint32(p.version),
+			NetworkID:       DefaultConfig.NetworkId,
+			TD:              td,
+			Head:            head,
+			Genesis:         genesis,
+			ForkID:          forkID,
+		}
+	default:
+		panic(fmt.Sprintf("unsupported eth protocol version: %d", p.version))
 	}
 	if err := p2p.ExpectMsg(p.app, StatusMsg, msg); err != nil {
 		t.Fatalf("status recv: %v", err)
@@ -0,0 +1,99 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// precompiles is the set of precompiled contracts the built in tracers skip,
+// matching the isPrecompiled helper exposed to the JavaScript tracers.
+var precompiles = vm.PrecompiledContractsForConfig(params.AllEthashProtocolChanges, big.NewInt(0))
+
+// isPrecompiled reports whether addr is a precompiled contract.
+func isPrecompiled(addr common.Address) bool {
+	_, ok := precompiles[addr]
+	return ok
+}
+
+// interrupter implements the Stop method of native tracers.
+type interrupter struct {
+	interrupt uint32 // Atomic flag to signal execution interruption
+	reason    error  // Textual reason for the interruption
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (i *interrupter) Stop(err error) {
+	i.reason = err
+	atomic.StoreUint32(&i.interrupt, 1)
+}
+
+// stopped returns whether the tracer was interrupted.
+func (i *interrupter) stopped() bool {
+	return atomic.LoadUint32(&i.interrupt) > 0
+}
+
+// peekStack returns the idx-th element from the top of the stack, or zero if
+// the stack is not deep enough.
+func peekStack(stack *vm.Stack, idx int) *big.Int {
+	data := stack.Data()
+	if len(data) <= idx {
+		log.Warn("Tracer accessed out of bound stack", "size", len(data), "index", idx)
+		return new(big.Int)
+	}
+	return data[len(data)-idx-1]
+}
+
+// peekUint64 returns the idx-th element from the top of the stack as a uint64,
+// saturating at the maximum value if it does not fit.
+func peekUint64(stack *vm.Stack, idx int) uint64 {
+	val := peekStack(stack, idx)
+	if !val.IsUint64() {
+		return ^uint64(0)
+	}
+	return val.Uint64()
+}
+
+// sliceMemory returns a copy of size bytes of memory starting at offset, or nil
+// if the memory is not large enough.
+func sliceMemory(memory *vm.Memory, offset, size uint64) []byte {
+	if offset+size < offset || uint64(memory.Len()) < offset+size {
+		log.Warn("Tracer accessed out of bound memory", "available", memory.Len(), "offset", offset, "size", size)
+		return nil
+	}
+	return memory.Get(int64(offset), int64(size))
+}
+
+// encodeJSON serializes a tracer result the same way the JavaScript engine
+// does, without escaping HTML characters.
+func encodeJSON(v interface{}) (json.RawMessage, error) {
+	buf := new(bytes.Buffer)
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return json.RawMessage(bytes.TrimRight(buf.Bytes(), "\n")), nil
+}
@@ -0,0 +1,288 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+func init() {
+	RegisterNative("callTracer", newCallTracer)
+}
+
+// callFrame is a single call of the call tracer. The field order matches the
+// order the JavaScript callTracer serializes its results in.
+type callFrame struct {
+	Type    string          `json:"type,omitempty"`
+	From    *common.Address `json:"from,omitempty"`
+	To      *common.Address `json:"to,omitempty"`
+	Value   *hexutil.Big    `json:"value,omitempty"`
+	Gas     *hexutil.Uint64 `json:"gas,omitempty"`
+	GasUsed *hexutil.Uint64 `json:"gasUsed,omitempty"`
+	Input   *hexutil.Bytes  `json:"input,omitempty"`
+	Output  *hexutil.Bytes  `json:"output,omitempty"`
+	Error   string          `json:"error,omitempty"`
+	Time    string          `json:"time,omitempty"`
+	Calls   []*callFrame    `json:"calls,omitempty"`
+
+	gasIn   uint64 // Gas available before the call opcode executed
+	gasCost uint64 // Cost of the call opcode itself
+	outOff  uint64 // Memory offset the call output is written to
+	outLen  uint64 // Length of the memory segment reserved for the output
+}
+
+// callTracer is a native implementation of the JavaScript callTracer, which
+// extracts and reports all the internal calls made by a transaction.
+type callTracer struct {
+	interrupter
+
+	callstack  []*callFrame // Current recursive call stack of the EVM execution
+	descended  bool         // Whether we've just descended into an inner call
+	typ        string       // Type of the outer transaction (CALL or CREATE)
+	from, to   common.Address
+	input      []byte
+	gas        uint64
+	value      *big.Int
+	output     []byte
+	gasUsed    uint64
+	time       time.Duration
+	err        error // Error reported by the outer execution
+	stopReason error // Interruption reason, if tracing was stopped
+}
+
+// newCallTracer creates a native call tracer.
+func newCallTracer() TxTracer {
+	return &callTracer{callstack: []*callFrame{{}}}
+}
+
+// CaptureStart implements the Tracer interface to initialize the tracing operation.
+func (t *callTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	t.typ = "CALL"
+	if create {
+		t.typ = "CREATE"
+	}
+	t.from, t.to = from, to
+	t.input = common.CopyBytes(input)
+	t.gas = gas
+	t.value = value
+	return nil
+}
+
+// CaptureState implements the Tracer interface to trace a single step of VM execution.
+func (t *callTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	if t.stopReason != nil {
+		return nil
+	}
+	if t.stopped() {
+		t.stopReason = t.reason
+		return nil
+	}
+	// Capture any errors immediately
+	if err != nil {
+		t.fault(err)
+		return nil
+	}
+	// If a new contract is being created, add to the call stack
+	if op == vm.CREATE || op == vm.CREATE2 {
+		from := contract.Address()
+		input := hexutil.Bytes(sliceMemory(memory, peekUint64(stack, 1), peekUint64(stack, 2)))
+
+		t.callstack = append(t.callstack, &callFrame{
+			Type:    op.String(),
+			From:    &from,
+			Input:   &input,
+			Value:   (*hexutil.Big)(new(big.Int).Set(peekStack(stack, 0))),
+			gasIn:   gas,
+			gasCost: cost,
+		})
+		t.descended = true
+		return nil
+	}
+	// If a contract is being self destructed, gather that as a subcall too
+	if op == vm.SELFDESTRUCT {
+		parent := t.callstack[len(t.callstack)-1]
+		parent.Calls = append(parent.Calls, &callFrame{Type: op.String()})
+		return nil
+	}
+	// If a new method invocation is being done, add to the call stack
+	if op == vm.CALL || op == vm.CALLCODE || op == vm.DELEGATECALL || op == vm.STATICCALL {
+		// Skip any pre-compile invocations, those are just fancy opcodes
+		to := common.BigToAddress(peekStack(stack, 1))
+		if isPrecompiled(to) {
+			return nil
+		}
+		off := 1
+		if op == vm.DELEGATECALL || op == vm.STATICCALL {
+			off = 0
+		}
+		from := contract.Address()
+		input := hexutil.Bytes(sliceMemory(memory, peekUint64(stack, 2+off), peekUint64(stack, 3+off)))
+
+		call := &callFrame{
+			Type:    op.String(),
+			From:    &from,
+			To:      &to,
+			Input:   &input,
+			gasIn:   gas,
+			gasCost: cost,
+			outOff:  peekUint64(stack, 4+off),
+			outLen:  peekUint64(stack, 5+off),
+		}
+		if op != vm.DELEGATECALL && op != vm.STATICCALL {
+			call.Value = (*hexutil.Big)(new(big.Int).Set(peekStack(stack, 2)))
+		}
+		t.callstack = append(t.callstack, call)
+		t.descended = true
+		return nil
+	}
+	// If we've just descended into an inner call, retrieve it's true allowance. We
+	// need to extract if from within the call as there may be funky gas dynamics
+	// with regard to requested and actually given gas (2300 stipend, 63/64 rule).
+	if t.descended {
+		if depth >= len(t.callstack) {
+			t.callstack[len(t.callstack)-1].Gas = (*hexutil.Uint64)(&gas)
+		}
+		t.descended = false
+	}
+	// If an existing call is returning, pop off the call stack
+	if op == vm.REVERT {
+		t.callstack[len(t.callstack)-1].Error = "execution reverted"
+		return nil
+	}
+	if depth == len(t.callstack)-1 {
+		// Pop off the last call and get the execution results
+		call := t.callstack[len(t.callstack)-1]
+		t.callstack = t.callstack[:len(t.callstack)-1]
+
+		if call.Type == vm.CREATE.String() || call.Type == vm.CREATE2.String() {
+			// If the call was a CREATE, retrieve the contract address and output code
+			gasUsed := hexutil.Uint64(call.gasIn - call.gasCost - gas)
+			call.GasUsed = &gasUsed
+
+			if ret := peekStack(stack, 0); ret.Sign() != 0 {
+				to := common.BigToAddress(ret)
+				output := hexutil.Bytes(env.StateDB.GetCode(to))
+				call.To, call.Output = &to, &output
+			} else if call.Error == "" {
+				call.Error = "internal failure"
+			}
+		} else if call.Gas != nil {
+			// If the call was a contract call, retrieve the gas usage and output
+			gasUsed := hexutil.Uint64(call.gasIn - call.gasCost + uint64(*call.Gas) - gas)
+			call.GasUsed = &gasUsed
+
+			if ret := peekStack(stack, 0); ret.Sign() != 0 {
+				output := hexutil.Bytes(sliceMemory(memory, call.outOff, call.outLen))
+				call.Output = &output
+			} else if call.Error == "" {
+				call.Error = "internal failure"
+			}
+		}
+		// Inject the call into the previous one
+		parent := t.callstack[len(t.callstack)-1]
+		parent.Calls = append(parent.Calls, call)
+	}
+	return nil
+}
+
+// CaptureFault implements the Tracer interface to trace an execution fault
+// while running an opcode.
+func (t *callTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	if t.stopReason == nil {
+		t.fault(err)
+	}
+	return nil
+}
+
+// fault handles the failure of the currently executing call.
+func (t *callTracer) fault(err error) {
+	// If the topmost call already reverted, don't handle the additional fault again
+	if t.callstack[len(t.callstack)-1].Error != "" {
+		return
+	}
+	// Pop off the just failed call
+	call := t.callstack[len(t.callstack)-1]
+	t.callstack = t.callstack[:len(t.callstack)-1]
+	call.Error = err.Error()
+
+	// Consume all available gas
+	if call.Gas != nil {
+		gasUsed := *call.Gas
+		call.GasUsed = &gasUsed
+	}
+	// Flatten the failed call into its parent
+	if len(t.callstack) > 0 {
+		parent := t.callstack[len(t.callstack)-1]
+		parent.Calls = append(parent.Calls, call)
+		return
+	}
+	// Last call failed too, leave it in the stack
+	t.callstack = append(t.callstack, call)
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *callTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) error {
+	t.output = common.CopyBytes(output)
+	t.gasUsed = gasUsed
+	t.time = d
+	t.err = err
+	return nil
+}
+
+// GetResult returns the JSON encoded call tree of the traced transaction.
+func (t *callTracer) GetResult() (json.RawMessage, error) {
+	if t.stopReason != nil {
+		return nil, t.stopReason
+	}
+	var (
+		value   = new(big.Int)
+		gas     = hexutil.Uint64(t.gas)
+		gasUsed = hexutil.Uint64(t.gasUsed)
+		input   = hexutil.Bytes(t.input)
+		output  = hexutil.Bytes(t.output)
+	)
+	if t.value != nil {
+		value.Set(t.value)
+	}
+	result := &callFrame{
+		Type:    t.typ,
+		From:    &t.from,
+		To:      &t.to,
+		Value:   (*hexutil.Big)(value),
+		Gas:     &gas,
+		GasUsed: &gasUsed,
+		Input:   &input,
+		Output:  &output,
+		Time:    t.time.String(),
+		Calls:   t.callstack[0].Calls,
+	}
+	if t.callstack[0].Error != "" {
+		result.Error = t.callstack[0].Error
+	} else if t.err != nil {
+		result.Error = t.err.Error()
+	}
+	if result.Error != "" {
+		result.Output = nil
+	}
+	return encodeJSON(result)
+}
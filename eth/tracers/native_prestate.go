@@ -0,0 +1,254 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func init() {
+	RegisterNative("prestateTracer", newPrestateTracer)
+}
+
+// prestateAccount is the pre-execution state of a single account.
+type prestateAccount struct {
+	Balance *hexutil.Big  `json:"balance"`
+	Nonce   int64         `json:"nonce"`
+	Code    hexutil.Bytes `json:"code"`
+	Storage *orderedMap   `json:"storage"`
+}
+
+// prestateTracer is a native implementation of the JavaScript prestateTracer,
+// which outputs sufficient information to create a local execution of the
+// transaction from a custom assembled genesis block.
+type prestateTracer struct {
+	interrupter
+
+	db       vm.StateDB  // State database to pull the accessed accounts from
+	prestate *orderedMap // Assembled allocations keyed by address
+
+	create   bool
+	from, to common.Address
+	value    *big.Int
+
+	stopReason error // Interruption reason, if tracing was stopped
+}
+
+// newPrestateTracer creates a native prestate tracer.
+func newPrestateTracer() TxTracer {
+	return new(prestateTracer)
+}
+
+// lookupAccount injects the specified account into the prestate object.
+func (t *prestateTracer) lookupAccount(addr common.Address) {
+	key := hexutil.Encode(addr[:])
+	if t.prestate.has(key) {
+		return
+	}
+	t.prestate.set(key, &prestateAccount{
+		Balance: (*hexutil.Big)(new(big.Int).Set(t.db.GetBalance(addr))),
+		Nonce:   int64(t.db.GetNonce(addr)),
+		Code:    t.db.GetCode(addr),
+		Storage: newOrderedMap(),
+	})
+}
+
+// lookupStorage injects the specified storage entry of the given account into
+// the prestate object.
+func (t *prestateTracer) lookupStorage(addr common.Address, slot common.Hash) {
+	t.lookupAccount(addr)
+
+	storage := t.prestate.get(hexutil.Encode(addr[:])).(*prestateAccount).Storage
+	if key := hexutil.Encode(slot[:]); !storage.has(key) {
+		val := t.db.GetState(addr, slot)
+		storage.set(key, hexutil.Bytes(val[:]))
+	}
+}
+
+// CaptureStart implements the Tracer interface to initialize the tracing operation.
+func (t *prestateTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	t.create = create
+	t.from, t.to = from, to
+	t.value = value
+	return nil
+}
+
+// CaptureState implements the Tracer interface to trace a single step of VM execution.
+func (t *prestateTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	if t.stopReason != nil {
+		return nil
+	}
+	if t.stopped() {
+		t.stopReason = t.reason
+		return nil
+	}
+	// Add the current account if we just started tracing
+	if t.prestate == nil {
+		t.db = env.StateDB
+		t.prestate = newOrderedMap()
+
+		// Balance will potentially be wrong here, since this will include the value
+		// sent along with the message. We fix that in GetResult.
+		t.lookupAccount(contract.Address())
+	}
+	// Whenever new state is accessed, add it to the prestate
+	switch op {
+	case vm.EXTCODECOPY, vm.EXTCODESIZE, vm.BALANCE:
+		t.lookupAccount(common.BigToAddress(peekStack(stack, 0)))
+
+	case vm.CREATE:
+		from := contract.Address()
+		t.lookupAccount(crypto.CreateAddress(from, t.db.GetNonce(from)))
+
+	case vm.CREATE2:
+		// stack: salt, size, offset, endowment
+		from := contract.Address()
+		code := sliceMemory(memory, peekUint64(stack, 1), peekUint64(stack, 2))
+		salt := common.BigToHash(peekStack(stack, 3))
+		t.lookupAccount(crypto.CreateAddress2(from, salt, crypto.Keccak256(code)))
+
+	case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+		t.lookupAccount(common.BigToAddress(peekStack(stack, 1)))
+
+	case vm.SSTORE, vm.SLOAD:
+		t.lookupStorage(contract.Address(), common.BigToHash(peekStack(stack, 0)))
+	}
+	return nil
+}
+
+// CaptureFault implements the Tracer interface to trace an execution fault
+// while running an opcode.
+func (t *prestateTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	return nil
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *prestateTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) error {
+	return nil
+}
+
+// GetResult returns the JSON encoded prestate of the accounts the traced
+// transaction accessed.
+func (t *prestateTracer) GetResult() (json.RawMessage, error) {
+	if t.stopReason != nil {
+		return nil, t.stopReason
+	}
+	if t.prestate == nil {
+		// No code was executed, there's no accessed state to report
+		return json.RawMessage(`{}`), nil
+	}
+	// At this point, we need to deduct the 'value' from the outer transaction,
+	// and move it back to the origin
+	t.lookupAccount(t.from)
+	t.lookupAccount(t.to)
+
+	value := t.value
+	if value == nil {
+		value = new(big.Int)
+	}
+	fromAcc := t.prestate.get(hexutil.Encode(t.from[:])).(*prestateAccount)
+	toAcc := t.prestate.get(hexutil.Encode(t.to[:])).(*prestateAccount)
+
+	toAcc.Balance = (*hexutil.Big)(new(big.Int).Sub(toAcc.Balance.ToInt(), value))
+	fromAcc.Balance = (*hexutil.Big)(new(big.Int).Add(fromAcc.Balance.ToInt(), value))
+
+	// Decrement the caller's nonce, and remove empty create targets
+	fromAcc.Nonce--
+	if t.create {
+		// We can blindly delete the contract prestate, as any existing state would
+		// have caused the transaction to be rejected as invalid in the first place.
+		t.prestate.delete(hexutil.Encode(t.to[:]))
+	}
+	return encodeJSON(t.prestate)
+}
+
+// orderedMap is a JSON object which retains the insertion order of its keys
+// when serialized, mirroring the behaviour of JavaScript objects.
+type orderedMap struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// newOrderedMap creates an empty ordered map.
+func newOrderedMap() *orderedMap {
+	return &orderedMap{values: make(map[string]interface{})}
+}
+
+// has reports whether key is present in the map.
+func (m *orderedMap) has(key string) bool {
+	_, ok := m.values[key]
+	return ok
+}
+
+// get retrieves the value stored under key, or nil if it's not present.
+func (m *orderedMap) get(key string) interface{} {
+	return m.values[key]
+}
+
+// set stores value under key, appending the key if it's not yet present.
+func (m *orderedMap) set(key string, value interface{}) {
+	if !m.has(key) {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+// delete removes key from the map.
+func (m *orderedMap) delete(key string) {
+	if !m.has(key) {
+		return
+	}
+	delete(m.values, key)
+	for i, k := range m.keys {
+		if k == key {
+			m.keys = append(m.keys[:i], m.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+// MarshalJSON implements json.Marshaler, encoding the entries in insertion order.
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	buf.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := encodeJSON(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
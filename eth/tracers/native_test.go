@@ -0,0 +1,154 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/tests"
+)
+
+// timeField matches the execution time reported by the call tracer, which is
+// inherently different between runs.
+var timeField = regexp.MustCompile(`"time":"[^"]*"`)
+
+// runTracerTest executes the transaction of the given call tracer test case
+// with the provided tracer and returns the trace result.
+func runTracerTest(t *testing.T, test *callTracerTest, tracer TxTracer) json.RawMessage {
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(common.FromHex(test.Input), tx); err != nil {
+		t.Fatalf("failed to parse testcase input: %v", err)
+	}
+	signer := types.MakeSigner(test.Genesis.Config, new(big.Int).SetUint64(uint64(test.Context.Number)))
+	origin, _ := signer.Sender(tx)
+
+	context := vm.Context{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		Origin:      origin,
+		Coinbase:    test.Context.Miner,
+		BlockNumber: new(big.Int).SetUint64(uint64(test.Context.Number)),
+		Time:        new(big.Int).SetUint64(uint64(test.Context.Time)),
+		Difficulty:  (*big.Int)(test.Context.Difficulty),
+		GasLimit:    uint64(test.Context.GasLimit),
+		GasPrice:    tx.GasPrice(),
+	}
+	statedb := tests.MakePreState(ethdb.NewMemDatabase(), test.Genesis.Alloc)
+	evm := vm.NewEVM(context, statedb, test.Genesis.Config, vm.Config{Debug: true, Tracer: tracer})
+
+	msg, err := tx.AsMessage(signer)
+	if err != nil {
+		t.Fatalf("failed to prepare transaction for tracing: %v", err)
+	}
+	st := core.NewStateTransition(evm, msg, new(core.GasPool).AddGas(tx.Gas()))
+	if _, _, _, err = st.TransitionDb(); err != nil {
+		t.Fatalf("failed to execute transaction: %v", err)
+	}
+	res, err := tracer.GetResult()
+	if err != nil {
+		t.Fatalf("failed to retrieve trace result: %v", err)
+	}
+	return res
+}
+
+// Iterates over all the input-output datasets in the tracer test harness and
+// checks that the native tracers produce the exact same output as their
+// JavaScript counterparts.
+func TestNativeTracers(t *testing.T) {
+	files, err := ioutil.ReadDir("testdata")
+	if err != nil {
+		t.Fatalf("failed to retrieve tracer test suite: %v", err)
+	}
+	for _, file := range files {
+		if !strings.HasPrefix(file.Name(), "call_tracer_") {
+			continue
+		}
+		file := file // capture range variable
+		t.Run(camel(strings.TrimSuffix(strings.TrimPrefix(file.Name(), "call_tracer_"), ".json")), func(t *testing.T) {
+			t.Parallel()
+
+			blob, err := ioutil.ReadFile(filepath.Join("testdata", file.Name()))
+			if err != nil {
+				t.Fatalf("failed to read testcase: %v", err)
+			}
+			test := new(callTracerTest)
+			if err := json.Unmarshal(blob, test); err != nil {
+				t.Fatalf("failed to parse testcase: %v", err)
+			}
+			for _, name := range []string{"callTracer", "prestateTracer", "4byteTracer"} {
+				jsTracer, err := New(name)
+				if err != nil {
+					t.Fatalf("failed to create JavaScript %s: %v", name, err)
+				}
+				goTracer, err := NewTracer(name)
+				if err != nil {
+					t.Fatalf("failed to create native %s: %v", name, err)
+				}
+				if _, ok := goTracer.(*Tracer); ok {
+					t.Fatalf("%s: native tracer not registered", name)
+				}
+				want := timeField.ReplaceAll(runTracerTest(t, test, jsTracer), []byte(`"time":""`))
+				have := timeField.ReplaceAll(runTracerTest(t, test, goTracer), []byte(`"time":""`))
+				if !bytes.Equal(have, want) {
+					t.Errorf("%s: trace mismatch:\nhave %s\nwant %s", name, have, want)
+				}
+				if name == "callTracer" {
+					ret := new(callTrace)
+					if err := json.Unmarshal(have, ret); err != nil {
+						t.Fatalf("failed to unmarshal trace result: %v", err)
+					}
+					if !reflect.DeepEqual(ret, test.Result) {
+						t.Errorf("trace mismatch: \nhave %+v\nwant %+v", ret, test.Result)
+					}
+				}
+			}
+		})
+	}
+}
+
+// errStopped is the interruption reason used by the tracer stop tests.
+var errStopped = errors.New("stopped")
+
+// Tests that stopping a native tracer surfaces the interruption reason.
+func TestNativeTracerStop(t *testing.T) {
+	for _, name := range []string{"callTracer", "prestateTracer", "4byteTracer"} {
+		tracer, err := NewTracer(name)
+		if err != nil {
+			t.Fatalf("failed to create native %s: %v", name, err)
+		}
+		tracer.Stop(errStopped)
+		tracer.CaptureState(&vm.EVM{}, 0, vm.STOP, 0, 0, nil, nil, nil, 1, nil)
+		if _, err := tracer.GetResult(); err != errStopped {
+			t.Errorf("%s: error mismatch: have %v, want %v", name, err, errStopped)
+		}
+	}
+}
@@ -14,18 +14,42 @@
 // You should have received a copy of the GNU Lesser General Public License
 // along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
 
-// Package tracers is a collection of JavaScript transaction tracers.
+// Package tracers is a collection of JavaScript and native Go transaction tracers.
 package tracers
 
 import (
+	"encoding/json"
 	"strings"
+	"sync"
 	"unicode"
 
+	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/eth/tracers/internal/tracers"
 )
 
-// all contains all the built in JavaScript tracers by name.
-var all = make(map[string]string)
+// TxTracer is a vm.Tracer which assembles a result that can be retrieved after
+// the traced execution finishes. Both the JavaScript and the native tracers
+// implement it.
+type TxTracer interface {
+	vm.Tracer
+
+	// GetResult returns the JSON encoded result of the trace, or any error that
+	// occurred during tracing.
+	GetResult() (json.RawMessage, error)
+
+	// Stop terminates the tracer at the first opportune moment, reporting err
+	// as the tracing result.
+	Stop(err error)
+}
+
+var (
+	// all contains all the built in JavaScript tracers by name.
+	all = make(map[string]string)
+
+	// native contains the constructors of all the registered Go tracers by name.
+	native     = make(map[string]func() TxTracer)
+	nativeLock sync.RWMutex
+)
 
 // camel converts a snake cased input string into a camel cased output.
 func camel(str string) string {
@@ -51,3 +75,28 @@ func tracer(name string) (string, bool) {
 	}
 	return "", false
 }
+
+// RegisterNative makes a Go tracer available under the given name. Native
+// tracers take precedence over the JavaScript tracers of the same name, so
+// registering one replaces the built in JavaScript implementation when the
+// tracer is requested by name.
+func RegisterNative(name string, ctor func() TxTracer) {
+	nativeLock.Lock()
+	defer nativeLock.Unlock()
+
+	native[name] = ctor
+}
+
+// NewTracer creates a tracer by name, preferring a registered native tracer.
+// If no native tracer is found, code is interpreted as either the name of a
+// built in JavaScript tracer or as a JavaScript snippet to evaluate.
+func NewTracer(code string) (TxTracer, error) {
+	nativeLock.RLock()
+	ctor, ok := native[code]
+	nativeLock.RUnlock()
+
+	if ok {
+		return ctor(), nil
+	}
+	return New(code)
+}
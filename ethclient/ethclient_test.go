@@ -17,6 +17,7 @@
 package ethclient
 
 import (
+	"context"
 	"fmt"
 	"math/big"
 	"reflect"
@@ -24,6 +25,8 @@ import (
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
 )
 
 // Verify that Client implements the ethereum interfaces.
@@ -150,3 +153,61 @@ func TestToFilterArg(t *testing.T) {
 		})
 	}
 }
+
+// blockSelectorService is a minimal eth namespace that records the block
+// selector each state query was issued against.
+type blockSelectorService struct {
+	selectors []rpc.BlockNumberOrHash
+}
+
+func (s *blockSelectorService) GetBalance(addr common.Address, blockNrOrHash rpc.BlockNumberOrHash) *hexutil.Big {
+	s.selectors = append(s.selectors, blockNrOrHash)
+	return (*hexutil.Big)(big.NewInt(1))
+}
+
+func (s *blockSelectorService) GetTransactionCount(addr common.Address, blockNrOrHash rpc.BlockNumberOrHash) hexutil.Uint64 {
+	s.selectors = append(s.selectors, blockNrOrHash)
+	return 1
+}
+
+func TestStateQueriesByHash(t *testing.T) {
+	service := new(blockSelectorService)
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", service); err != nil {
+		t.Fatalf("failed to register service: %v", err)
+	}
+	client := NewClient(rpc.DialInProc(server))
+	defer client.Close()
+
+	var (
+		ctx  = context.Background()
+		addr = common.HexToAddress("0x01")
+		hash = common.HexToHash("0x02")
+	)
+	if _, err := client.BalanceAt(ctx, addr, big.NewInt(3)); err != nil {
+		t.Fatalf("BalanceAt failed: %v", err)
+	}
+	if _, err := client.BalanceAtHash(ctx, addr, hash, false); err != nil {
+		t.Fatalf("BalanceAtHash failed: %v", err)
+	}
+	if _, err := client.NonceAtHash(ctx, addr, hash, true); err != nil {
+		t.Fatalf("NonceAtHash failed: %v", err)
+	}
+	if len(service.selectors) != 3 {
+		t.Fatalf("selector count mismatch: have %d, want 3", len(service.selectors))
+	}
+	if number, ok := service.selectors[0].Number(); !ok || number != 3 {
+		t.Errorf("number selector mismatch: have %v", service.selectors[0])
+	}
+	for i, selector := range service.selectors[1:] {
+		if have, ok := selector.Hash(); !ok || have != hash {
+			t.Errorf("query %d: hash selector mismatch: have %v, want %x", i+1, selector, hash)
+		}
+	}
+	if service.selectors[1].RequireCanonical {
+		t.Errorf("BalanceAtHash selector requires canonical block")
+	}
+	if !service.selectors[2].RequireCanonical {
+		t.Errorf("NonceAtHash selector doesn't require canonical block")
+	}
+}
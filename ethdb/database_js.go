@@ -56,6 +56,10 @@ func (db *LDBDatabase) Delete(key []byte) error {
 	return errNotSupported
 }
 
+func (db *LDBDatabase) DeleteRange(start []byte, limit []byte) error {
+	return errNotSupported
+}
+
 func (db *LDBDatabase) Close() {
 }
 
@@ -66,3 +70,38 @@ func (db *LDBDatabase) Meter(prefix string) {
 func (db *LDBDatabase) NewBatch() Batch {
 	return nil
 }
+
+// NewIterator returns an exhausted iterator, iteration is not supported.
+func (db *LDBDatabase) NewIterator() Iterator {
+	return &errIterator{err: errNotSupported}
+}
+
+// NewIteratorWithStart returns an exhausted iterator, iteration is not supported.
+func (db *LDBDatabase) NewIteratorWithStart(start []byte) Iterator {
+	return &errIterator{err: errNotSupported}
+}
+
+// NewIteratorWithPrefix returns an exhausted iterator, iteration is not supported.
+func (db *LDBDatabase) NewIteratorWithPrefix(prefix []byte) Iterator {
+	return &errIterator{err: errNotSupported}
+}
+
+func (db *LDBDatabase) Stat(property string) (string, error) {
+	return "", errNotSupported
+}
+
+func (db *LDBDatabase) Compact(start []byte, limit []byte) error {
+	return errNotSupported
+}
+
+// errIterator is an iterator that yields no data, only reporting the error
+// it was created with.
+type errIterator struct {
+	err error
+}
+
+func (it *errIterator) Next() bool    { return false }
+func (it *errIterator) Error() error  { return it.err }
+func (it *errIterator) Key() []byte   { return nil }
+func (it *errIterator) Value() []byte { return nil }
+func (it *errIterator) Release()      {}
@@ -212,3 +212,143 @@ func testParallelPutGet(db ethdb.Database, t *testing.T) {
 	}
 	pending.Wait()
 }
+
+func TestLDB_Iterator(t *testing.T) {
+	db, remove := newTestLDB()
+	defer remove()
+	testIterator(db, t)
+}
+
+func TestMemoryDB_Iterator(t *testing.T) {
+	testIterator(ethdb.NewMemDatabase(), t)
+}
+
+func TestTable_Iterator(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+
+	// Surround the table with foreign keys to ensure they are not iterated
+	db.Put([]byte("a"), []byte("foreign"))
+	db.Put([]byte("u"), []byte("foreign"))
+
+	testIterator(ethdb.NewTable(db, "t"), t)
+}
+
+func testIterator(db ethdb.Database, t *testing.T) {
+	t.Parallel()
+
+	for _, k := range []string{"1", "2", "3", "5", "10", "20", "30", "50"} {
+		if err := db.Put([]byte(k), []byte("v"+k)); err != nil {
+			t.Fatalf("put failed: %v", err)
+		}
+	}
+	tests := []struct {
+		iter ethdb.Iterator
+		want []string
+	}{
+		{db.NewIterator(), []string{"1", "10", "2", "20", "3", "30", "5", "50"}},
+		{db.NewIteratorWithPrefix([]byte("2")), []string{"2", "20"}},
+		{db.NewIteratorWithPrefix([]byte("4")), nil},
+		{db.NewIteratorWithStart([]byte("25")), []string{"3", "30", "5", "50"}},
+		{db.NewIteratorWithStart([]byte("6")), nil},
+	}
+	for i, tt := range tests {
+		var keys []string
+		for tt.iter.Next() {
+			keys = append(keys, string(tt.iter.Key()))
+			if want := "v" + string(tt.iter.Key()); string(tt.iter.Value()) != want {
+				t.Errorf("test %d: value mismatch for key %q: have %q, want %q", i, tt.iter.Key(), tt.iter.Value(), want)
+			}
+		}
+		if err := tt.iter.Error(); err != nil {
+			t.Errorf("test %d: iteration failed: %v", i, err)
+		}
+		tt.iter.Release()
+
+		if fmt.Sprint(keys) != fmt.Sprint(tt.want) {
+			t.Errorf("test %d: key mismatch: have %v, want %v", i, keys, tt.want)
+		}
+	}
+}
+
+func TestLDB_DeleteRange(t *testing.T) {
+	db, remove := newTestLDB()
+	defer remove()
+	testDeleteRange(db, t)
+}
+
+func TestMemoryDB_DeleteRange(t *testing.T) {
+	testDeleteRange(ethdb.NewMemDatabase(), t)
+}
+
+func TestTable_DeleteRange(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+
+	// Surround the table with foreign keys to ensure they are not deleted
+	db.Put([]byte("a"), []byte("foreign"))
+	db.Put([]byte("u"), []byte("foreign"))
+
+	testDeleteRange(ethdb.NewTable(db, "t"), t)
+	if n := db.Len(); n != 2 {
+		t.Fatalf("foreign keys deleted: have %d keys, want 2", n)
+	}
+}
+
+func testDeleteRange(db ethdb.Database, t *testing.T) {
+	put := func() {
+		for _, k := range []string{"1", "2", "3", "5", "10", "20", "30", "50"} {
+			if err := db.Put([]byte(k), []byte("v"+k)); err != nil {
+				t.Fatalf("put failed: %v", err)
+			}
+		}
+	}
+	tests := []struct {
+		start, limit []byte
+		want         []string
+	}{
+		{[]byte("2"), []byte("3"), []string{"1", "10", "3", "30", "5", "50"}},
+		{[]byte("25"), nil, []string{"1", "10", "2", "20"}},
+		{nil, []byte("20"), []string{"20", "3", "30", "5", "50"}},
+		{[]byte("6"), nil, []string{"1", "10", "2", "20", "3", "30", "5", "50"}},
+		{nil, nil, nil},
+	}
+	for i, tt := range tests {
+		put()
+		if err := db.DeleteRange(tt.start, tt.limit); err != nil {
+			t.Fatalf("test %d: range deletion failed: %v", i, err)
+		}
+		var keys []string
+		iter := db.NewIterator()
+		for iter.Next() {
+			keys = append(keys, string(iter.Key()))
+		}
+		iter.Release()
+
+		if fmt.Sprint(keys) != fmt.Sprint(tt.want) {
+			t.Errorf("test %d: key mismatch: have %v, want %v", i, keys, tt.want)
+		}
+		db.DeleteRange(nil, nil)
+	}
+}
+
+func TestLDB_StatCompact(t *testing.T) {
+	db, remove := newTestLDB()
+	defer remove()
+
+	for i := 0; i < 100; i++ {
+		db.Put([]byte(strconv.Itoa(i)), []byte("v"))
+	}
+	if err := db.Compact(nil, nil); err != nil {
+		t.Fatalf("compaction failed: %v", err)
+	}
+	if err := ethdb.NewTable(db, "1").Compact(nil, nil); err != nil {
+		t.Fatalf("table compaction failed: %v", err)
+	}
+	for _, prop := range []string{"leveldb.stats", "stats"} {
+		if stats, err := db.Stat(prop); err != nil || stats == "" {
+			t.Errorf("stat %q failed: %q, %v", prop, stats, err)
+		}
+	}
+	if _, err := db.Stat("leveldb.nonexistent"); err == nil {
+		t.Errorf("unknown property succeeded")
+	}
+}
@@ -16,6 +16,8 @@
 
 package ethdb
 
+import "strings"
+
 type table struct {
 	db     Database
 	prefix string
@@ -49,3 +51,125 @@ func (dt *table) Delete(key []byte) error {
 func (dt *table) Close() {
 	// Do nothing; don't close the underlying DB.
 }
+
+// NewIterator creates a binary-alphabetical iterator over the entire keyspace
+// contained within the table.
+func (dt *table) NewIterator() Iterator {
+	return dt.NewIteratorWithPrefix(nil)
+}
+
+// NewIteratorWithStart creates a binary-alphabetical iterator over a subset of
+// table content starting at a particular initial key (or after, if it does not
+// exist).
+func (dt *table) NewIteratorWithStart(start []byte) Iterator {
+	iter := dt.db.NewIteratorWithStart(append([]byte(dt.prefix), start...))
+	return &tableIterator{iter: iter, prefix: dt.prefix}
+}
+
+// NewIteratorWithPrefix creates a binary-alphabetical iterator over a subset
+// of table content with a particular key prefix.
+func (dt *table) NewIteratorWithPrefix(prefix []byte) Iterator {
+	iter := dt.db.NewIteratorWithPrefix(append([]byte(dt.prefix), prefix...))
+	return &tableIterator{iter: iter, prefix: dt.prefix}
+}
+
+// Stat returns a particular internal stat of the underlying database.
+func (dt *table) Stat(property string) (string, error) {
+	return dt.db.Stat(property)
+}
+
+// DeleteRange removes all keys in the range [start, limit) from the table. A
+// nil start or limit is treated as the respective boundary of the table, not
+// of the entire database.
+func (dt *table) DeleteRange(start []byte, limit []byte) error {
+	start, limit = dt.keyRange(start, limit)
+	return dt.db.DeleteRange(start, limit)
+}
+
+// Compact flattens the underlying data store for the given key range. A nil
+// start or limit is treated as the respective boundary of the table, not of
+// the entire database.
+func (dt *table) Compact(start []byte, limit []byte) error {
+	start, limit = dt.keyRange(start, limit)
+	return dt.db.Compact(start, limit)
+}
+
+// keyRange converts a key range within the table into the corresponding range
+// of the underlying database.
+func (dt *table) keyRange(start []byte, limit []byte) ([]byte, []byte) {
+	// If no start was specified, use the table prefix as the first value
+	if start == nil {
+		start = []byte(dt.prefix)
+	} else {
+		start = append([]byte(dt.prefix), start...)
+	}
+	// If no limit was specified, use the first element not matching the prefix
+	// as the limit
+	if limit == nil {
+		limit = []byte(dt.prefix)
+		for i := len(limit) - 1; i >= 0; i-- {
+			// Bump the current character, stopping if it doesn't overflow
+			limit[i]++
+			if limit[i] > 0 {
+				break
+			}
+			// Character overflown, proceed to the next or nil if the last
+			if i == 0 {
+				limit = nil
+			}
+		}
+	} else {
+		limit = append([]byte(dt.prefix), limit...)
+	}
+	return start, limit
+}
+
+// tableIterator is a wrapper around a database iterator that strips the table
+// prefix from the iterated keys and stops at the end of the table's keyspace.
+type tableIterator struct {
+	iter   Iterator
+	prefix string
+	done   bool
+}
+
+// Next moves the iterator to the next key/value pair. It returns whether the
+// iterator is exhausted.
+func (it *tableIterator) Next() bool {
+	if it.done {
+		return false
+	}
+	if !it.iter.Next() || !strings.HasPrefix(string(it.iter.Key()), it.prefix) {
+		it.done = true
+		return false
+	}
+	return true
+}
+
+// Error returns any accumulated error. Exhausting all the key/value pairs
+// is not considered to be an error.
+func (it *tableIterator) Error() error {
+	return it.iter.Error()
+}
+
+// Key returns the key of the current key/value pair, or nil if done.
+func (it *tableIterator) Key() []byte {
+	key := it.iter.Key()
+	if it.done || key == nil {
+		return nil
+	}
+	return key[len(it.prefix):]
+}
+
+// Value returns the value of the current key/value pair, or nil if done.
+func (it *tableIterator) Value() []byte {
+	if it.done {
+		return nil
+	}
+	return it.iter.Value()
+}
+
+// Release releases associated resources. Release should always succeed and can
+// be called multiple times without causing error.
+func (it *tableIterator) Release() {
+	it.iter.Release()
+}
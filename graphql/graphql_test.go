@@ -0,0 +1,351 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	testKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddress = crypto.PubkeyToAddress(testKey.PublicKey)
+
+	testLogger    = common.Address{0xc0} // Contract emitting a log with testTopic
+	testTopic     = common.Hash{0x01}
+	testReturner  = common.Address{0xc1} // Contract returning the word 42
+	testTransfer  = big.NewInt(1000)
+	testRecipient = common.Address{0xd0}
+)
+
+func TestBuildSchema(t *testing.T) {
+	// Make sure the schema can be parsed and matched up to the object model.
+	if _, err := NewHandler(nil); err != nil {
+		t.Errorf("Could not construct GraphQL handler: %v", err)
+	}
+}
+
+func TestGraphiQLMethods(t *testing.T) {
+	handler, err := NewHandler(nil)
+	if err != nil {
+		t.Fatalf("Could not construct GraphQL handler: %v", err)
+	}
+	// The interactive browser is only served over GET
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("GET /: status mismatch: have %d, want %d", rec.Code, http.StatusOK)
+	}
+	if !strings.Contains(rec.Body.String(), "graphiql") {
+		t.Errorf("GET /: response does not contain the query browser")
+	}
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("POST", "/", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST /: status mismatch: have %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+// newTestChain starts an Ethereum service with a chain of the given length, in
+// which each block contains a value transfer and a call to the log emitting
+// contract, returning a GraphQL handler on top of it.
+func newTestChain(t *testing.T, n int) (*node.Node, []*types.Block, http.Handler) {
+	workspace, err := ioutil.TempDir("", "graphql-test-")
+	if err != nil {
+		t.Fatalf("failed to create temporary datadir: %v", err)
+	}
+	stack, err := node.New(&node.Config{DataDir: workspace, UseLightweightKDF: true, Name: "graphql-test"})
+	if err != nil {
+		os.RemoveAll(workspace)
+		t.Fatalf("failed to create node: %v", err)
+	}
+	genesis := &core.Genesis{
+		Config: params.AllEthashProtocolChanges,
+		Alloc: core.GenesisAlloc{
+			testAddress: {Balance: big.NewInt(params.Ether)},
+			testLogger: {
+				// LOG1(0, 0, testTopic)
+				Code:    append(append([]byte{0x7f}, testTopic[:]...), 0x60, 0x00, 0x80, 0xa1, 0x00),
+				Balance: common.Big0,
+			},
+			testReturner: {
+				// MSTORE(0, 42), RETURN(0, 32)
+				Code:    []byte{0x60, 0x2a, 0x60, 0x00, 0x52, 0x60, 0x20, 0x60, 0x00, 0xf3},
+				Balance: common.Big0,
+			},
+		},
+	}
+	conf := &eth.Config{Genesis: genesis, Ethash: ethash.Config{PowMode: ethash.ModeFake}}
+	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) { return eth.New(ctx, conf) }); err != nil {
+		t.Fatalf("failed to register Ethereum protocol: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("failed to start test stack: %v", err)
+	}
+	var ethereum *eth.Ethereum
+	if err := stack.Service(&ethereum); err != nil {
+		t.Fatalf("failed to retrieve Ethereum service: %v", err)
+	}
+	// Generate and import the test chain
+	db := ethdb.NewMemDatabase()
+	blocks, _ := core.GenerateChain(genesis.Config, genesis.MustCommit(db), ethash.NewFaker(), db, n, func(i int, b *core.BlockGen) {
+		signer := types.HomesteadSigner{}
+		transfer, _ := types.SignTx(types.NewTransaction(b.TxNonce(testAddress), testRecipient, testTransfer, params.TxGas, big.NewInt(1), nil), signer, testKey)
+		b.AddTx(transfer)
+		call, _ := types.SignTx(types.NewTransaction(b.TxNonce(testAddress), testLogger, common.Big0, 100000, big.NewInt(1), nil), signer, testKey)
+		b.AddTx(call)
+	})
+	if _, err := ethereum.BlockChain().InsertChain(blocks); err != nil {
+		t.Fatalf("failed to import test chain: %v", err)
+	}
+	handler, err := NewHandler(ethereum.APIBackend)
+	if err != nil {
+		t.Fatalf("could not construct GraphQL handler: %v", err)
+	}
+	return stack, blocks, handler
+}
+
+// query runs a GraphQL query against the handler, decoding the response data
+// into result and returning any reported errors.
+func query(t *testing.T, handler http.Handler, q string, result interface{}) []string {
+	body, _ := json.Marshal(map[string]string{"query": q})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("POST", "/graphql", bytes.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("query %q: status mismatch: have %d, want %d", q, rec.Code, http.StatusOK)
+	}
+	var res struct {
+		Data   json.RawMessage
+		Errors []struct{ Message string }
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatalf("query %q: failed to decode response %s: %v", q, rec.Body, err)
+	}
+	var errs []string
+	for _, err := range res.Errors {
+		errs = append(errs, err.Message)
+	}
+	if len(errs) == 0 && result != nil {
+		if err := json.Unmarshal(res.Data, result); err != nil {
+			t.Fatalf("query %q: failed to decode data %s: %v", q, res.Data, err)
+		}
+	}
+	return errs
+}
+
+func stopTestChain(stack *node.Node) {
+	stack.Stop()
+	os.RemoveAll(stack.DataDir())
+}
+
+func TestBlockQuery(t *testing.T) {
+	stack, blocks, handler := newTestChain(t, 3)
+	defer stopTestChain(stack)
+
+	var res struct {
+		Block struct {
+			Number           hexutil.Uint64
+			Hash             common.Hash
+			Parent           struct{ Hash common.Hash }
+			TransactionCount int
+		}
+	}
+	if errs := query(t, handler, `{ block(number: 2) { number hash parent { hash } transactionCount } }`, &res); errs != nil {
+		t.Fatalf("block query failed: %v", errs)
+	}
+	if res.Block.Number != 2 || res.Block.Hash != blocks[1].Hash() || res.Block.Parent.Hash != blocks[0].Hash() || res.Block.TransactionCount != 2 {
+		t.Errorf("block mismatch: have %+v, want number 2, hash %x, parent %x, 2 transactions", res.Block, blocks[1].Hash(), blocks[0].Hash())
+	}
+	// Blocks are also retrievable by hash, and missing ones are reported as null
+	res.Block.Number = 0
+	if errs := query(t, handler, fmt.Sprintf(`{ block(hash: "%s") { number } }`, blocks[2].Hash().Hex()), &res); errs != nil || res.Block.Number != 3 {
+		t.Errorf("block by hash mismatch: have %d (errors %v), want 3", res.Block.Number, errs)
+	}
+	var missing struct {
+		Block *struct{ Number hexutil.Uint64 }
+	}
+	if errs := query(t, handler, `{ block(number: 10) { number } }`, &missing); errs != nil || missing.Block != nil {
+		t.Errorf("missing block mismatch: have %+v (errors %v), want null", missing.Block, errs)
+	}
+}
+
+func TestBlocksQuery(t *testing.T) {
+	stack, blocks, handler := newTestChain(t, 3)
+	defer stopTestChain(stack)
+
+	var res struct {
+		Blocks []struct{ Hash common.Hash }
+	}
+	if errs := query(t, handler, `{ blocks(from: 1) { hash } }`, &res); errs != nil {
+		t.Fatalf("blocks query failed: %v", errs)
+	}
+	if len(res.Blocks) != len(blocks) {
+		t.Fatalf("block count mismatch: have %d, want %d", len(res.Blocks), len(blocks))
+	}
+	for i, block := range res.Blocks {
+		if block.Hash != blocks[i].Hash() {
+			t.Errorf("block %d: hash mismatch: have %x, want %x", i+1, block.Hash, blocks[i].Hash())
+		}
+	}
+	// Ranges above the limit are rejected
+	if errs := query(t, handler, fmt.Sprintf(`{ blocks(from: 0, to: %d) { number } }`, maxBlocksRange-1), nil); errs != nil {
+		t.Errorf("maximum block range rejected: %v", errs)
+	}
+	errs := query(t, handler, fmt.Sprintf(`{ blocks(from: 0, to: %d) { number } }`, maxBlocksRange), nil)
+	if len(errs) != 1 || errs[0] != errBlocksRange.Error() {
+		t.Errorf("oversized block range error mismatch: have %v, want %v", errs, errBlocksRange)
+	}
+}
+
+func TestAccountQuery(t *testing.T) {
+	stack, _, handler := newTestChain(t, 3)
+	defer stopTestChain(stack)
+
+	var res struct {
+		Block struct {
+			Sender struct {
+				Balance          hexutil.Big
+				TransactionCount hexutil.Uint64
+			}
+			Recipient struct{ Balance hexutil.Big }
+			Logger    struct{ Code hexutil.Bytes }
+		}
+	}
+	q := fmt.Sprintf(`{ block(number: 2) {
+		sender: account(address: "%s") { balance transactionCount }
+		recipient: account(address: "%s") { balance }
+		logger: account(address: "%s") { code }
+	} }`, testAddress.Hex(), testRecipient.Hex(), testLogger.Hex())
+
+	if errs := query(t, handler, q, &res); errs != nil {
+		t.Fatalf("account query failed: %v", errs)
+	}
+	if res.Block.Sender.TransactionCount != 4 {
+		t.Errorf("sender nonce mismatch: have %d, want 4", res.Block.Sender.TransactionCount)
+	}
+	if balance := (*big.Int)(&res.Block.Sender.Balance); balance.Cmp(big.NewInt(params.Ether)) >= 0 {
+		t.Errorf("sender balance not charged: have %v", balance)
+	}
+	if balance, want := (*big.Int)(&res.Block.Recipient.Balance), new(big.Int).Mul(testTransfer, big.NewInt(2)); balance.Cmp(want) != 0 {
+		t.Errorf("recipient balance mismatch: have %v, want %v", balance, want)
+	}
+	if len(res.Block.Logger.Code) == 0 {
+		t.Errorf("contract code missing")
+	}
+}
+
+func TestTransactionQuery(t *testing.T) {
+	stack, blocks, handler := newTestChain(t, 3)
+	defer stopTestChain(stack)
+
+	tx := blocks[1].Transactions()[1]
+
+	var res struct {
+		Transaction struct {
+			Hash   common.Hash
+			Nonce  hexutil.Uint64
+			Index  int
+			From   struct{ Address common.Address }
+			To     struct{ Address common.Address }
+			Block  struct{ Hash common.Hash }
+			Status hexutil.Uint64
+			Logs   []struct{ Topics []common.Hash }
+		}
+	}
+	q := fmt.Sprintf(`{ transaction(hash: "%s") { hash nonce index from { address } to { address } block { hash } status logs { topics } } }`, tx.Hash().Hex())
+	if errs := query(t, handler, q, &res); errs != nil {
+		t.Fatalf("transaction query failed: %v", errs)
+	}
+	have := res.Transaction
+	if have.Hash != tx.Hash() || have.Nonce != 3 || have.Index != 1 || have.Block.Hash != blocks[1].Hash() || have.Status != 1 {
+		t.Errorf("transaction mismatch: have %+v", have)
+	}
+	if have.From.Address != testAddress || have.To.Address != testLogger {
+		t.Errorf("transaction endpoints mismatch: have %x -> %x, want %x -> %x", have.From.Address, have.To.Address, testAddress, testLogger)
+	}
+	if len(have.Logs) != 1 || len(have.Logs[0].Topics) != 1 || have.Logs[0].Topics[0] != testTopic {
+		t.Errorf("transaction logs mismatch: have %+v", have.Logs)
+	}
+}
+
+func TestLogsQuery(t *testing.T) {
+	stack, blocks, handler := newTestChain(t, 3)
+	defer stopTestChain(stack)
+
+	var res struct {
+		Logs []struct {
+			Topics      []common.Hash
+			Transaction struct{ Hash common.Hash }
+		}
+	}
+	q := fmt.Sprintf(`{ logs(filter: { fromBlock: 2, toBlock: 3, addresses: ["%s"] }) { topics transaction { hash } } }`, testLogger.Hex())
+	if errs := query(t, handler, q, &res); errs != nil {
+		t.Fatalf("logs query failed: %v", errs)
+	}
+	if len(res.Logs) != 2 {
+		t.Fatalf("log count mismatch: have %d, want 2", len(res.Logs))
+	}
+	for i, log := range res.Logs {
+		if want := blocks[i+1].Transactions()[1].Hash(); log.Transaction.Hash != want {
+			t.Errorf("log %d: transaction mismatch: have %x, want %x", i, log.Transaction.Hash, want)
+		}
+		if len(log.Topics) != 1 || log.Topics[0] != testTopic {
+			t.Errorf("log %d: topics mismatch: have %x, want [%x]", i, log.Topics, testTopic)
+		}
+	}
+}
+
+func TestCallQuery(t *testing.T) {
+	stack, _, handler := newTestChain(t, 3)
+	defer stopTestChain(stack)
+
+	var res struct {
+		Block struct {
+			Call struct {
+				Data   hexutil.Bytes
+				Status hexutil.Uint64
+			}
+		}
+	}
+	q := fmt.Sprintf(`{ block { call(data: { from: "%s", to: "%s" }) { data status } } }`, testAddress.Hex(), testReturner.Hex())
+	if errs := query(t, handler, q, &res); errs != nil {
+		t.Fatalf("call query failed: %v", errs)
+	}
+	if want := common.BigToHash(big.NewInt(42)).Bytes(); !bytes.Equal(res.Block.Call.Data, want) || res.Block.Call.Status != 1 {
+		t.Errorf("call result mismatch: have %x (status %d), want %x (status 1)", res.Block.Call.Data, res.Block.Call.Status, want)
+	}
+}
@@ -135,6 +135,18 @@ web3._extend({
 			call: 'ethash_submitHashRate',
 			params: 2,
 		}),
+		new web3._extend.Method({
+			name: 'blockReward',
+			call: 'ethash_blockReward',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'issuance',
+			call: 'ethash_issuance',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
 	]
 });
 `
@@ -213,6 +225,10 @@ web3._extend({
 			name: 'datadir',
 			getter: 'admin_datadir'
 		}),
+		new web3._extend.Property({
+			name: 'forkSchedule',
+			getter: 'admin_forkSchedule'
+		}),
 	]
 });
 `
@@ -415,6 +431,12 @@ web3._extend({
 			params: 2,
 			inputFormatter: [null, null]
 		}),
+		new web3._extend.Method({
+			name: 'traceCall',
+			call: 'debug_traceCall',
+			params: 3,
+			inputFormatter: [null, null, null]
+		}),
 		new web3._extend.Method({
 			name: 'preimage',
 			call: 'debug_preimage',
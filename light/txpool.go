@@ -19,6 +19,7 @@ package light
 import (
 	"context"
 	"fmt"
+	"math/big"
 	"sync"
 	"time"
 
@@ -67,7 +68,8 @@ type TxPool struct {
 	mined        map[common.Hash][]*types.Transaction // mined transactions by block hash
 	clearIdx     uint64                               // earliest block nr that can contain mined tx info
 
-	eip2f bool
+	eip2f    bool
+	eip2028f bool
 }
 
 // TxRelayBackend provides an interface to the mechanism that forwards transacions
@@ -309,7 +311,10 @@ func (pool *TxPool) setNewHead(head *types.Header) {
 	txc, _ := pool.reorgOnNewHead(ctx, head)
 	m, r := txc.getLists()
 	pool.relay.NewHead(pool.head, m, r)
-	pool.eip2f = pool.config.IsEIP2F(head.Number)
+	// Update the fork flags to the rules of the pending block on top of the new head
+	next := new(big.Int).Add(head.Number, big.NewInt(1))
+	pool.eip2f = pool.config.IsEIP2F(next)
+	pool.eip2028f = pool.config.IsEIP2028F(next)
 	pool.signer = types.MakeSigner(pool.config, head.Number)
 }
 
@@ -378,7 +383,7 @@ func (pool *TxPool) validateTx(ctx context.Context, tx *types.Transaction) error
 	}
 
 	// Should supply enough intrinsic gas
-	gas, err := core.IntrinsicGas(tx.Data(), tx.To() == nil, pool.eip2f)
+	gas, err := core.IntrinsicGas(tx.Data(), tx.To() == nil, pool.eip2f, pool.eip2028f)
 	if err != nil {
 		return err
 	}
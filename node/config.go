@@ -152,6 +152,15 @@ type Config struct {
 	// interface.
 	HTTPTimeouts rpc.HTTPTimeouts
 
+	// HTTPAuth configures bearer token authentication of the HTTP RPC interface.
+	// If any tokens are configured, requests without a valid token are rejected
+	// and each token may only call the modules it is granted.
+	HTTPAuth rpc.AuthConfig
+
+	// HTTPLimits configures the request rate, concurrency, batch and response size
+	// limits each client of the HTTP RPC interface is subject to.
+	HTTPLimits rpc.Limits
+
 	// WSHost is the host interface on which to start the websocket RPC server. If
 	// this field is empty, no websocket API endpoint will be started.
 	WSHost string `toml:",omitempty"`
@@ -178,6 +187,15 @@ type Config struct {
 	// private APIs to untrusted users is a major security risk.
 	WSExposeAll bool `toml:",omitempty"`
 
+	// WSAuth configures bearer token authentication of the websocket handshake.
+	// If any tokens are configured, connections without a valid token are rejected
+	// and each token may only call the modules it is granted.
+	WSAuth rpc.AuthConfig
+
+	// WSLimits configures the request rate, concurrency, batch and response size
+	// limits each client of the websocket RPC interface is subject to.
+	WSLimits rpc.Limits
+
 	// Logger is a custom logger to use with the p2p.Server.
 	Logger log.Logger `toml:",omitempty"`
 
@@ -333,6 +351,24 @@ func (c *Config) ResolvePath(path string) string {
 	return filepath.Join(c.instanceDir(), path)
 }
 
+// ResolveAncient returns the absolute path of the ancient chain store attached
+// to the named database. Relative ancient directories live inside the database
+// directory. An empty string is returned if no ancient directory is configured
+// or the node uses ephemeral storage.
+func (c *Config) ResolveAncient(name, ancient string) string {
+	if ancient == "" {
+		return ""
+	}
+	if filepath.IsAbs(ancient) {
+		return ancient
+	}
+	root := c.ResolvePath(name)
+	if root == "" {
+		return ""
+	}
+	return filepath.Join(root, ancient)
+}
+
 func (c *Config) instanceDir() string {
 	if c.DataDir == "" {
 		return ""
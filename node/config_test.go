@@ -105,6 +105,29 @@ func TestIPCPathResolution(t *testing.T) {
 	}
 }
 
+// Tests that ancient chain store directories are resolved relative to their
+// database, and disabled for ephemeral nodes.
+func TestAncientPathResolution(t *testing.T) {
+	var tests = []struct {
+		DataDir string
+		Ancient string
+		Path    string
+	}{
+		{"", "", ""},
+		{"", "ancient", ""},
+		{"", "/ancient", "/ancient"},
+		{"/data", "", ""},
+		{"/data", "ancient", "/data/test/chaindata/ancient"},
+		{"/data", "/ancient", "/ancient"},
+	}
+	for i, test := range tests {
+		config := &Config{Name: "test", DataDir: test.DataDir}
+		if path := config.ResolveAncient("chaindata", test.Ancient); path != filepath.FromSlash(test.Path) {
+			t.Errorf("test %d: ancient path mismatch: have %s, want %s", i, path, test.Path)
+		}
+	}
+}
+
 // Tests that node keys can be correctly created, persisted, loaded and/or made
 // ephemeral.
 func TestNodeKeyPersistency(t *testing.T) {
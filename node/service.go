@@ -50,6 +50,13 @@ func (ctx *ServiceContext) OpenDatabase(name string, cache int, handles int) (et
 	return db, nil
 }
 
+// OpenDatabaseWithFreezer opens an existing database with the given name (or
+// creates one if no previous can be found) from within the node's data directory,
+// attaching a chain freezer to it as Node.OpenDatabaseWithFreezer does.
+func (ctx *ServiceContext) OpenDatabaseWithFreezer(name string, cache int, handles int, freezer, namespace string, threshold uint64, readonly bool) (ethdb.Database, error) {
+	return openDatabaseWithFreezer(ctx.config, name, cache, handles, freezer, namespace, threshold, readonly)
+}
+
 // ResolvePath resolves a user path into the data directory if that was relative
 // and if the user actually uses persistent storage. It will return an empty string
 // for emphemeral storage and the user's own input for absolute paths.
@@ -0,0 +1,114 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+package discover
+
+import (
+	crand "crypto/rand"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+// lookupRetryDelay is the time to wait before the next lookup when a lookup
+// didn't find any nodes.
+const lookupRetryDelay = time.Second
+
+// lookupIterator performs random lookups and returns the nodes found. If resolve
+// is set, the records of nodes learned from neighbors packets are requested
+// before they are returned.
+type lookupIterator struct {
+	tab     *Table
+	resolve bool
+	buffer  []*node
+	cur     *enode.Node
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+func newLookupIterator(tab *Table, resolve bool) *lookupIterator {
+	return &lookupIterator{tab: tab, resolve: resolve, closed: make(chan struct{})}
+}
+
+// Node returns the current node.
+func (it *lookupIterator) Node() *enode.Node {
+	return it.cur
+}
+
+// Next moves to the next node.
+func (it *lookupIterator) Next() bool {
+	for {
+		for len(it.buffer) > 0 {
+			n := it.buffer[0]
+			it.buffer = it.buffer[1:]
+			if it.isClosed() {
+				break
+			}
+			if it.resolve {
+				it.cur = it.resolveRecord(n)
+			} else {
+				it.cur = unwrapNode(n)
+			}
+			return true
+		}
+		if it.isClosed() {
+			it.cur = nil
+			return false
+		}
+		var target encPubkey
+		crand.Read(target[:])
+		if it.buffer = it.tab.lookup(target, true); len(it.buffer) > 0 {
+			continue
+		}
+		// Nothing was found, wait a bit before trying again.
+		select {
+		case <-time.After(lookupRetryDelay):
+		case <-it.closed:
+		case <-it.tab.closeReq:
+			it.Close()
+		}
+	}
+}
+
+// resolveRecord returns the node with its current record. Nodes without a known
+// record are asked for it. If the request fails, the node is returned as is.
+func (it *lookupIterator) resolveRecord(n *node) *enode.Node {
+	if n.Seq() > 0 {
+		return unwrapNode(n)
+	}
+	rn, err := it.tab.net.requestENR(unwrapNode(n))
+	if err != nil {
+		return unwrapNode(n)
+	}
+	return rn
+}
+
+// Close ends the iterator.
+func (it *lookupIterator) Close() {
+	it.closeOnce.Do(func() { close(it.closed) })
+}
+
+func (it *lookupIterator) isClosed() bool {
+	select {
+	case <-it.closed:
+		return true
+	case <-it.tab.closeReq:
+		return true
+	default:
+		return false
+	}
+}
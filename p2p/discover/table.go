@@ -88,7 +88,8 @@ type Table struct {
 // sockets and without generating a private key.
 type transport interface {
 	self() *enode.Node
-	ping(enode.ID, *net.UDPAddr) error
+	ping(enode.ID, *net.UDPAddr) (seq uint64, err error)
+	requestENR(*enode.Node) (*enode.Node, error)
 	findnode(toid enode.ID, addr *net.UDPAddr, target encPubkey) ([]*node, error)
 	close()
 }
@@ -237,6 +238,25 @@ func (tab *Table) Resolve(n *enode.Node) *enode.Node {
 	return nil
 }
 
+// RequestENR requests the current record of the given node (EIP-868).
+func (tab *Table) RequestENR(n *enode.Node) (*enode.Node, error) {
+	return tab.net.requestENR(n)
+}
+
+// RandomNodes returns an iterator over nodes found by random lookups in the
+// network. Nodes learned from neighbors packets are returned without requesting
+// their records, which may thus be incomplete.
+func (tab *Table) RandomNodes() enode.Iterator {
+	return newLookupIterator(tab, false)
+}
+
+// RandomNodesWithRecords is like RandomNodes, but requests the record of every
+// node without a known one before returning it, costing a round trip per node.
+// Combine it with enode.Filter to select nodes by their ENR entries.
+func (tab *Table) RandomNodesWithRecords() enode.Iterator {
+	return newLookupIterator(tab, true)
+}
+
 // LookupRandom finds random nodes in the network.
 func (tab *Table) LookupRandom() []*enode.Node {
 	var target encPubkey
@@ -459,7 +479,17 @@ func (tab *Table) doRevalidate(done chan<- struct{}) {
 	}
 
 	// Ping the selected node and wait for a pong.
-	err := tab.net.ping(last.ID(), last.addr())
+	remoteSeq, err := tab.net.ping(last.ID(), last.addr())
+
+	// Also fetch the record if the node replied with a higher sequence number.
+	if err == nil && last.Seq() < remoteSeq {
+		n, err := tab.net.requestENR(unwrapNode(last))
+		if err != nil {
+			log.Debug("ENR request failed", "id", last.ID(), "addr", last.addr(), "err", err)
+		} else {
+			last = &node{Node: *n, addedAt: last.addedAt, livenessChecks: last.livenessChecks}
+		}
+	}
 
 	tab.mutex.Lock()
 	defer tab.mutex.Unlock()
@@ -368,6 +368,107 @@ func TestTable_addSeenNode(t *testing.T) {
 	checkIPLimitInvariant(t, tab)
 }
 
+// This test checks that ENR updates happen during revalidation. If a node in the table
+// announces a new sequence number, the new record should be pulled.
+func TestTable_revalidateSyncRecord(t *testing.T) {
+	transport := newPingRecorder()
+	tab, db := newTestTable(transport)
+	<-tab.initDone
+	defer db.Close()
+	defer tab.Close()
+
+	// Insert a node.
+	var r enr.Record
+	r.Set(enr.IP(net.IP{127, 0, 0, 1}))
+	id := enode.ID{1}
+	n1 := wrapNode(enode.SignNull(&r, id))
+	tab.addSeenNode(n1)
+
+	// Update the node record.
+	r.Set(enr.WithEntry("foo", "bar"))
+	n2 := enode.SignNull(&r, id)
+	transport.updateRecord(n2)
+
+	tab.doRevalidate(make(chan struct{}, 1))
+	intable := tab.bucket(id).entries[0]
+	if !reflect.DeepEqual(unwrapNode(intable), n2) {
+		t.Fatalf("table contains old record with seq %d, want seq %d", intable.Seq(), n2.Seq())
+	}
+}
+
+// This test checks that the random node iterator with records fetches the
+// records of nodes and can be filtered by their ENR entries.
+func TestTable_RandomNodesFilter(t *testing.T) {
+	transport := newPingRecorder()
+	tab, db := newTestTable(transport)
+	<-tab.initDone
+	defer db.Close()
+	defer tab.Close()
+
+	// Add live nodes without known records to the table. The records of half
+	// of them contain the entry we're looking for.
+	want := make(map[enode.ID]bool)
+	for i := 0; i < 10; i++ {
+		var r enr.Record
+		r.Set(enr.IP(intIP(i + 1)))
+		id := enode.ID{byte(i + 1)}
+		n := wrapNode(enode.SignNull(&r, id))
+		n.livenessChecks = 1
+		tab.addSeenNode(n)
+		if i%2 == 0 {
+			r.Set(enr.WithEntry("foo", uint(i)))
+			transport.updateRecord(enode.SignNull(&r, id))
+			want[id] = true
+		}
+	}
+	it := enode.Filter(tab.RandomNodesWithRecords(), func(n *enode.Node) bool {
+		var foo uint
+		return n.Load(enr.WithEntry("foo", &foo)) == nil
+	})
+	defer it.Close()
+
+	seen := make(map[enode.ID]bool)
+	for len(seen) < len(want) && it.Next() {
+		if id := it.Node().ID(); !want[id] {
+			t.Fatalf("iterator returned node %v without entry", id)
+		}
+		seen[it.Node().ID()] = true
+	}
+	if len(seen) != len(want) {
+		t.Fatalf("iterator returned %d nodes, want %d", len(seen), len(want))
+	}
+}
+
+// This test checks that the plain random node iterator returns nodes without
+// requesting their records.
+func TestTable_RandomNodesNoResolve(t *testing.T) {
+	transport := newPingRecorder()
+	tab, db := newTestTable(transport)
+	<-tab.initDone
+	defer db.Close()
+	defer tab.Close()
+
+	for i := 0; i < 10; i++ {
+		var r enr.Record
+		r.Set(enr.IP(intIP(i + 1)))
+		id := enode.ID{byte(i + 1)}
+		n := wrapNode(enode.SignNull(&r, id))
+		n.livenessChecks = 1
+		tab.addSeenNode(n)
+		r.Set(enr.WithEntry("foo", uint(i)))
+		transport.updateRecord(enode.SignNull(&r, id))
+	}
+	it := tab.RandomNodes()
+	defer it.Close()
+
+	for i := 0; i < 10 && it.Next(); i++ {
+		var foo uint
+		if it.Node().Load(enr.WithEntry("foo", &foo)) == nil {
+			t.Fatalf("iterator returned resolved record of node %v", it.Node().ID())
+		}
+	}
+}
+
 func TestTable_Lookup(t *testing.T) {
 	tab, db := newTestTable(lookupTestnet)
 	defer db.Close()
@@ -621,8 +722,13 @@ func (tn *preminedTestnet) findnode(toid enode.ID, toaddr *net.UDPAddr, target e
 	return result, nil
 }
 
-func (*preminedTestnet) close()                                        {}
-func (*preminedTestnet) ping(toid enode.ID, toaddr *net.UDPAddr) error { return nil }
+func (*preminedTestnet) close() {}
+func (*preminedTestnet) ping(toid enode.ID, toaddr *net.UDPAddr) (uint64, error) {
+	return 0, nil
+}
+func (*preminedTestnet) requestENR(n *enode.Node) (*enode.Node, error) {
+	return nil, errTimeout
+}
 
 // mine generates a testnet struct literal with nodes at
 // various distances to the given target.
@@ -29,6 +29,7 @@ import (
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
 	"github.com/ethereum/go-ethereum/p2p/netutil"
 	"github.com/ethereum/go-ethereum/rlp"
 )
@@ -62,6 +63,8 @@ const (
 	pongPacket
 	findnodePacket
 	neighborsPacket
+	enrRequestPacket
+	enrResponsePacket
 )
 
 // RPC request structures
@@ -72,7 +75,8 @@ type (
 		Version    uint
 		From, To   rpcEndpoint
 		Expiration uint64
-		// Ignore additional fields (for forward compatibility).
+		// The first additional field is the sender's ENR sequence number (EIP-868).
+		// Ignore further fields (for forward compatibility).
 		Rest []rlp.RawValue `rlp:"tail"`
 	}
 
@@ -85,7 +89,8 @@ type (
 
 		ReplyTok   []byte // This contains the hash of the ping packet.
 		Expiration uint64 // Absolute timestamp at which the packet becomes invalid.
-		// Ignore additional fields (for forward compatibility).
+		// The first additional field is the sender's ENR sequence number (EIP-868).
+		// Ignore further fields (for forward compatibility).
 		Rest []rlp.RawValue `rlp:"tail"`
 	}
 
@@ -105,6 +110,21 @@ type (
 		Rest []rlp.RawValue `rlp:"tail"`
 	}
 
+	// enrRequest queries for the remote node's record.
+	enrRequest struct {
+		Expiration uint64
+		// Ignore additional fields (for forward compatibility).
+		Rest []rlp.RawValue `rlp:"tail"`
+	}
+
+	// enrResponse is the reply to enrRequest.
+	enrResponse struct {
+		ReplyTok []byte // Hash of the enrRequest packet.
+		Record   enr.Record
+		// Ignore additional fields (for forward compatibility).
+		Rest []rlp.RawValue `rlp:"tail"`
+	}
+
 	rpcNode struct {
 		IP  net.IP // len 4 for IPv4 or 16 for IPv6
 		UDP uint16 // for discovery protocol
@@ -129,6 +149,23 @@ func makeEndpoint(addr *net.UDPAddr, tcpPort uint16) rpcEndpoint {
 	return rpcEndpoint{IP: ip, UDP: uint16(addr.Port), TCP: tcpPort}
 }
 
+// seqTail encodes an ENR sequence number as the tail of a ping or pong packet.
+func seqTail(seq uint64) []rlp.RawValue {
+	enc, _ := rlp.EncodeToBytes(seq)
+	return []rlp.RawValue{enc}
+}
+
+// seqFromTail decodes the ENR sequence number from the tail of a ping or pong
+// packet. It returns zero if the sender didn't include one.
+func seqFromTail(tail []rlp.RawValue) uint64 {
+	if len(tail) == 0 {
+		return 0
+	}
+	var seq uint64
+	rlp.DecodeBytes(tail[0], &seq)
+	return seq
+}
+
 func (t *udp) nodeFromRPC(sender *net.UDPAddr, rn rpcNode) (*node, error) {
 	if rn.UDP <= 1024 {
 		return nil, errors.New("low port")
@@ -296,19 +333,24 @@ func (t *udp) ourEndpoint() rpcEndpoint {
 	return makeEndpoint(a, uint16(n.TCP()))
 }
 
-// ping sends a ping message to the given node and waits for a reply.
-func (t *udp) ping(toid enode.ID, toaddr *net.UDPAddr) error {
-	return <-t.sendPing(toid, toaddr, nil)
+// ping sends a ping message to the given node and waits for a reply. It returns
+// the ENR sequence number announced by the remote node.
+func (t *udp) ping(toid enode.ID, toaddr *net.UDPAddr) (seq uint64, err error) {
+	err = <-t.sendPing(toid, toaddr, func(p *pong) {
+		seq = seqFromTail(p.Rest)
+	})
+	return seq, err
 }
 
 // sendPing sends a ping message to the given node and invokes the callback
 // when the reply arrives.
-func (t *udp) sendPing(toid enode.ID, toaddr *net.UDPAddr, callback func()) <-chan error {
+func (t *udp) sendPing(toid enode.ID, toaddr *net.UDPAddr, callback func(*pong)) <-chan error {
 	req := &ping{
 		Version:    4,
 		From:       t.ourEndpoint(),
 		To:         makeEndpoint(toaddr, 0), // TODO: maybe use known TCP port from DB
 		Expiration: uint64(time.Now().Add(expiration).Unix()),
+		Rest:       seqTail(t.localNode.Node().Seq()),
 	}
 	packet, hash, err := encodePacket(t.priv, pingPacket, req)
 	if err != nil {
@@ -321,7 +363,7 @@ func (t *udp) sendPing(toid enode.ID, toaddr *net.UDPAddr, callback func()) <-ch
 	errc := t.pending(toid, toaddr.IP, pongPacket, func(p interface{}) (matched bool, requestDone bool) {
 		matched = bytes.Equal(p.(*pong).ReplyTok, hash)
 		if matched && callback != nil {
-			callback()
+			callback(p.(*pong))
 		}
 		return matched, matched
 	})
@@ -334,13 +376,7 @@ func (t *udp) sendPing(toid enode.ID, toaddr *net.UDPAddr, callback func()) <-ch
 // findnode sends a findnode request to the given node and waits until
 // the node has sent up to k neighbors.
 func (t *udp) findnode(toid enode.ID, toaddr *net.UDPAddr, target encPubkey) ([]*node, error) {
-	// If we haven't seen a ping from the destination node for a while, it won't remember
-	// our endpoint proof and reject findnode. Solicit a ping first.
-	if time.Since(t.db.LastPingReceived(toid, toaddr.IP)) > bondExpiration {
-		t.ping(toid, toaddr)
-		// Wait for them to ping back and process our pong.
-		time.Sleep(respTimeout)
-	}
+	t.ensureBond(toid, toaddr)
 
 	// Add a matcher for 'neighbours' replies to the pending reply queue. The matcher is
 	// active until enough nodes have been received.
@@ -366,6 +402,61 @@ func (t *udp) findnode(toid enode.ID, toaddr *net.UDPAddr, target encPubkey) ([]
 	return nodes, <-errc
 }
 
+// ensureBond solicits a ping from a node if we haven't seen a ping from it for a while.
+// Nodes which don't remember our endpoint proof reject findnode and ENR requests.
+func (t *udp) ensureBond(toid enode.ID, toaddr *net.UDPAddr) {
+	if time.Since(t.db.LastPingReceived(toid, toaddr.IP)) > bondExpiration {
+		t.ping(toid, toaddr)
+		// Wait for them to ping back and process our pong.
+		time.Sleep(respTimeout)
+	}
+}
+
+// requestENR sends an ENR request to the given node and waits for a response.
+// If the node responds with an older record than n, n is returned.
+func (t *udp) requestENR(n *enode.Node) (*enode.Node, error) {
+	addr := &net.UDPAddr{IP: n.IP(), Port: n.UDP()}
+	t.ensureBond(n.ID(), addr)
+
+	req := &enrRequest{
+		Expiration: uint64(time.Now().Add(expiration).Unix()),
+	}
+	packet, hash, err := encodePacket(t.priv, enrRequestPacket, req)
+	if err != nil {
+		return nil, err
+	}
+	// Add a matcher for the reply to the pending reply queue. Responses are matched if
+	// they reference the request we're about to send.
+	var resp *enrResponse
+	errc := t.pending(n.ID(), addr.IP, enrResponsePacket, func(r interface{}) (matched bool, requestDone bool) {
+		matched = bytes.Equal(r.(*enrResponse).ReplyTok, hash)
+		if matched {
+			resp = r.(*enrResponse)
+		}
+		return matched, matched
+	})
+	// Send the packet and wait for the reply.
+	t.write(addr, n.ID(), req.name(), packet)
+	if err := <-errc; err != nil {
+		return nil, err
+	}
+	// Verify the response record.
+	respN, err := enode.New(enode.ValidSchemes, &resp.Record)
+	if err != nil {
+		return nil, err
+	}
+	if respN.ID() != n.ID() {
+		return nil, errors.New("invalid ID in response record")
+	}
+	if respN.Seq() < n.Seq() {
+		return n, nil // response record is older
+	}
+	if err := netutil.CheckRelayIP(addr.IP, respN.IP()); err != nil {
+		return nil, fmt.Errorf("invalid IP in response record: %v", err)
+	}
+	return respN, nil
+}
+
 // pending adds a reply matcher to the pending reply queue.
 // see the documentation of type replyMatcher for a detailed explanation.
 func (t *udp) pending(id enode.ID, ip net.IP, ptype byte, callback replyMatchFunc) <-chan error {
@@ -627,6 +718,10 @@ func decodePacket(buf []byte) (packet, encPubkey, []byte, error) {
 		req = new(findnode)
 	case neighborsPacket:
 		req = new(neighbors)
+	case enrRequestPacket:
+		req = new(enrRequest)
+	case enrResponsePacket:
+		req = new(enrResponse)
 	default:
 		return nil, fromKey, hash, fmt.Errorf("unknown type: %d", ptype)
 	}
@@ -655,12 +750,13 @@ func (req *ping) handle(t *udp, from *net.UDPAddr, fromID enode.ID, mac []byte)
 		To:         makeEndpoint(from, req.From.TCP),
 		ReplyTok:   mac,
 		Expiration: uint64(time.Now().Add(expiration).Unix()),
+		Rest:       seqTail(t.localNode.Node().Seq()),
 	})
 
 	// Ping back if our last pong on file is too far in the past.
 	n := wrapNode(enode.NewV4(req.senderKey, from.IP, int(req.From.TCP), from.Port))
 	if time.Since(t.db.LastPongReceived(n.ID(), from.IP)) > bondExpiration {
-		t.sendPing(fromID, from, func() {
+		t.sendPing(fromID, from, func(*pong) {
 			t.tab.addVerifiedNode(n)
 		})
 	} else {
@@ -750,6 +846,39 @@ func (req *neighbors) handle(t *udp, from *net.UDPAddr, fromID enode.ID, mac []b
 
 func (req *neighbors) name() string { return "NEIGHBORS/v4" }
 
+func (req *enrRequest) preverify(t *udp, from *net.UDPAddr, fromID enode.ID, fromKey encPubkey) error {
+	if expired(req.Expiration) {
+		return errExpired
+	}
+	if time.Since(t.db.LastPongReceived(fromID, from.IP)) > bondExpiration {
+		// Like findnode, ENR requests are only answered for bonded nodes because
+		// the response is larger than the request.
+		return errUnknownNode
+	}
+	return nil
+}
+
+func (req *enrRequest) handle(t *udp, from *net.UDPAddr, fromID enode.ID, mac []byte) {
+	t.send(from, fromID, enrResponsePacket, &enrResponse{
+		ReplyTok: mac,
+		Record:   *t.localNode.Node().Record(),
+	})
+}
+
+func (req *enrRequest) name() string { return "ENRREQUEST/v4" }
+
+func (req *enrResponse) preverify(t *udp, from *net.UDPAddr, fromID enode.ID, fromKey encPubkey) error {
+	if !t.handleReply(fromID, from.IP, enrResponsePacket, req) {
+		return errUnsolicitedReply
+	}
+	return nil
+}
+
+func (req *enrResponse) handle(t *udp, from *net.UDPAddr, fromID enode.ID, mac []byte) {
+}
+
+func (req *enrResponse) name() string { return "ENRRESPONSE/v4" }
+
 func expired(ts uint64) bool {
 	return time.Unix(int64(ts), 0).Before(time.Now())
 }
@@ -38,6 +38,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
 	"github.com/ethereum/go-ethereum/rlp"
 )
 
@@ -152,7 +153,7 @@ func TestUDP_pingTimeout(t *testing.T) {
 
 	toaddr := &net.UDPAddr{IP: net.ParseIP("1.2.3.4"), Port: 2222}
 	toid := enode.ID{1, 2, 3, 4}
-	if err := test.udp.ping(toid, toaddr); err != errTimeout {
+	if _, err := test.udp.ping(toid, toaddr); err != errTimeout {
 		t.Error("expected timeout error, got", err)
 	}
 }
@@ -356,6 +357,96 @@ func TestUDP_findnodeMultiReply(t *testing.T) {
 	}
 }
 
+func TestUDP_pingSeq(t *testing.T) {
+	test := newUDPTest(t)
+	defer test.close()
+
+	// The ping carries the local record sequence number.
+	resultc := make(chan uint64, 1)
+	go func() {
+		rid := encodePubkey(&test.remotekey.PublicKey).id()
+		seq, err := test.udp.ping(rid, test.remoteaddr)
+		if err != nil {
+			t.Error("ping error:", err)
+		}
+		resultc <- seq
+	}()
+	_, hash, _ := test.waitPacketOut(func(p *ping) {
+		if seq := seqFromTail(p.Rest); seq != test.udp.self().Seq() {
+			t.Errorf("wrong sequence number in ping: got %d, want %d", seq, test.udp.self().Seq())
+		}
+	})
+	// The sequence number of the pong is returned.
+	test.packetIn(nil, pongPacket, &pong{ReplyTok: hash, Expiration: futureExp, Rest: seqTail(7)})
+	if seq := <-resultc; seq != 7 {
+		t.Errorf("wrong sequence number returned: got %d, want 7", seq)
+	}
+}
+
+func TestUDP_ENRRequest(t *testing.T) {
+	test := newUDPTest(t)
+	defer test.close()
+
+	// Requests from unbonded nodes are rejected.
+	test.packetIn(errUnknownNode, enrRequestPacket, &enrRequest{Expiration: futureExp})
+
+	// Once bonded, the request is answered with the local record.
+	remoteID := encodePubkey(&test.remotekey.PublicKey).id()
+	test.table.db.UpdateLastPongReceived(remoteID, test.remoteaddr.IP, time.Now())
+	test.packetIn(nil, enrRequestPacket, &enrRequest{Expiration: futureExp})
+
+	test.waitPacketOut(func(p *enrResponse) {
+		reqhash := test.sent[len(test.sent)-1][:macSize]
+		if !bytes.Equal(p.ReplyTok, reqhash) {
+			t.Errorf("wrong reply token: got %x, want %x", p.ReplyTok, reqhash)
+		}
+		n, err := enode.New(enode.ValidSchemes, &p.Record)
+		if err != nil {
+			t.Fatal("invalid record in response:", err)
+		}
+		if !reflect.DeepEqual(n, test.udp.self()) {
+			t.Errorf("wrong record in response: got %v, want %v", n, test.udp.self())
+		}
+	})
+}
+
+func TestUDP_requestENR(t *testing.T) {
+	test := newUDPTest(t)
+	defer test.close()
+
+	// Ensure there's a bond so the request is sent right away.
+	remoteID := encodePubkey(&test.remotekey.PublicKey).id()
+	test.table.db.UpdateLastPingReceived(remoteID, test.remoteaddr.IP, time.Now())
+
+	remote := enode.NewV4(&test.remotekey.PublicKey, test.remoteaddr.IP, 0, test.remoteaddr.Port)
+	resultc := make(chan *enode.Node, 1)
+	go func() {
+		n, err := test.udp.requestENR(remote)
+		if err != nil {
+			t.Error("request error:", err)
+		}
+		resultc <- n
+	}()
+	_, hash, _ := test.waitPacketOut(func(*enrRequest) {})
+
+	// Reply with a newer record.
+	var r enr.Record
+	r.Set(enr.IP(test.remoteaddr.IP))
+	r.Set(enr.UDP(test.remoteaddr.Port))
+	r.SetSeq(3)
+	if err := enode.SignV4(&r, test.remotekey); err != nil {
+		t.Fatal(err)
+	}
+	test.packetIn(nil, enrResponsePacket, &enrResponse{ReplyTok: hash, Record: r})
+
+	n := <-resultc
+	if n == nil || n.ID() != remote.ID() || n.Seq() != 3 {
+		t.Fatalf("wrong node returned: %v", n)
+	}
+	// Unsolicited responses are rejected.
+	test.packetIn(errUnsolicitedReply, enrResponsePacket, &enrResponse{ReplyTok: hash, Record: r})
+}
+
 func TestUDP_pingMatch(t *testing.T) {
 	test := newUDPTest(t)
 	defer test.close()
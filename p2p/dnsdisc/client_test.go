@@ -0,0 +1,219 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+package dnsdisc
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+)
+
+func TestClientSyncTree(t *testing.T) {
+	keys := testKeys(2)
+	linked, _ := MakeTree(1, nil, nil)
+	linkURL, _ := linked.Sign(keys[1], "linked")
+
+	nodes := testNodes(20)
+	tree, url := makeTestTree(t, keys[0], "n", 3, nodes, []string{linkURL})
+	r := mapResolver(tree.ToTXT("n"))
+
+	c := NewClient(Config{Resolver: r})
+	synced, err := c.SyncTree(url)
+	if err != nil {
+		t.Fatal("sync error:", err)
+	}
+	if !reflect.DeepEqual(synced.Nodes(), sortByID(nodes)) {
+		t.Errorf("wrong nodes in synced tree:\nhave %v\nwant %v", synced.Nodes(), sortByID(nodes))
+	}
+	if !reflect.DeepEqual(synced.Links(), []string{linkURL}) {
+		t.Errorf("wrong links in synced tree: %v", synced.Links())
+	}
+	if synced.Seq() != 3 {
+		t.Errorf("synced tree has wrong seq: %d", synced.Seq())
+	}
+	if synced.Signature() != tree.Signature() {
+		t.Errorf("synced tree has wrong signature")
+	}
+}
+
+// In this test, syncing the tree fails because it contains an invalid ENR entry.
+func TestClientSyncTreeBadNode(t *testing.T) {
+	key := testKeys(1)[0]
+	tree, url := makeTestTree(t, key, "n", 1, testNodes(1), nil)
+	r := mapResolver(tree.ToTXT("n"))
+
+	// Replace the node record with a different one.
+	other := (&enrEntry{testNodes(1)[0]}).String()
+	for name, txt := range r {
+		if strings.HasPrefix(txt, enrPrefix) {
+			r[name] = other
+		}
+	}
+	c := NewClient(Config{Resolver: r})
+	_, err := c.SyncTree(url)
+	if ne, ok := err.(nameError); !ok || ne.err != errHashMismatch {
+		t.Fatalf("expected hash mismatch error, got %v", err)
+	}
+}
+
+// This test checks that a root signed by a different key is rejected.
+func TestClientSyncTreeBadRoot(t *testing.T) {
+	keys := testKeys(2)
+	tree, _ := makeTestTree(t, keys[0], "n", 1, testNodes(1), nil)
+	r := mapResolver(tree.ToTXT("n"))
+
+	url := newLinkEntry("n", &keys[1].PublicKey).String()
+	c := NewClient(Config{Resolver: r})
+	_, err := c.SyncTree(url)
+	want := nameError{"n", entryError{typ: "root", err: errInvalidSig}}
+	if err != want {
+		t.Fatalf("wrong error: %v, want %v", err, want)
+	}
+}
+
+// This test checks that the iterator visits all nodes of the tree and the trees
+// linked from it.
+func TestIterator(t *testing.T) {
+	keys := testKeys(2)
+	nodes := testNodes(40)
+	linked, linkURL := makeTestTree(t, keys[1], "linked", 1, nodes[20:], nil)
+	tree, url := makeTestTree(t, keys[0], "n", 1, nodes[:20], []string{linkURL})
+
+	r := mapResolver(tree.ToTXT("n"))
+	r.add(linked.ToTXT("linked"))
+	c := NewClient(Config{Resolver: r})
+
+	it, err := c.NewIterator(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer it.Close()
+
+	seen := make(map[enode.ID]bool)
+	for i := 0; i < 10000 && len(seen) < len(nodes); i++ {
+		if !it.Next() {
+			t.Fatal("iterator ended early")
+		}
+		seen[it.Node().ID()] = true
+	}
+	for _, n := range nodes {
+		if !seen[n.ID()] {
+			t.Errorf("node %v not returned by iterator", n.ID())
+		}
+	}
+}
+
+// This test checks that Close interrupts Next when no nodes are available.
+func TestIteratorClose(t *testing.T) {
+	key := testKeys(1)[0]
+	tree, url := makeTestTree(t, key, "n", 1, nil, nil)
+	c := NewClient(Config{Resolver: mapResolver(tree.ToTXT("n"))})
+
+	it, err := c.NewIterator(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan bool)
+	go func() { done <- it.Next() }()
+
+	time.Sleep(50 * time.Millisecond)
+	it.Close()
+	select {
+	case ok := <-done:
+		if ok {
+			t.Fatal("Next returned true for empty tree")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Next not interrupted by Close")
+	}
+}
+
+func makeTestTree(t *testing.T, key *ecdsa.PrivateKey, domain string, seq uint, nodes []*enode.Node, links []string) (*Tree, string) {
+	tree, err := MakeTree(seq, nodes, links)
+	if err != nil {
+		t.Fatal(err)
+	}
+	url, err := tree.Sign(key, domain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tree, url
+}
+
+func testKeys(n int) []*ecdsa.PrivateKey {
+	keys := make([]*ecdsa.PrivateKey, n)
+	for i := range keys {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			panic(err)
+		}
+		keys[i] = key
+	}
+	return keys
+}
+
+func testNodes(n int) []*enode.Node {
+	nodes := make([]*enode.Node, n)
+	for i, key := range testKeys(n) {
+		var r enr.Record
+		r.Set(enr.IP{127, 0, 0, byte(i)})
+		r.Set(enr.TCP(30303))
+		r.SetSeq(uint64(i))
+		if err := enode.SignV4(&r, key); err != nil {
+			panic(err)
+		}
+		node, err := enode.New(enode.ValidSchemes, &r)
+		if err != nil {
+			panic(err)
+		}
+		nodes[i] = node
+	}
+	return nodes
+}
+
+func sortByID(nodes []*enode.Node) []*enode.Node {
+	sorted := make([]*enode.Node, len(nodes))
+	copy(sorted, nodes)
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i].ID().Bytes(), sorted[j].ID().Bytes()) < 0
+	})
+	return sorted
+}
+
+// mapResolver is an in-memory resolver serving TXT records from a map.
+type mapResolver map[string]string
+
+func (mr mapResolver) add(m map[string]string) {
+	for k, v := range m {
+		mr[k] = v
+	}
+}
+
+func (mr mapResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	if record, ok := mr[name]; ok {
+		return []string{record}, nil
+	}
+	return nil, nil
+}
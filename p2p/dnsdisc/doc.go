@@ -0,0 +1,25 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+// Package dnsdisc implements node discovery via DNS (EIP-1459).
+//
+// Node lists are published as merkle trees of node records in DNS TXT records. The root
+// of a tree is signed by the tree owner, whose public key is part of the tree location:
+//
+//	enrtree://<base32 compressed public key>@<domain>
+//
+// The client resolves and verifies entries on demand and can be used as a source of dial
+// candidates by the p2p server. Trees are created and signed with the Tree type.
+package dnsdisc
@@ -18,6 +18,7 @@ package enode
 
 import (
 	"crypto/ecdsa"
+	"encoding/base64"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -27,6 +28,7 @@ import (
 	"strings"
 
 	"github.com/ethereum/go-ethereum/p2p/enr"
+	"github.com/ethereum/go-ethereum/rlp"
 )
 
 // Node represents a host on the network.
@@ -48,6 +50,23 @@ func New(validSchemes enr.IdentityScheme, r *enr.Record) (*Node, error) {
 	return node, nil
 }
 
+// Parse decodes and verifies a textual node representation. The input may either
+// be a node record in text form, prefixed with "enr:", or an enode:// URL.
+func Parse(validSchemes enr.IdentityScheme, input string) (*Node, error) {
+	if !strings.HasPrefix(input, "enr:") {
+		return ParseV4(input)
+	}
+	bin, err := base64.RawURLEncoding.DecodeString(input[4:])
+	if err != nil {
+		return nil, err
+	}
+	var r enr.Record
+	if err := rlp.DecodeBytes(bin, &r); err != nil {
+		return nil, err
+	}
+	return New(validSchemes, &r)
+}
+
 // ID returns the node identifier.
 func (n *Node) ID() ID {
 	return n.id
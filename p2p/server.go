@@ -45,6 +45,11 @@ import (
 const (
 	defaultDialTimeout = 15 * time.Second
 
+	// This is the fairness knob for the discovery mixer. When looking for peers, we'll
+	// wait this long for a single source of candidates before moving on and trying other
+	// sources.
+	discmixTimeout = 5 * time.Second
+
 	// Connectivity defaults.
 	maxActiveDialTasks     = 16
 	defaultMaxPendingPeers = 50
@@ -170,6 +175,7 @@ type Server struct {
 	ourHandshake *protoHandshake
 	lastLookup   time.Time
 	DiscV5       *discv5.Network
+	discmix      *enode.FairMix
 
 	// These are for Peers, PeerCount (and nothing else).
 	peerOp     chan peerOpFunc
@@ -361,6 +367,11 @@ func (srv *Server) Self() *enode.Node {
 	return ln.Node()
 }
 
+// LocalNode returns the local node record.
+func (srv *Server) LocalNode() *enode.LocalNode {
+	return srv.localnode
+}
+
 // Stop terminates the server and all active peer connections.
 // It blocks until all active connections have been closed.
 func (srv *Server) Stop() {
@@ -375,6 +386,9 @@ func (srv *Server) Stop() {
 		srv.listener.Close()
 	}
 	close(srv.quit)
+	if srv.discmix != nil {
+		srv.discmix.Close()
+	}
 	srv.lock.Unlock()
 	srv.loopWG.Wait()
 }
@@ -456,7 +470,7 @@ func (srv *Server) Start() (err error) {
 	}
 
 	dynPeers := srv.maxDialedConns()
-	dialer := newDialState(srv.localnode.ID(), srv.StaticNodes, srv.BootstrapNodes, srv.ntab, dynPeers, srv.NetRestrict)
+	dialer := newDialState(srv.localnode.ID(), srv.StaticNodes, srv.BootstrapNodes, dynPeers, srv.NetRestrict)
 	srv.loopWG.Add(1)
 	go srv.run(dialer)
 	return nil
@@ -507,7 +521,31 @@ func (srv *Server) setupLocalNode() error {
 	return nil
 }
 
+// dialCandidateSources returns the distinct dial candidate iterators of all
+// protocols. Multiple versions of a protocol usually share the same iterator.
+func (srv *Server) dialCandidateSources() []enode.Iterator {
+	var (
+		sources []enode.Iterator
+		seen    = make(map[enode.Iterator]bool)
+	)
+	for _, p := range srv.Protocols {
+		if p.DialCandidates != nil && !seen[p.DialCandidates] {
+			seen[p.DialCandidates] = true
+			sources = append(sources, p.DialCandidates)
+		}
+	}
+	return sources
+}
+
+// setupDiscovery creates the discovery mixer, adds the protocols' dial candidate
+// sources to it and starts the UDP discovery listener.
 func (srv *Server) setupDiscovery() error {
+	srv.discmix = enode.NewFairMix(discmixTimeout)
+	for _, it := range srv.dialCandidateSources() {
+		srv.discmix.AddSource(it)
+	}
+
+	// Don't listen on UDP endpoint if DHT is disabled.
 	if srv.NoDiscovery && !srv.DiscoveryV5 {
 		return nil
 	}
@@ -548,6 +586,7 @@ func (srv *Server) setupDiscovery() error {
 			return err
 		}
 		srv.ntab = ntab
+		srv.discmix.AddSource(ntab.RandomNodes())
 	}
 	// Discovery V5
 	if srv.DiscoveryV5 {
@@ -17,11 +17,14 @@
 package p2p
 
 import (
+	"bytes"
 	"crypto/ecdsa"
 	"errors"
 	"math/rand"
 	"net"
 	"reflect"
+	"sort"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -265,6 +268,93 @@ func TestServerTaskScheduling(t *testing.T) {
 	}
 }
 
+// This test checks that nodes from protocol dial candidate iterators
+// are returned by discovery tasks.
+func TestServerDialCandidates(t *testing.T) {
+	nodes := []*enode.Node{
+		newNode(uintID(1), nil),
+		newNode(uintID(2), nil),
+		newNode(uintID(3), nil),
+	}
+	srv := &Server{
+		Config: Config{
+			NoDiscovery: true,
+			Protocols:   []Protocol{{Name: "test", DialCandidates: enode.IterNodes(nodes)}},
+		},
+		quit:    make(chan struct{}),
+		running: true,
+		log:     log.New(),
+	}
+	if err := srv.setupDiscovery(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+
+	task := &discoverTask{want: len(nodes)}
+	task.Do(srv)
+	results := task.results
+	sort.Slice(results, func(i, j int) bool {
+		return bytes.Compare(results[i].ID().Bytes(), results[j].ID().Bytes()) < 0
+	})
+	if !reflect.DeepEqual(results, nodes) {
+		t.Fatalf("wrong dial candidates: got %v, want %v", results, nodes)
+	}
+}
+
+// This test checks that discovery tasks return the nodes found by the discovery
+// table along with the other dial candidates, without resolving them one by one.
+func TestServerDiscoveryCandidates(t *testing.T) {
+	tab := &resolveCountTable{}
+	for i := 0; i < 8; i++ {
+		tab.nodes = append(tab.nodes, newNode(uintID(uint32(i+10)), net.IP{127, 0, 0, byte(i + 1)}))
+	}
+	candidates := []*enode.Node{newNode(uintID(1), nil), newNode(uintID(2), nil)}
+	srv := &Server{
+		Config: Config{
+			NoDiscovery: true,
+			Protocols:   []Protocol{{Name: "test", DialCandidates: enode.IterNodes(candidates)}},
+		},
+		quit:    make(chan struct{}),
+		running: true,
+		log:     log.New(),
+	}
+	if err := srv.setupDiscovery(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+	srv.ntab = tab
+	srv.discmix.AddSource(tab.RandomNodes())
+
+	want := 6
+	task := &discoverTask{want: want}
+	task.Do(srv)
+	if len(task.results) != want {
+		t.Fatalf("wrong number of dial candidates: got %d, want %d", len(task.results), want)
+	}
+	if n := atomic.LoadInt32(&tab.resolved); n != 0 {
+		t.Fatalf("discovery task resolved %d nodes, want none", n)
+	}
+}
+
+// resolveCountTable is a discoverTable serving a fixed set of nodes through its
+// random node iterator. It counts the nodes resolved through it, each of which
+// would cost a round trip in the network.
+type resolveCountTable struct {
+	nodes    []*enode.Node
+	resolved int32
+}
+
+func (t *resolveCountTable) Close() {}
+
+func (t *resolveCountTable) Resolve(n *enode.Node) *enode.Node {
+	atomic.AddInt32(&t.resolved, 1)
+	return n
+}
+
+func (t *resolveCountTable) RandomNodes() enode.Iterator {
+	return enode.CycleNodes(t.nodes)
+}
+
 // This test checks that Server doesn't drop tasks,
 // even if newTasks returns more than the maximum number of tasks.
 func TestServerManyTasks(t *testing.T) {
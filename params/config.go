@@ -19,6 +19,7 @@ package params
 import (
 	"fmt"
 	"math/big"
+	"strings"
 
 	"github.com/ethereum/go-ethereum/common"
 )
@@ -295,6 +296,15 @@ var (
 		nil,           // EIP1283FBlock
 
 		nil, // PetersburgBlock
+
+		nil, // IstanbulBlock
+		nil, // EIP152FBlock
+		nil, // EIP1108FBlock
+		nil, // EIP1344FBlock
+		nil, // EIP1884FBlock
+		nil, // EIP2028FBlock
+		nil, // EIP2200FBlock
+
 		nil, // EWASMBlock
 
 		nil, // ECIP1010PauseBlock
@@ -303,6 +313,8 @@ var (
 		nil, // DisposalBlock
 		nil, // SocialBlock
 		nil, // EthersocialBlock
+		nil, // ECBP1100FBlock
+		nil, // ECIP1099FBlock
 
 		new(EthashConfig), // Ethash
 		nil,               // Clique
@@ -350,6 +362,15 @@ var (
 		nil,           // EIP1283FBlock
 
 		nil, // PetersburgBlock
+
+		nil, // IstanbulBlock
+		nil, // EIP152FBlock
+		nil, // EIP1108FBlock
+		nil, // EIP1344FBlock
+		nil, // EIP1884FBlock
+		nil, // EIP2028FBlock
+		nil, // EIP2200FBlock
+
 		nil, // EWASMBlock
 
 		nil, // ECIP1010PauseBlock
@@ -358,6 +379,8 @@ var (
 		nil, // DisposalBlock
 		nil, // SocialBlock
 		nil, // EthersocialBlock
+		nil, // ECBP1100FBlock
+		nil, // ECIP1099FBlock
 
 		nil, // Ethash
 		&CliqueConfig{
@@ -404,6 +427,15 @@ var (
 		nil,           // EIP1283FBlock
 
 		nil, // PetersburgBlock
+
+		nil, // IstanbulBlock
+		nil, // EIP152FBlock
+		nil, // EIP1108FBlock
+		nil, // EIP1344FBlock
+		nil, // EIP1884FBlock
+		nil, // EIP2028FBlock
+		nil, // EIP2200FBlock
+
 		nil, // EWASMBlock
 
 		nil, // ECIP1010PauseBlock
@@ -412,6 +444,8 @@ var (
 		nil, // DisposalBlock
 		nil, // SocialBlock
 		nil, // EthersocialBlock
+		nil, // ECBP1100FBlock
+		nil, // ECIP1099FBlock
 
 		new(EthashConfig), // Ethash
 		nil,               // Clique
@@ -535,6 +569,28 @@ type ChainConfig struct {
 
 	PetersburgBlock *big.Int `json:"petersburgBlock,omitempty"` // Petersburg switch block (nil = same as Constantinople)
 
+	// HF: Istanbul
+	IstanbulBlock *big.Int `json:"istanbulBlock,omitempty"` // Istanbul switch block (nil = no fork, 0 = already on istanbul)
+	//
+	// Precompiled contract for the BLAKE2b F compression function
+	// https://eips.ethereum.org/EIPS/eip-152
+	EIP152FBlock *big.Int `json:"eip152FBlock,omitempty"`
+	// Reduced gas costs of the alt_bn128 precompiled contracts
+	// https://eips.ethereum.org/EIPS/eip-1108
+	EIP1108FBlock *big.Int `json:"eip1108FBlock,omitempty"`
+	// Opcode CHAINID
+	// https://eips.ethereum.org/EIPS/eip-1344
+	EIP1344FBlock *big.Int `json:"eip1344FBlock,omitempty"`
+	// Repricing of trie-size-dependent opcodes, opcode SELFBALANCE
+	// https://eips.ethereum.org/EIPS/eip-1884
+	EIP1884FBlock *big.Int `json:"eip1884FBlock,omitempty"`
+	// Reduced gas cost of transaction calldata
+	// https://eips.ethereum.org/EIPS/eip-2028
+	EIP2028FBlock *big.Int `json:"eip2028FBlock,omitempty"`
+	// Net gas metering with a reentrancy sentry, superseding EIP1283
+	// https://eips.ethereum.org/EIPS/eip-2200
+	EIP2200FBlock *big.Int `json:"eip2200FBlock,omitempty"`
+
 	EWASMBlock *big.Int `json:"ewasmBlock,omitempty"` // EWASM switch block (nil = no fork, 0 = already activated)
 
 	ECIP1010PauseBlock *big.Int `json:"ecip1010PauseBlock,omitempty"` // ECIP1010 pause HF block
@@ -544,6 +600,14 @@ type ChainConfig struct {
 	SocialBlock        *big.Int `json:"socialBlock,omitempty"`        // Ethereum Social Reward block
 	EthersocialBlock   *big.Int `json:"ethersocialBlock,omitempty"`   // Ethersocial Reward block
 
+	// Modified exponential subjective scoring (MESS), rejecting deep reorgs unless
+	// the competing chain is overwhelmingly heavier. Not a consensus rule.
+	// https://ecips.ethereumclassic.org/ECIPs/ecip-1100
+	ECBP1100FBlock *big.Int `json:"ecbp1100FBlock,omitempty"`
+	// Doubled ethash epoch length, slowing down the growth of the mining DAG
+	// https://ecips.ethereumclassic.org/ECIPs/ecip-1099
+	ECIP1099FBlock *big.Int `json:"ecip1099FBlock,omitempty"`
+
 	// Various consensus engines
 	Ethash *EthashConfig `json:"ethash,omitempty"`
 	Clique *CliqueConfig `json:"clique,omitempty"`
@@ -579,26 +643,16 @@ func (c *ChainConfig) String() string {
 	default:
 		engine = "unknown"
 	}
-	return fmt.Sprintf("{ChainID: %v Homestead: %v DAO: %v DAOSupport: %v EIP150: %v EIP155: %v EIP158: %v Byzantium: %v Disposal: %v Social: %v Ethersocial: %v ECIP1017: %v EIP160: %v ECIP1010PauseBlock: %v ECIP1010Length: %v Constantinople: %v ConstantinopleFix: %v Engine: %v}",
-		c.ChainID,
-		c.HomesteadBlock,
-		c.DAOForkBlock,
-		c.DAOForkSupport,
-		c.EIP150Block,
-		c.EIP155Block,
-		c.EIP158Block,
-		c.ByzantiumBlock,
-		c.DisposalBlock,
-		c.SocialBlock,
-		c.EthersocialBlock,
-		c.ECIP1017EraRounds,
-		c.EIP160FBlock,
-		c.ECIP1010PauseBlock,
-		c.ECIP1010Length,
-		c.ConstantinopleBlock,
-		c.PetersburgBlock,
-		engine,
-	)
+	var b strings.Builder
+	fmt.Fprintf(&b, "{ChainID: %v", c.ChainID)
+	for _, field := range c.ForkSchedule() {
+		fmt.Fprintf(&b, " %s: %v", field.Name, c.Value(field))
+		if field.Name == "DAOFork" {
+			fmt.Fprintf(&b, " DAOSupport: %v", c.DAOForkSupport)
+		}
+	}
+	fmt.Fprintf(&b, " Engine: %v}", engine)
+	return b.String()
 }
 
 // HasECIP1017 returns whether the chain is configured with ECIP1017.
@@ -720,6 +774,41 @@ func (c *ChainConfig) IsEIP1283F(num *big.Int) bool {
 	return !c.IsPetersburg(num) && (isForked(c.ConstantinopleBlock, num) || isForked(c.EIP1283FBlock, num))
 }
 
+// IsEIP152F returns whether num is equal to or greater than the Istanbul or EIP152 block.
+func (c *ChainConfig) IsEIP152F(num *big.Int) bool {
+	return isForked(c.IstanbulBlock, num) || isForked(c.EIP152FBlock, num)
+}
+
+// IsEIP1108F returns whether num is equal to or greater than the Istanbul or EIP1108 block.
+func (c *ChainConfig) IsEIP1108F(num *big.Int) bool {
+	return isForked(c.IstanbulBlock, num) || isForked(c.EIP1108FBlock, num)
+}
+
+// IsEIP1344F returns whether num is equal to or greater than the Istanbul or EIP1344 block.
+func (c *ChainConfig) IsEIP1344F(num *big.Int) bool {
+	return isForked(c.IstanbulBlock, num) || isForked(c.EIP1344FBlock, num)
+}
+
+// IsEIP1884F returns whether num is equal to or greater than the Istanbul or EIP1884 block.
+func (c *ChainConfig) IsEIP1884F(num *big.Int) bool {
+	return isForked(c.IstanbulBlock, num) || isForked(c.EIP1884FBlock, num)
+}
+
+// IsEIP2028F returns whether num is equal to or greater than the Istanbul or EIP2028 block.
+func (c *ChainConfig) IsEIP2028F(num *big.Int) bool {
+	return isForked(c.IstanbulBlock, num) || isForked(c.EIP2028FBlock, num)
+}
+
+// IsEIP2200F returns whether num is equal to or greater than the Istanbul or EIP2200 block.
+func (c *ChainConfig) IsEIP2200F(num *big.Int) bool {
+	return isForked(c.IstanbulBlock, num) || isForked(c.EIP2200FBlock, num)
+}
+
+// IsIstanbul returns whether num is either equal to the Istanbul fork block or greater.
+func (c *ChainConfig) IsIstanbul(num *big.Int) bool {
+	return isForked(c.IstanbulBlock, num)
+}
+
 func (c *ChainConfig) IsBombDisposal(num *big.Int) bool {
 	return isForked(c.DisposalBlock, num)
 }
@@ -736,6 +825,18 @@ func (c *ChainConfig) IsECIP1010(num *big.Int) bool {
 	return isForked(c.ECIP1010PauseBlock, num)
 }
 
+// IsECBP1100F returns whether num is equal to or greater than the block from which
+// deep reorgs are subject to modified exponential subjective scoring.
+func (c *ChainConfig) IsECBP1100F(num *big.Int) bool {
+	return isForked(c.ECBP1100FBlock, num)
+}
+
+// IsECIP1099F returns whether num is equal to or greater than the block from which
+// ethash epochs are twice as long.
+func (c *ChainConfig) IsECIP1099F(num *big.Int) bool {
+	return isForked(c.ECIP1099FBlock, num)
+}
+
 // IsPetersburg returns whether num is either
 // - equal to or greater than the PetersburgBlock fork block,
 // - OR is nil, and Constantinople is active
@@ -750,21 +851,37 @@ func (c *ChainConfig) IsEWASM(num *big.Int) bool {
 
 // GasTable returns the gas table corresponding to the current phase.
 //
+// The repricing EIPs may be scheduled independently of each other, so the table
+// is assembled from the prices each of the activated EIPs sets, rather than
+// picking the table of the latest phase.
+//
 // The returned GasTable's fields shouldn't, under any circumstances, be changed.
 func (c *ChainConfig) GasTable(num *big.Int) GasTable {
+	gt := GasTableHomestead
 	if num == nil {
-		return GasTableHomestead
+		return gt
 	}
-	switch {
-	case c.IsEIP1052F(num):
-		return GasTableEIP1052
-	case c.IsEIP160F(num):
-		return GasTableEIP160
-	case c.IsEIP150(num):
-		return GasTableEIP150
-	default:
-		return GasTableHomestead
+	if c.IsEIP150(num) {
+		gt.ExtcodeSize = GasTableEIP150.ExtcodeSize
+		gt.ExtcodeCopy = GasTableEIP150.ExtcodeCopy
+		gt.Balance = GasTableEIP150.Balance
+		gt.SLoad = GasTableEIP150.SLoad
+		gt.Calls = GasTableEIP150.Calls
+		gt.Suicide = GasTableEIP150.Suicide
+		gt.CreateBySuicide = GasTableEIP150.CreateBySuicide
 	}
+	if c.IsEIP160F(num) {
+		gt.ExpByte = GasTableEIP160.ExpByte
+	}
+	if c.IsEIP1052F(num) {
+		gt.ExtcodeHash = GasTableEIP1052.ExtcodeHash
+	}
+	if c.IsEIP1884F(num) {
+		gt.ExtcodeHash = GasTableEIP1884.ExtcodeHash
+		gt.Balance = GasTableEIP1884.Balance
+		gt.SLoad = GasTableEIP1884.SLoad
+	}
+	return gt
 }
 
 // CheckCompatible checks whether scheduled fork transitions have been imported
@@ -786,47 +903,25 @@ func (c *ChainConfig) CheckCompatible(newcfg *ChainConfig, height uint64) *Confi
 }
 
 func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head *big.Int) *ConfigCompatError {
-	for _, ch := range []struct {
-		name   string
-		c1, c2 *big.Int
-	}{
-		{"Homestead", c.HomesteadBlock, newcfg.HomesteadBlock},
-		{"EIP7F", c.EIP7FBlock, newcfg.EIP7FBlock},
-		{"DAO", c.DAOForkBlock, newcfg.DAOForkBlock},
-		{"EIP150", c.EIP150Block, newcfg.EIP150Block},
-		{"EIP155", c.EIP155Block, newcfg.EIP155Block},
-		{"EIP158", c.EIP158Block, newcfg.EIP158Block},
-		{"EIP160F", c.EIP160FBlock, newcfg.EIP160FBlock},
-		{"EIP161F", c.EIP161FBlock, newcfg.EIP161FBlock},
-		{"EIP170F", c.EIP170FBlock, newcfg.EIP170FBlock},
-		{"Byzantium", c.ByzantiumBlock, newcfg.ByzantiumBlock},
-		{"EIP100F", c.EIP100FBlock, newcfg.EIP100FBlock},
-		{"EIP140F", c.EIP140FBlock, newcfg.EIP140FBlock},
-		{"EIP198F", c.EIP198FBlock, newcfg.EIP198FBlock},
-		{"EIP211F", c.EIP211FBlock, newcfg.EIP211FBlock},
-		{"EIP212F", c.EIP212FBlock, newcfg.EIP212FBlock},
-		{"EIP213F", c.EIP213FBlock, newcfg.EIP213FBlock},
-		{"EIP214F", c.EIP214FBlock, newcfg.EIP214FBlock},
-		{"EIP649F", c.EIP649FBlock, newcfg.EIP649FBlock},
-		{"EIP658F", c.EIP658FBlock, newcfg.EIP658FBlock},
-		{"Constantinople", c.ConstantinopleBlock, newcfg.ConstantinopleBlock},
-		{"EIP145F", c.EIP145FBlock, newcfg.EIP145FBlock},
-		{"EIP1014F", c.EIP1014FBlock, newcfg.EIP1014FBlock},
-		{"EIP1052F", c.EIP1052FBlock, newcfg.EIP1052FBlock},
-		{"EIP1234F", c.EIP1234FBlock, newcfg.EIP1234FBlock},
-		{"EIP1283F", c.EIP1283FBlock, newcfg.EIP1283FBlock},
-		{"EWASM", c.EWASMBlock, newcfg.EWASMBlock},
-	} {
-		if err := func(c1, c2, head *big.Int) *ConfigCompatError {
-			if isForkIncompatible(ch.c1, ch.c2, head) {
-				return newCompatError(ch.name+" fork block", ch.c1, ch.c2)
+	// Ensure no scheduled fork or fork parameter was changed in the past
+	for _, field := range forkFields {
+		var (
+			v1, v2 = c.Value(field), newcfg.Value(field)
+			a1, a2 = c.Activation(field), newcfg.Activation(field)
+		)
+		switch field.Kind {
+		case ForkBlock:
+			if isForkIncompatible(v1, v2, head) {
+				return newCompatError(field.Name+" fork block", v1, v2)
 			}
-			return nil
-		}(ch.c1, ch.c2, head); err != nil {
-			return err
+		case ForkParam:
+			if !configNumEqual(v1, v2) && (isForked(a1, head) || isForked(a2, head)) {
+				return newCompatError(field.Name+" fork parameter", a1, a2)
+			}
+		case ForkPolicy:
+			// Local chain selection policies may be rescheduled freely
 		}
 	}
-
 	if c.IsDAOFork(head) && c.DAOForkSupport != newcfg.DAOForkSupport {
 		return newCompatError("DAO fork support flag", c.DAOForkBlock, newcfg.DAOForkBlock)
 	}
@@ -845,16 +940,6 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head *big.Int) *Confi
 			return newCompatError("EIP649F/EIP100F fork block", c.EIP649FBlock, newcfg.EIP100FBlock)
 		}
 	}
-	if isForkIncompatible(c.PetersburgBlock, newcfg.PetersburgBlock, head) {
-		return newCompatError("ConstantinopleFix fork block", c.PetersburgBlock, newcfg.PetersburgBlock)
-	}
-	if isForkIncompatible(c.PetersburgBlock, newcfg.PetersburgBlock, head) {
-		return newCompatError("ConstantinopleFix fork block", c.PetersburgBlock, newcfg.PetersburgBlock)
-	}
-	if isForkIncompatible(c.EWASMBlock, newcfg.EWASMBlock, head) {
-		return newCompatError("ewasm fork block", c.EWASMBlock, newcfg.EWASMBlock)
-	}
-
 	return nil
 }
 
@@ -930,7 +1015,10 @@ type Rules struct {
 	// Constantinople
 	IsEIP145F, IsEIP1014F, IsEIP1052F, IsEIP1283F, IsEIP1234F bool
 	IsPetersburg                                              bool
-	IsBombDisposal, IsSocial, IsEthersocial, IsECIP1010       bool
+	// Istanbul
+	IsEIP152F, IsEIP1108F, IsEIP1344F, IsEIP1884F, IsEIP2028F, IsEIP2200F bool
+	IsIstanbul                                                            bool
+	IsBombDisposal, IsSocial, IsEthersocial, IsECIP1010                   bool
 }
 
 // Rules ensures c's ChainID is not nil.
@@ -969,6 +1057,14 @@ func (c *ChainConfig) Rules(num *big.Int) Rules {
 
 		IsPetersburg: c.IsPetersburg(num),
 
+		IsEIP152F:  c.IsEIP152F(num),
+		IsEIP1108F: c.IsEIP1108F(num),
+		IsEIP1344F: c.IsEIP1344F(num),
+		IsEIP1884F: c.IsEIP1884F(num),
+		IsEIP2028F: c.IsEIP2028F(num),
+		IsEIP2200F: c.IsEIP2200F(num),
+		IsIstanbul: c.IsIstanbul(num),
+
 		IsBombDisposal: c.IsBombDisposal(num),
 		IsSocial:       c.IsSocial(num),
 		IsEthersocial:  c.IsEthersocial(num),
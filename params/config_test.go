@@ -117,6 +117,12 @@ func TestCheckCompatible(t *testing.T) {
 			head:    25,
 			wantErr: nil,
 		},
+		{
+			stored:  &ChainConfig{ECBP1100FBlock: big.NewInt(10)},
+			new:     &ChainConfig{ECBP1100FBlock: big.NewInt(20)},
+			head:    25,
+			wantErr: nil,
+		},
 		{
 			stored: MainnetChainConfig,
 			new: func() *ChainConfig {
@@ -149,6 +155,56 @@ func TestCheckCompatible(t *testing.T) {
 				RewindTo:     new(big.Int).Sub(MainnetChainConfig.EIP158Block, common.Big1).Uint64(),
 			},
 		},
+		{
+			stored: &ChainConfig{EIP2FBlock: big.NewInt(10)},
+			new:    &ChainConfig{EIP2FBlock: big.NewInt(20)},
+			head:   15,
+			wantErr: &ConfigCompatError{
+				What:         "EIP2F fork block",
+				StoredConfig: big.NewInt(10),
+				NewConfig:    big.NewInt(20),
+				RewindTo:     9,
+			},
+		},
+		{
+			stored: &ChainConfig{DisposalBlock: big.NewInt(10)},
+			new:    &ChainConfig{},
+			head:   15,
+			wantErr: &ConfigCompatError{
+				What:         "Disposal fork block",
+				StoredConfig: big.NewInt(10),
+				NewConfig:    nil,
+				RewindTo:     9,
+			},
+		},
+		{
+			stored:  &ChainConfig{ECIP1010PauseBlock: big.NewInt(10), ECIP1010Length: big.NewInt(5)},
+			new:     &ChainConfig{ECIP1010PauseBlock: big.NewInt(10), ECIP1010Length: big.NewInt(7)},
+			head:    9,
+			wantErr: nil,
+		},
+		{
+			stored: &ChainConfig{ECIP1010PauseBlock: big.NewInt(10), ECIP1010Length: big.NewInt(5)},
+			new:    &ChainConfig{ECIP1010PauseBlock: big.NewInt(10), ECIP1010Length: big.NewInt(7)},
+			head:   12,
+			wantErr: &ConfigCompatError{
+				What:         "ECIP1010Length fork parameter",
+				StoredConfig: big.NewInt(10),
+				NewConfig:    big.NewInt(10),
+				RewindTo:     9,
+			},
+		},
+		{
+			stored: &ChainConfig{ECIP1017EraRounds: big.NewInt(100)},
+			new:    &ChainConfig{ECIP1017EraRounds: big.NewInt(200)},
+			head:   150,
+			wantErr: &ConfigCompatError{
+				What:         "ECIP1017EraRounds fork parameter",
+				StoredConfig: big.NewInt(100),
+				NewConfig:    big.NewInt(200),
+				RewindTo:     99,
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -158,3 +214,122 @@ func TestCheckCompatible(t *testing.T) {
 		}
 	}
 }
+
+// Tests that the fork registry covers every scheduling field of the chain config
+// and classifies them correctly. The expected registry is spelled out explicitly,
+// so that adding a field to ChainConfig requires a deliberate update here.
+func TestForkFields(t *testing.T) {
+	want := []struct {
+		name, json string
+		kind       ForkKind
+		gate       string
+	}{
+		{"Homestead", "homesteadBlock", ForkBlock, ""},
+		{"EIP2F", "eip2FBlock", ForkBlock, ""},
+		{"EIP7F", "eip7FBlock", ForkBlock, ""},
+		{"DAOFork", "daoForkBlock", ForkBlock, ""},
+		{"EIP150", "eip150Block", ForkBlock, ""},
+		{"EIP155", "eip155Block", ForkBlock, ""},
+		{"EIP158", "eip158Block", ForkBlock, ""},
+		{"EIP160F", "eip160Block", ForkBlock, ""},
+		{"EIP161F", "eip161FBlock", ForkBlock, ""},
+		{"EIP170F", "eip170FBlock", ForkBlock, ""},
+		{"Byzantium", "byzantiumBlock", ForkBlock, ""},
+		{"EIP100F", "eip100FBlock", ForkBlock, ""},
+		{"EIP140F", "eip140FBlock", ForkBlock, ""},
+		{"EIP198F", "eip198FBlock", ForkBlock, ""},
+		{"EIP211F", "eip211FBlock", ForkBlock, ""},
+		{"EIP212F", "eip212FBlock", ForkBlock, ""},
+		{"EIP213F", "eip213FBlock", ForkBlock, ""},
+		{"EIP214F", "eip214FBlock", ForkBlock, ""},
+		{"EIP649F", "eip649FBlock", ForkBlock, ""},
+		{"EIP658F", "eip658FBlock", ForkBlock, ""},
+		{"Constantinople", "constantinopleBlock", ForkBlock, ""},
+		{"EIP145F", "eip145FBlock", ForkBlock, ""},
+		{"EIP1014F", "eip1014FBlock", ForkBlock, ""},
+		{"EIP1052F", "eip1052FBlock", ForkBlock, ""},
+		{"EIP1234F", "eip1234FBlock", ForkBlock, ""},
+		{"EIP1283F", "eip1283FBlock", ForkBlock, ""},
+		{"Petersburg", "petersburgBlock", ForkBlock, ""},
+		{"Istanbul", "istanbulBlock", ForkBlock, ""},
+		{"EIP152F", "eip152FBlock", ForkBlock, ""},
+		{"EIP1108F", "eip1108FBlock", ForkBlock, ""},
+		{"EIP1344F", "eip1344FBlock", ForkBlock, ""},
+		{"EIP1884F", "eip1884FBlock", ForkBlock, ""},
+		{"EIP2028F", "eip2028FBlock", ForkBlock, ""},
+		{"EIP2200F", "eip2200FBlock", ForkBlock, ""},
+		{"EWASM", "ewasmBlock", ForkBlock, ""},
+		{"ECIP1010Pause", "ecip1010PauseBlock", ForkBlock, ""},
+		{"ECIP1010Length", "ecip1010Length", ForkParam, "ECIP1010Pause"},
+		{"ECIP1017EraRounds", "ecip1017EraRounds", ForkParam, ""},
+		{"Disposal", "disposalBlock", ForkBlock, ""},
+		{"Social", "socialBlock", ForkBlock, ""},
+		{"Ethersocial", "ethersocialBlock", ForkBlock, ""},
+		{"ECBP1100F", "ecbp1100FBlock", ForkPolicy, ""},
+		{"ECIP1099F", "ecip1099FBlock", ForkBlock, ""},
+	}
+	have := ForkFields()
+	if len(have) != len(want) {
+		t.Errorf("fork field count mismatch: have %d, want %d", len(have), len(want))
+	}
+	for i := 0; i < len(have) && i < len(want); i++ {
+		field, exp := have[i], want[i]
+		if field.Name != exp.name || field.JSON != exp.json || field.Kind != exp.kind || field.Gate != exp.gate {
+			t.Errorf("fork field %d mismatch: have {%s %s %v %q}, want {%s %s %v %q}",
+				i, field.Name, field.JSON, field.Kind, field.Gate, exp.name, exp.json, exp.kind, exp.gate)
+		}
+	}
+	// Ensure the registry reads and reports the configured values
+	config := &ChainConfig{ChainID: big.NewInt(1), EIP2FBlock: big.NewInt(2), ECIP1017EraRounds: big.NewInt(3)}
+	if have, want := config.String(), "{ChainID: 1 EIP2F: 2 ECIP1017EraRounds: 3 Engine: unknown}"; have != want {
+		t.Errorf("string mismatch: have %q, want %q", have, want)
+	}
+}
+
+// Tests that the gas table is assembled from the independently scheduled repricing
+// EIPs, matching the phase tables on chains activating them in the usual order.
+func TestGasTable(t *testing.T) {
+	mainnet := &ChainConfig{
+		EIP150Block:         big.NewInt(10),
+		EIP158Block:         big.NewInt(20),
+		ConstantinopleBlock: big.NewInt(30),
+		IstanbulBlock:       big.NewInt(40),
+	}
+	tests := []struct {
+		config *ChainConfig
+		num    *big.Int
+		want   GasTable
+	}{
+		{mainnet, nil, GasTableHomestead},
+		{mainnet, big.NewInt(0), GasTableHomestead},
+		{mainnet, big.NewInt(10), GasTableEIP150},
+		{mainnet, big.NewInt(20), GasTableEIP160},
+		{mainnet, big.NewInt(30), GasTableEIP1052},
+		{mainnet, big.NewInt(40), GasTableEIP1884},
+
+		// EIP-1884 scheduled without the earlier repricings only changes its own prices
+		{&ChainConfig{EIP1884FBlock: big.NewInt(0)}, big.NewInt(0), GasTable{
+			ExtcodeSize: 20,
+			ExtcodeCopy: 20,
+			ExtcodeHash: 700,
+			Balance:     700,
+			SLoad:       800,
+			Calls:       40,
+			ExpByte:     10,
+		}},
+		// EIP-160 scheduled before EIP-150 only reprices EXP
+		{&ChainConfig{EIP160FBlock: big.NewInt(0), EIP150Block: big.NewInt(10)}, big.NewInt(0), GasTable{
+			ExtcodeSize: 20,
+			ExtcodeCopy: 20,
+			Balance:     20,
+			SLoad:       50,
+			Calls:       40,
+			ExpByte:     50,
+		}},
+	}
+	for i, tt := range tests {
+		if have := tt.config.GasTable(tt.num); have != tt.want {
+			t.Errorf("test %d: gas table mismatch:\nhave %+v\nwant %+v", i, have, tt.want)
+		}
+	}
+}
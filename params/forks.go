@@ -0,0 +1,154 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package params
+
+import (
+	"math/big"
+	"reflect"
+	"strings"
+)
+
+// ForkKind is the consensus role of a fork field in the chain configuration.
+type ForkKind uint8
+
+const (
+	// ForkBlock fields schedule the activation of a fork at a block number.
+	ForkBlock ForkKind = iota
+
+	// ForkParam fields parametrise the consensus rules of a fork, taking effect
+	// from the activation of their gating fork onwards.
+	ForkParam
+
+	// ForkPolicy fields schedule node local chain selection policies, which are
+	// not consensus rules and may be rescheduled at any time.
+	ForkPolicy
+)
+
+// String implements fmt.Stringer.
+func (k ForkKind) String() string {
+	switch k {
+	case ForkBlock:
+		return "block"
+	case ForkParam:
+		return "param"
+	case ForkPolicy:
+		return "policy"
+	default:
+		return "unknown"
+	}
+}
+
+// ForkField describes a single consensus-relevant field of ChainConfig.
+type ForkField struct {
+	Name string   // Human readable name, the Go field name sans the Block suffix
+	JSON string   // Name of the field in the JSON encoded chain configuration
+	Kind ForkKind // Consensus role of the field
+	Gate string   // Name of the fork activating a parameter, empty if self-gating
+
+	index int // Index of the field within ChainConfig
+}
+
+// forkParamGates maps the fork parameters to the forks they parametrise. Any
+// parameter without a gate takes effect at the block number it holds (e.g. the
+// era length of ECIP1017 only changes rewards from the end of the first era).
+var forkParamGates = map[string]string{
+	"ECIP1010Length": "ECIP1010Pause",
+}
+
+// forkPolicies is the set of fork fields scheduling chain selection policies.
+var forkPolicies = map[string]bool{
+	"ECBP1100F": true,
+}
+
+// forkFields is the registry of all fork fields in ChainConfig, in declaration
+// order. It is assembled reflectively, so new forks are picked up automatically.
+var forkFields = func() []ForkField {
+	var (
+		typ    = reflect.TypeOf(ChainConfig{})
+		bigInt = reflect.TypeOf((*big.Int)(nil))
+		fields []ForkField
+	)
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.Type != bigInt || field.Name == "ChainID" {
+			continue // Chain ID is checked against EIP155 separately
+		}
+		fork := ForkField{
+			Name:  strings.TrimSuffix(field.Name, "Block"),
+			JSON:  strings.Split(field.Tag.Get("json"), ",")[0],
+			index: i,
+		}
+		switch {
+		case forkPolicies[fork.Name]:
+			fork.Kind = ForkPolicy
+		case !strings.HasSuffix(field.Name, "Block"):
+			fork.Kind = ForkParam
+			fork.Gate = forkParamGates[fork.Name]
+		}
+		fields = append(fields, fork)
+	}
+	return fields
+}()
+
+// ForkFields returns the descriptors of all fork fields of ChainConfig.
+func ForkFields() []ForkField {
+	fields := make([]ForkField, len(forkFields))
+	copy(fields, forkFields)
+	return fields
+}
+
+// forkField retrieves the descriptor of a fork field by name.
+func forkField(name string) (ForkField, bool) {
+	for _, field := range forkFields {
+		if field.Name == name {
+			return field, true
+		}
+	}
+	return ForkField{}, false
+}
+
+// Value returns the configured value of the fork field, nil if unset.
+func (c *ChainConfig) Value(field ForkField) *big.Int {
+	return reflect.ValueOf(c).Elem().Field(field.index).Interface().(*big.Int)
+}
+
+// SetValue sets the configured value of the fork field, nil unsetting it.
+func (c *ChainConfig) SetValue(field ForkField, value *big.Int) {
+	reflect.ValueOf(c).Elem().Field(field.index).Set(reflect.ValueOf(value))
+}
+
+// Activation returns the block number from which the fork field affects the
+// consensus rules, nil if never.
+func (c *ChainConfig) Activation(field ForkField) *big.Int {
+	if field.Kind == ForkParam && field.Gate != "" {
+		gate, _ := forkField(field.Gate)
+		return c.Value(gate)
+	}
+	return c.Value(field)
+}
+
+// ForkSchedule returns all the fork fields configured in the chain configuration,
+// in declaration order.
+func (c *ChainConfig) ForkSchedule() []ForkField {
+	var forks []ForkField
+	for _, field := range forkFields {
+		if c.Value(field) != nil {
+			forks = append(forks, field)
+		}
+	}
+	return forks
+}
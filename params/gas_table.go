@@ -92,4 +92,19 @@ var (
 
 		CreateBySuicide: 25000,
 	}
+
+	// GasTableEIP1884 contain the gas re-prices for
+	// the trie-size-dependent opcodes of the istanbul phase.
+	GasTableEIP1884 = GasTable{
+		ExtcodeSize: 700,
+		ExtcodeCopy: 700,
+		ExtcodeHash: 700,
+		Balance:     700,
+		SLoad:       800,
+		Calls:       700,
+		Suicide:     5000,
+		ExpByte:     50,
+
+		CreateBySuicide: 25000,
+	}
 )
@@ -0,0 +1,206 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// DefaultIssuedAtTolerance is the maximum distance between the issued-at claim of
+// a JWT and the local clock that is accepted if no tolerance is configured.
+const DefaultIssuedAtTolerance = 60 * time.Second
+
+var (
+	errMissingToken    = errors.New("missing bearer token")
+	errInvalidToken    = errors.New("invalid bearer token")
+	errMissingIssuedAt = errors.New("missing issued-at claim")
+	errStaleIssuedAt   = errors.New("issued-at claim outside of tolerance")
+	errTokenExpired    = errors.New("token expired")
+	errEmptyToken      = errors.New("empty bearer token")
+	errEmptySecret     = errors.New("empty JWT secret")
+)
+
+// AuthToken is a credential accepted by the HTTP and WebSocket RPC endpoints,
+// along with the API modules its bearer is allowed to call.
+type AuthToken struct {
+	// Token is either a static bearer token which must be presented verbatim,
+	// or the HS256 secret used to sign JWTs if JWT is set.
+	Token string `toml:",omitempty"`
+
+	// JWT marks Token as a signing secret rather than a static bearer token.
+	JWT bool `toml:",omitempty"`
+
+	// Modules is the list of API modules the bearer may call. If empty, every
+	// module exposed by the endpoint is accessible.
+	Modules []string `toml:",omitempty"`
+}
+
+// AuthConfig configures token based authentication of HTTP and WebSocket RPC
+// requests. Authentication is disabled if no tokens are configured.
+//
+// The GraphQL endpoint is not covered: it is served by a separate service with
+// its own listener and has no API modules to grant, so it must not be exposed
+// beyond trusted interfaces when authentication is required.
+type AuthConfig struct {
+	// Tokens is the list of credentials accepted in the Authorization header.
+	Tokens []AuthToken `toml:",omitempty"`
+
+	// IssuedAtTolerance is the maximum distance between the issued-at claim of
+	// a JWT and the local clock. If zero, DefaultIssuedAtTolerance is used.
+	IssuedAtTolerance time.Duration `toml:",omitempty"`
+}
+
+// Enabled reports whether requests need to be authenticated.
+func (c AuthConfig) Enabled() bool {
+	return len(c.Tokens) > 0
+}
+
+// authModulesKey is the context key under which the modules granted to the
+// authenticated caller are stored.
+type authModulesKey struct{}
+
+// authIdentityKey is the context key under which the identity of the token the
+// caller authenticated with is stored.
+type authIdentityKey struct{}
+
+// authCredential is a parsed AuthToken.
+type authCredential struct {
+	id      string // identity of the credential, never the token itself
+	token   []byte
+	modules map[string]bool // nil if all modules are allowed
+}
+
+// authHandler is a handler which authenticates incoming requests against the
+// configured bearer tokens and JWT secrets, recording the modules granted to the
+// caller in the request context.
+type authHandler struct {
+	static    []authCredential
+	secrets   []authCredential
+	tolerance time.Duration
+	next      http.Handler
+}
+
+// newAuthHandler wraps the handler with authentication if the config has tokens.
+// Empty tokens and secrets are rejected, as they would match any empty bearer
+// token or sign JWTs with an empty key.
+func newAuthHandler(config AuthConfig, next http.Handler) (http.Handler, error) {
+	if !config.Enabled() {
+		return next, nil
+	}
+	h := &authHandler{tolerance: config.IssuedAtTolerance, next: next}
+	if h.tolerance <= 0 {
+		h.tolerance = DefaultIssuedAtTolerance
+	}
+	for i, token := range config.Tokens {
+		if token.Token == "" {
+			if token.JWT {
+				return nil, fmt.Errorf("auth token %d: %v", i, errEmptySecret)
+			}
+			return nil, fmt.Errorf("auth token %d: %v", i, errEmptyToken)
+		}
+		cred := authCredential{id: fmt.Sprintf("token%d", i), token: []byte(token.Token)}
+		if len(token.Modules) > 0 {
+			cred.modules = make(map[string]bool)
+			for _, module := range token.Modules {
+				cred.modules[module] = true
+			}
+		}
+		if token.JWT {
+			h.secrets = append(h.secrets, cred)
+		} else {
+			h.static = append(h.static, cred)
+		}
+	}
+	return h, nil
+}
+
+// ServeHTTP authenticates the request and passes it on, implements http.Handler
+func (h *authHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id, modules, err := h.authenticate(r)
+	if err != nil {
+		log.Debug("Rejected unauthenticated RPC request", "remote", r.RemoteAddr, "err", err)
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	ctx := context.WithValue(r.Context(), authIdentityKey{}, id)
+	if modules != nil {
+		ctx = context.WithValue(ctx, authModulesKey{}, modules)
+	}
+	h.next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// authenticate checks the bearer token of the request and returns the identity
+// of the caller and the modules it may access, or nil if all are accessible.
+// Callers are identified by the credential they authenticated with, claims of
+// JWTs are chosen by the caller and don't tell callers apart.
+func (h *authHandler) authenticate(r *http.Request) (string, map[string]bool, error) {
+	header := r.Header.Get("Authorization")
+	if len(header) < 7 || !strings.EqualFold(header[:7], "Bearer ") {
+		return "", nil, errMissingToken
+	}
+	token := strings.TrimSpace(header[7:])
+	for _, cred := range h.static {
+		if subtle.ConstantTimeCompare(cred.token, []byte(token)) == 1 {
+			return cred.id, cred.modules, nil
+		}
+	}
+	if len(h.secrets) == 0 {
+		return "", nil, errInvalidToken
+	}
+	parser := &jwt.Parser{
+		ValidMethods:         []string{jwt.SigningMethodHS256.Alg()},
+		SkipClaimsValidation: true,
+	}
+	for _, cred := range h.secrets {
+		claims := new(jwt.StandardClaims)
+		keyfunc := func(*jwt.Token) (interface{}, error) { return cred.token, nil }
+		if _, err := parser.ParseWithClaims(token, claims, keyfunc); err != nil {
+			continue
+		}
+		// Signature matches, the token is rejected if its claims are invalid
+		if err := h.verifyClaims(claims, time.Now()); err != nil {
+			return "", nil, err
+		}
+		return cred.id, cred.modules, nil
+	}
+	return "", nil, errInvalidToken
+}
+
+// verifyClaims checks that the JWT was issued within the tolerated distance of
+// the local clock and hasn't expired yet.
+func (h *authHandler) verifyClaims(claims *jwt.StandardClaims, now time.Time) error {
+	if claims.IssuedAt == 0 {
+		return errMissingIssuedAt
+	}
+	if diff := now.Sub(time.Unix(claims.IssuedAt, 0)); diff > h.tolerance || diff < -h.tolerance {
+		return errStaleIssuedAt
+	}
+	if claims.ExpiresAt != 0 && now.Unix() >= claims.ExpiresAt {
+		return errTokenExpired
+	}
+	return nil
+}
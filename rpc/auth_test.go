@@ -0,0 +1,216 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"golang.org/x/net/websocket"
+)
+
+var testAuthConfig = AuthConfig{
+	Tokens: []AuthToken{
+		{Token: "full-access"},
+		{Token: "nftest-only", Modules: []string{"nftest"}},
+		{Token: "test-secret", JWT: true, Modules: []string{"test"}},
+	},
+}
+
+func signTestJWT(t *testing.T, secret string, claims jwt.StandardClaims) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return token
+}
+
+// doAuthRequest issues a test_echo call with the given bearer token and returns
+// the HTTP status and the JSON-RPC error message, if any.
+func doAuthRequest(t *testing.T, url, token string) (int, string) {
+	body := `{"jsonrpc":"2.0","id":1,"method":"test_echo","params":["x",1]}`
+	req, _ := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	blob, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, ""
+	}
+	var msg jsonrpcMessage
+	if err := json.Unmarshal(blob, &msg); err != nil {
+		t.Fatalf("invalid response %q: %v", blob, err)
+	}
+	if msg.Error != nil {
+		return resp.StatusCode, msg.Error.Message
+	}
+	return resp.StatusCode, ""
+}
+
+func TestHTTPAuth(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	handler, err := newAuthHandler(testAuthConfig, server)
+	if err != nil {
+		t.Fatalf("failed to create auth handler: %v", err)
+	}
+	httpsrv := httptest.NewServer(handler)
+	defer httpsrv.Close()
+
+	now := time.Now()
+	tests := []struct {
+		token  string
+		status int
+		rpcErr bool
+	}{
+		{token: "", status: http.StatusUnauthorized},
+		{token: "wrong", status: http.StatusUnauthorized},
+		{token: "full-access", status: http.StatusOK},
+		{token: "nftest-only", status: http.StatusOK, rpcErr: true},
+		{
+			token:  signTestJWT(t, "test-secret", jwt.StandardClaims{IssuedAt: now.Unix()}),
+			status: http.StatusOK,
+		},
+		{
+			token:  signTestJWT(t, "test-secret", jwt.StandardClaims{IssuedAt: now.Unix(), ExpiresAt: now.Add(time.Hour).Unix()}),
+			status: http.StatusOK,
+		},
+		{
+			token:  signTestJWT(t, "wrong-secret", jwt.StandardClaims{IssuedAt: now.Unix()}),
+			status: http.StatusUnauthorized,
+		},
+		{
+			token:  signTestJWT(t, "test-secret", jwt.StandardClaims{}),
+			status: http.StatusUnauthorized,
+		},
+		{
+			token:  signTestJWT(t, "test-secret", jwt.StandardClaims{IssuedAt: now.Add(-time.Hour).Unix()}),
+			status: http.StatusUnauthorized,
+		},
+		{
+			token:  signTestJWT(t, "test-secret", jwt.StandardClaims{IssuedAt: now.Add(time.Hour).Unix()}),
+			status: http.StatusUnauthorized,
+		},
+		{
+			token:  signTestJWT(t, "test-secret", jwt.StandardClaims{IssuedAt: now.Unix(), ExpiresAt: now.Add(-time.Second).Unix()}),
+			status: http.StatusUnauthorized,
+		},
+	}
+	for i, tt := range tests {
+		status, rpcErr := doAuthRequest(t, httpsrv.URL, tt.token)
+		if status != tt.status {
+			t.Errorf("test %d: status mismatch: have %d, want %d", i, status, tt.status)
+		}
+		if (rpcErr != "") != tt.rpcErr {
+			t.Errorf("test %d: unexpected RPC error state: %q", i, rpcErr)
+		}
+	}
+}
+
+func TestWebsocketAuth(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	handler, err := newAuthHandler(testAuthConfig, server.WebsocketHandler([]string{"*"}))
+	if err != nil {
+		t.Fatalf("failed to create auth handler: %v", err)
+	}
+	httpsrv := httptest.NewServer(handler)
+	defer httpsrv.Close()
+	wsURL := "ws:" + strings.TrimPrefix(httpsrv.URL, "http:")
+
+	dial := func(token string) (*Client, error) {
+		config, err := websocket.NewConfig(wsURL, "http://localhost")
+		if err != nil {
+			return nil, err
+		}
+		if token != "" {
+			config.Header.Set("Authorization", "Bearer "+token)
+		}
+		return newClient(context.Background(), func(context.Context) (ServerCodec, error) {
+			conn, err := websocket.DialConfig(config)
+			if err != nil {
+				return nil, err
+			}
+			return newWebsocketCodec(conn), nil
+		})
+	}
+	// Connections without a valid token are rejected during the handshake.
+	for _, token := range []string{"", "wrong"} {
+		if client, err := dial(token); err == nil {
+			client.Close()
+			t.Errorf("token %q: expected handshake failure", token)
+		}
+	}
+	// Authenticated connections may only call the modules granted to the token.
+	tests := []struct {
+		token string
+		fail  bool
+	}{
+		{token: "full-access"},
+		{token: "nftest-only", fail: true},
+		{token: signTestJWT(t, "test-secret", jwt.StandardClaims{IssuedAt: time.Now().Unix()})},
+	}
+	for i, tt := range tests {
+		client, err := dial(tt.token)
+		if err != nil {
+			t.Fatalf("test %d: dial failed: %v", i, err)
+		}
+		var result Result
+		err = client.Call(&result, "test_echo", "x", 1)
+		if (err != nil) != tt.fail {
+			t.Errorf("test %d: call error mismatch: %v", i, err)
+		}
+		client.Close()
+	}
+}
+
+// Tests that empty static tokens and JWT secrets are rejected, as they would
+// authenticate a bare "Bearer " header or JWTs signed with an empty key.
+func TestAuthEmptyCredentials(t *testing.T) {
+	tests := []struct {
+		config AuthConfig
+		err    error
+	}{
+		{config: AuthConfig{Tokens: []AuthToken{{Token: "full-access"}, {Token: ""}}}, err: errEmptyToken},
+		{config: AuthConfig{Tokens: []AuthToken{{Token: "", JWT: true}}}, err: errEmptySecret},
+	}
+	for i, tt := range tests {
+		if _, err := newAuthHandler(tt.config, http.NotFoundHandler()); err == nil || !strings.Contains(err.Error(), tt.err.Error()) {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+		if _, _, err := StartHTTPEndpoint("127.0.0.1:0", nil, nil, nil, nil, DefaultHTTPTimeouts, tt.config, Limits{}); err == nil {
+			t.Errorf("test %d: HTTP endpoint started with empty credential", i)
+		}
+		if _, _, err := StartWSEndpoint("127.0.0.1:0", nil, nil, nil, false, tt.config, Limits{}); err == nil {
+			t.Errorf("test %d: WebSocket endpoint started with empty credential", i)
+		}
+	}
+}
@@ -61,6 +61,7 @@ type handler struct {
 	conn           jsonWriter                     // where responses will be sent
 	log            log.Logger
 	allowSubscribe bool
+	limiter        *clientLimiter // resource limits of the remote client, nil if unlimited
 
 	subLock    sync.Mutex
 	serverSubs map[ID]*Subscription
@@ -102,6 +103,15 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 		return
 	}
 
+	// Reject batches exceeding the client's limits
+	if h.limiter != nil {
+		if err := h.limiter.checkBatch(len(msgs)); err != nil {
+			h.startCallProc(func(cp *callProc) {
+				h.conn.Write(cp.ctx, errorMessage(err))
+			})
+			return
+		}
+	}
 	// Handle non-call messages first:
 	calls := make([]*jsonrpcMessage, 0, len(msgs))
 	for _, msg := range msgs {
@@ -295,7 +305,19 @@ func (h *handler) handleCallMsg(ctx *callProc, msg *jsonrpcMessage) *jsonrpcMess
 		h.log.Debug("Served "+msg.Method, "t", time.Since(start))
 		return nil
 	case msg.isCall():
+		if h.limiter != nil {
+			if err := h.limiter.admit(msg.Method); err != nil {
+				h.log.Debug("Rejected "+msg.Method, "reqid", idForLog{msg.ID}, "err", err)
+				return msg.errorResponse(err)
+			}
+			defer h.limiter.release()
+		}
 		resp := h.handleCall(ctx, msg)
+		if h.limiter != nil && resp.Error == nil {
+			if err := h.limiter.checkResponse(resp); err != nil {
+				resp = msg.errorResponse(err)
+			}
+		}
 		if resp.Error != nil {
 			h.log.Info("Served "+msg.Method, "reqid", idForLog{msg.ID}, "t", time.Since(start), "err", resp.Error.Message)
 		} else {
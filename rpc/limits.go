@@ -0,0 +1,234 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"math"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/golang-lru"
+)
+
+// limitedClients is the number of idle clients whose limiter state is retained.
+// The state of the least recently active idle clients is dropped beyond this.
+const limitedClients = 4096
+
+// Limits configures the resources a single client may consume. Clients are told
+// apart by their authentication token if they present one, or by their remote
+// address otherwise. Zero values disable the respective limit.
+type Limits struct {
+	// RequestsPerSecond is the sustained rate at which a client may spend request
+	// cost units. Every call costs one unit unless listed in MethodCosts.
+	RequestsPerSecond float64 `toml:",omitempty"`
+
+	// Burst is the number of cost units a client may spend at once. If zero, it
+	// defaults to one second worth of requests.
+	Burst float64 `toml:",omitempty"`
+
+	// MaxConcurrent is the maximum number of calls a client may have in flight.
+	MaxConcurrent int `toml:",omitempty"`
+
+	// MaxBatchLength is the maximum number of requests in a single batch.
+	MaxBatchLength int `toml:",omitempty"`
+
+	// MaxResponseSize is the maximum size in bytes of the result of a single call.
+	MaxResponseSize int `toml:",omitempty"`
+
+	// MethodCosts overrides the cost of individual methods. Keys are either full
+	// method names such as "eth_getLogs", or prefixes ending in '*' such as
+	// "debug_trace*", in which case the longest matching prefix applies.
+	MethodCosts map[string]float64 `toml:",omitempty"`
+}
+
+// Enabled reports whether any limit is configured.
+func (l Limits) Enabled() bool {
+	return l.RequestsPerSecond > 0 || l.MaxConcurrent > 0 || l.MaxBatchLength > 0 || l.MaxResponseSize > 0
+}
+
+// burst returns the capacity of the clients' request buckets.
+func (l Limits) burst() float64 {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+	return l.RequestsPerSecond
+}
+
+// cost returns the number of request units the given method consumes.
+func (l Limits) cost(method string) float64 {
+	if cost, ok := l.MethodCosts[method]; ok {
+		return cost
+	}
+	var (
+		cost    = 1.0
+		longest = -1
+	)
+	for pattern, c := range l.MethodCosts {
+		if !strings.HasSuffix(pattern, "*") {
+			continue
+		}
+		prefix := strings.TrimSuffix(pattern, "*")
+		if strings.HasPrefix(method, prefix) && len(prefix) > longest {
+			cost, longest = c, len(prefix)
+		}
+	}
+	return cost
+}
+
+// limitExceededError is returned when a client exceeds one of its limits.
+type limitExceededError struct{ message string }
+
+func (e *limitExceededError) ErrorCode() int { return -32005 }
+
+func (e *limitExceededError) Error() string { return e.message }
+
+var (
+	errRateLimited        = &limitExceededError{"request rate limit exceeded"}
+	errConcurrencyLimited = &limitExceededError{"too many concurrent requests"}
+	errBatchLimited       = &limitExceededError{"batch too large"}
+	errResponseLimited    = &limitExceededError{"response too large"}
+)
+
+// serverLimiter tracks the limiter state of the clients of a server. Clients with
+// calls in flight are kept outside of the LRU cache, so that their concurrency
+// limits can't be reset by evicting them.
+type serverLimiter struct {
+	limits  Limits
+	clients *lru.Cache                // client key -> *clientLimiter
+	active  map[string]*clientLimiter // clients with calls in flight
+	mu      sync.Mutex
+}
+
+func newServerLimiter(limits Limits) *serverLimiter {
+	clients, _ := lru.New(limitedClients)
+	return &serverLimiter{limits: limits, clients: clients, active: make(map[string]*clientLimiter)}
+}
+
+// client returns the limiter of the caller of the given context, whose remote
+// address is used to identify it if it isn't authenticated.
+func (l *serverLimiter) client(ctx context.Context, remote string) *clientLimiter {
+	key, ok := ctx.Value(authIdentityKey{}).(string)
+	if !ok {
+		if host, _, err := net.SplitHostPort(remote); err == nil {
+			remote = host
+		}
+		key = remote
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if c, ok := l.active[key]; ok {
+		return c
+	}
+	if c, ok := l.clients.Get(key); ok {
+		return c.(*clientLimiter)
+	}
+	c := &clientLimiter{server: l, key: key, limits: &l.limits, tokens: l.limits.burst(), updated: time.Now()}
+	l.clients.Add(key, c)
+	return c
+}
+
+// setActive moves the client in or out of the set of clients with calls in
+// flight, returning it to the LRU cache once it becomes idle.
+func (l *serverLimiter) setActive(c *clientLimiter, active bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if active {
+		l.active[c.key] = c
+	} else {
+		delete(l.active, c.key)
+		l.clients.Add(c.key, c)
+	}
+}
+
+// clientLimiter enforces the limits of a single client across all of its
+// connections and requests.
+type clientLimiter struct {
+	server *serverLimiter
+	key    string
+	limits *Limits
+
+	mu       sync.Mutex
+	tokens   float64   // request cost units available
+	updated  time.Time // last time tokens were refilled
+	inflight int       // number of calls in flight
+}
+
+// admit checks whether the client may call the given method, reserving one of
+// its in-flight slots if so. Admitted calls must be released once done.
+func (c *clientLimiter) admit(method string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.limits.MaxConcurrent > 0 && c.inflight >= c.limits.MaxConcurrent {
+		limitedConcurrencyCounter.Inc(1)
+		return errConcurrencyLimited
+	}
+	if c.limits.RequestsPerSecond > 0 {
+		now, burst := time.Now(), c.limits.burst()
+		c.tokens += now.Sub(c.updated).Seconds() * c.limits.RequestsPerSecond
+		if c.tokens > burst {
+			c.tokens = burst
+		}
+		c.updated = now
+
+		// Methods costlier than the burst may be called once the bucket is full
+		cost := math.Min(c.limits.cost(method), burst)
+		if c.tokens < cost {
+			limitedRateCounter.Inc(1)
+			return errRateLimited
+		}
+		c.tokens -= cost
+	}
+	if c.inflight++; c.inflight == 1 {
+		c.server.setActive(c, true)
+	}
+	limitedRequestCounter.Inc(1)
+	return nil
+}
+
+// release frees the in-flight slot of an admitted call.
+func (c *clientLimiter) release() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.inflight--; c.inflight == 0 {
+		c.server.setActive(c, false)
+	}
+}
+
+// checkBatch verifies the length of a batch.
+func (c *clientLimiter) checkBatch(length int) error {
+	if c.limits.MaxBatchLength > 0 && length > c.limits.MaxBatchLength {
+		limitedBatchCounter.Inc(1)
+		return errBatchLimited
+	}
+	return nil
+}
+
+// checkResponse verifies the size of a call's result.
+func (c *clientLimiter) checkResponse(resp *jsonrpcMessage) error {
+	if c.limits.MaxResponseSize > 0 && len(resp.Result) > c.limits.MaxResponseSize {
+		limitedResponseCounter.Inc(1)
+		return errResponseLimited
+	}
+	return nil
+}
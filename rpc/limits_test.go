@@ -0,0 +1,205 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+func TestLimitsMethodCost(t *testing.T) {
+	limits := Limits{MethodCosts: map[string]float64{
+		"eth_getLogs":  10,
+		"debug_*":      5,
+		"debug_trace*": 20,
+	}}
+	tests := map[string]float64{
+		"eth_getLogs":            10,
+		"eth_blockNumber":        1,
+		"debug_traceTransaction": 20,
+		"debug_getBadBlocks":     5,
+	}
+	for method, want := range tests {
+		if have := limits.cost(method); have != want {
+			t.Errorf("%s: cost mismatch: have %v, want %v", method, have, want)
+		}
+	}
+}
+
+func TestClientLimiterAdmit(t *testing.T) {
+	limiter := newServerLimiter(Limits{
+		RequestsPerSecond: 0.001,
+		Burst:             3,
+		MaxConcurrent:     2,
+		MethodCosts:       map[string]float64{"test_expensive": 100},
+	})
+	client := limiter.client(context.Background(), "127.0.0.1:1234")
+	if other := limiter.client(context.Background(), "127.0.0.1:5678"); other != client {
+		t.Fatalf("clients on the same host should share a limiter")
+	}
+	// Concurrency is limited independently of the rate
+	if err := client.admit("test_echo"); err != nil {
+		t.Fatalf("first call rejected: %v", err)
+	}
+	if err := client.admit("test_echo"); err != nil {
+		t.Fatalf("second call rejected: %v", err)
+	}
+	if err := client.admit("test_echo"); err != errConcurrencyLimited {
+		t.Fatalf("third concurrent call error mismatch: have %v, want %v", err, errConcurrencyLimited)
+	}
+	client.release()
+	client.release()
+
+	// The burst allowance is now mostly spent and doesn't refill in time
+	if err := client.admit("test_echo"); err != nil {
+		t.Fatalf("third call rejected: %v", err)
+	}
+	client.release()
+	if err := client.admit("test_echo"); err != errRateLimited {
+		t.Fatalf("fourth call error mismatch: have %v, want %v", err, errRateLimited)
+	}
+	// Methods costlier than the burst are admitted with a full bucket only
+	fresh := limiter.client(context.Background(), "10.0.0.1:1234")
+	if err := fresh.admit("test_expensive"); err != nil {
+		t.Fatalf("expensive call rejected with full bucket: %v", err)
+	}
+	fresh.release()
+	if err := fresh.admit("test_echo"); err != errRateLimited {
+		t.Fatalf("call after expensive one error mismatch: have %v, want %v", err, errRateLimited)
+	}
+	// Authenticated callers are limited by token rather than address
+	ctx := context.WithValue(context.Background(), authIdentityKey{}, "token0")
+	if authed := limiter.client(ctx, "127.0.0.1:1234"); authed == client {
+		t.Fatalf("authenticated caller shares the limiter of its address")
+	}
+}
+
+func TestClientLimiterEviction(t *testing.T) {
+	limiter := newServerLimiter(Limits{MaxConcurrent: 1})
+
+	busy := limiter.client(context.Background(), "127.0.0.1:1234")
+	if err := busy.admit("test_echo"); err != nil {
+		t.Fatalf("call rejected: %v", err)
+	}
+	// Clients with calls in flight survive the eviction of idle ones
+	for i := 0; i <= limitedClients; i++ {
+		limiter.client(context.Background(), fmt.Sprintf("10.0.%d.%d:1234", i/256, i%256))
+	}
+	if client := limiter.client(context.Background(), "127.0.0.1:1234"); client != busy {
+		t.Fatalf("limiter of busy client evicted")
+	}
+	if err := busy.admit("test_echo"); err != errConcurrencyLimited {
+		t.Fatalf("concurrent call error mismatch: have %v, want %v", err, errConcurrencyLimited)
+	}
+	// Once idle, the client is retained like any other
+	busy.release()
+	if client := limiter.client(context.Background(), "127.0.0.1:1234"); client != busy {
+		t.Fatalf("limiter of idle client dropped")
+	}
+	if len(limiter.active) != 0 {
+		t.Fatalf("idle clients still active: %d", len(limiter.active))
+	}
+}
+
+// postLimited sends the given JSON-RPC payload to the server and returns the raw
+// response body.
+func postLimited(t *testing.T, url, body string) string {
+	resp, err := http.Post(url, contentType, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	blob, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read response: %v", err)
+	}
+	return string(blob)
+}
+
+func TestHTTPLimits(t *testing.T) {
+	server := newTestServer()
+	server.SetLimits(Limits{
+		RequestsPerSecond: 0.001,
+		Burst:             4,
+		MaxBatchLength:    2,
+		MaxResponseSize:   16,
+	})
+	defer server.Stop()
+	httpsrv := httptest.NewServer(server)
+	defer httpsrv.Close()
+
+	const limitCode = `"code":-32005`
+
+	// Small responses are served, large ones are replaced by an error
+	if resp := postLimited(t, httpsrv.URL, `{"jsonrpc":"2.0","id":1,"method":"test_rets"}`); strings.Contains(resp, limitCode) {
+		t.Errorf("small response rejected: %s", resp)
+	}
+	resp := postLimited(t, httpsrv.URL, `{"jsonrpc":"2.0","id":2,"method":"test_echo","params":["x",1]}`)
+	if !strings.Contains(resp, limitCode) || !strings.Contains(resp, errResponseLimited.Error()) {
+		t.Errorf("large response not rejected: %s", resp)
+	}
+	// Batches exceeding the maximum length are rejected as a whole
+	resp = postLimited(t, httpsrv.URL, `[
+		{"jsonrpc":"2.0","id":3,"method":"test_rets"},
+		{"jsonrpc":"2.0","id":4,"method":"test_rets"},
+		{"jsonrpc":"2.0","id":5,"method":"test_rets"}
+	]`)
+	if !strings.Contains(resp, limitCode) || !strings.Contains(resp, errBatchLimited.Error()) {
+		t.Errorf("long batch not rejected: %s", resp)
+	}
+	// The request rate is limited across requests once the burst is spent
+	postLimited(t, httpsrv.URL, `{"jsonrpc":"2.0","id":6,"method":"test_rets"}`)
+	postLimited(t, httpsrv.URL, `{"jsonrpc":"2.0","id":7,"method":"test_rets"}`)
+	resp = postLimited(t, httpsrv.URL, `{"jsonrpc":"2.0","id":8,"method":"test_rets"}`)
+	if !strings.Contains(resp, limitCode) || !strings.Contains(resp, errRateLimited.Error()) {
+		t.Errorf("request not rate limited: %s", resp)
+	}
+}
+
+func TestHTTPLimitsJWTSubject(t *testing.T) {
+	server := newTestServer()
+	server.SetLimits(Limits{RequestsPerSecond: 0.001, Burst: 2})
+	defer server.Stop()
+
+	handler, err := newAuthHandler(testAuthConfig, server)
+	if err != nil {
+		t.Fatalf("failed to create auth handler: %v", err)
+	}
+	httpsrv := httptest.NewServer(handler)
+	defer httpsrv.Close()
+
+	// Tokens signed with the same secret share a bucket, whatever their subject
+	for i, sub := range []string{"alice", "bob"} {
+		token := signTestJWT(t, "test-secret", jwt.StandardClaims{Subject: sub, IssuedAt: time.Now().Unix()})
+		if _, msg := doAuthRequest(t, httpsrv.URL, token); msg == errRateLimited.Error() {
+			t.Fatalf("call %d rate limited", i)
+		}
+	}
+	token := signTestJWT(t, "test-secret", jwt.StandardClaims{Subject: "carol", IssuedAt: time.Now().Unix()})
+	if _, msg := doAuthRequest(t, httpsrv.URL, token); msg != errRateLimited.Error() {
+		t.Fatalf("call with fresh subject error mismatch: have %q, want %q", msg, errRateLimited.Error())
+	}
+}
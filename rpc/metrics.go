@@ -0,0 +1,29 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+var (
+	limitedRequestCounter     = metrics.NewRegisteredCounter("rpc/limits/requests", nil)     // Counter of calls admitted by the client limits
+	limitedRateCounter        = metrics.NewRegisteredCounter("rpc/limits/rate", nil)         // Counter of calls rejected for exceeding the request rate
+	limitedConcurrencyCounter = metrics.NewRegisteredCounter("rpc/limits/concurrency", nil)  // Counter of calls rejected for exceeding the in-flight calls
+	limitedBatchCounter       = metrics.NewRegisteredCounter("rpc/limits/batch", nil)        // Counter of batches rejected for exceeding the batch length
+	limitedResponseCounter    = metrics.NewRegisteredCounter("rpc/limits/responsesize", nil) // Counter of responses dropped for exceeding the response size
+)
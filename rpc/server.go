@@ -43,6 +43,7 @@ const (
 // Server is an RPC server.
 type Server struct {
 	services serviceRegistry
+	limiter  *serverLimiter
 	idgen    func() ID
 	run      int32
 	codecs   mapset.Set
@@ -66,12 +67,28 @@ func (s *Server) RegisterName(name string, receiver interface{}) error {
 	return s.services.registerName(name, receiver)
 }
 
+// SetLimits configures the resources each client of the server may consume. It
+// must be called before the server starts serving requests.
+func (s *Server) SetLimits(limits Limits) {
+	if limits.Enabled() {
+		s.limiter = newServerLimiter(limits)
+	} else {
+		s.limiter = nil
+	}
+}
+
 // ServeCodec reads incoming requests from codec, calls the appropriate callback and writes
 // the response back using the given codec. It will block until the codec is closed or the
 // server is stopped. In either case the codec is closed.
 //
 // Note that codec options are no longer supported.
 func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
+	s.serveCodec(codec, &s.services, nil)
+}
+
+// serveCodec serves the given codec from the given service registry, enforcing
+// the limits of the remote client if any.
+func (s *Server) serveCodec(codec ServerCodec, services *serviceRegistry, limiter *clientLimiter) {
 	defer codec.Close()
 
 	// Don't serve if server is stopped.
@@ -83,7 +100,7 @@ func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
 	s.codecs.Add(codec)
 	defer s.codecs.Remove(codec)
 
-	c := initClient(codec, s.idgen, &s.services)
+	c := initClient(codec, s.idgen, services, limiter)
 	<-codec.Closed()
 	c.Close()
 }
@@ -97,7 +114,8 @@ func (s *Server) serveSingleRequest(ctx context.Context, codec ServerCodec) {
 		return
 	}
 
-	h := newHandler(ctx, codec, s.idgen, &s.services)
+	h := newHandler(ctx, codec, s.idgen, s.registry(ctx))
+	h.limiter = s.clientLimiter(ctx, codec.RemoteAddr())
 	h.allowSubscribe = false
 	defer h.close(io.EOF, nil)
 
@@ -115,6 +133,24 @@ func (s *Server) serveSingleRequest(ctx context.Context, codec ServerCodec) {
 	}
 }
 
+// registry returns the services available to the caller of the given context,
+// which are restricted to the modules granted by its authentication token.
+func (s *Server) registry(ctx context.Context) *serviceRegistry {
+	if modules, ok := ctx.Value(authModulesKey{}).(map[string]bool); ok {
+		return s.services.restrict(modules)
+	}
+	return &s.services
+}
+
+// clientLimiter returns the limiter of the caller of the given context, or nil
+// if the server doesn't limit its clients.
+func (s *Server) clientLimiter(ctx context.Context, remote string) *clientLimiter {
+	if s.limiter == nil {
+		return nil
+	}
+	return s.limiter.client(ctx, remote)
+}
+
 // Stop stops reading new requests, waits for stopPendingRequestTimeout to allow pending
 // requests to finish, then closes all codecs which will cancel pending requests and
 // subscriptions.
@@ -93,6 +93,21 @@ func (r *serviceRegistry) registerName(name string, rcvr interface{}) error {
 	return nil
 }
 
+// restrict returns a registry holding only the services of the given modules,
+// along with the metadata service describing the server.
+func (r *serviceRegistry) restrict(modules map[string]bool) *serviceRegistry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	restricted := &serviceRegistry{services: make(map[string]service)}
+	for name, svc := range r.services {
+		if modules[name] || name == MetadataApi {
+			restricted.services[name] = svc
+		}
+	}
+	return restricted
+}
+
 // callback returns the callback corresponding to the given RPC method name.
 func (r *serviceRegistry) callback(method string) *callback {
 	elem := strings.SplitN(method, serviceMethodSeparator, 2)
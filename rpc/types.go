@@ -18,10 +18,12 @@ package rpc
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"math"
 	"strings"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 )
 
@@ -105,3 +107,126 @@ func (bn *BlockNumber) UnmarshalJSON(data []byte) error {
 func (bn BlockNumber) Int64() int64 {
 	return (int64)(bn)
 }
+
+// encode returns the textual representation of the block number as accepted
+// by UnmarshalJSON. It's deliberately not a String method, as that would alter
+// how block numbers are rendered by the fmt verbs.
+func (bn BlockNumber) encode() string {
+	switch bn {
+	case EarliestBlockNumber:
+		return "earliest"
+	case LatestBlockNumber:
+		return "latest"
+	case PendingBlockNumber:
+		return "pending"
+	}
+	return hexutil.Uint64(bn).String()
+}
+
+// BlockNumberOrHash selects a block either by number (or one of the special
+// labels "latest", "earliest" and "pending"), or by hash. Selecting by hash
+// pins a query to an exact block, so it can't silently resolve to a different
+// block across a reorg. If RequireCanonical is set, the hash must additionally
+// still be part of the canonical chain.
+type BlockNumberOrHash struct {
+	BlockNumber      *BlockNumber `json:"blockNumber,omitempty"`
+	BlockHash        *common.Hash `json:"blockHash,omitempty"`
+	RequireCanonical bool         `json:"requireCanonical,omitempty"`
+}
+
+// UnmarshalJSON parses the given JSON fragment into a BlockNumberOrHash. It supports:
+// - everything accepted by BlockNumber.UnmarshalJSON
+// - a 32 byte hex encoded block hash
+// - an object with either a blockNumber or a blockHash field, and an optional
+//   requireCanonical flag
+func (bnh *BlockNumberOrHash) UnmarshalJSON(data []byte) error {
+	type erased BlockNumberOrHash
+	e := erased{}
+	if err := json.Unmarshal(data, &e); err == nil {
+		if e.BlockNumber != nil && e.BlockHash != nil {
+			return fmt.Errorf("cannot specify both BlockHash and BlockNumber, choose one or the other")
+		}
+		if e.BlockNumber == nil && e.BlockHash == nil {
+			return fmt.Errorf("either BlockHash or BlockNumber must be specified")
+		}
+		if e.BlockNumber != nil && e.RequireCanonical {
+			return fmt.Errorf("requireCanonical is only valid for BlockHash")
+		}
+		*bnh = BlockNumberOrHash(e)
+		return nil
+	}
+	var input string
+	if err := json.Unmarshal(data, &input); err != nil {
+		return err
+	}
+	if len(input) == 2*common.HashLength+2 {
+		var hash common.Hash
+		if err := hash.UnmarshalText([]byte(input)); err != nil {
+			return err
+		}
+		*bnh = BlockNumberOrHashWithHash(hash, false)
+		return nil
+	}
+	var number BlockNumber
+	if err := number.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	*bnh = BlockNumberOrHashWithNumber(number)
+	return nil
+}
+
+// MarshalJSON encodes the selector in the most compact form UnmarshalJSON
+// accepts: a plain string unless canonicality is required.
+func (bnh BlockNumberOrHash) MarshalJSON() ([]byte, error) {
+	if bnh.BlockNumber != nil {
+		return json.Marshal(bnh.BlockNumber.encode())
+	}
+	if bnh.BlockHash != nil && !bnh.RequireCanonical {
+		return json.Marshal(bnh.BlockHash)
+	}
+	type erased BlockNumberOrHash
+	return json.Marshal(erased(bnh))
+}
+
+// Number returns the selected block number, if the block is selected by number.
+func (bnh *BlockNumberOrHash) Number() (BlockNumber, bool) {
+	if bnh.BlockNumber != nil {
+		return *bnh.BlockNumber, true
+	}
+	return BlockNumber(0), false
+}
+
+// Hash returns the selected block hash, if the block is selected by hash.
+func (bnh *BlockNumberOrHash) Hash() (common.Hash, bool) {
+	if bnh.BlockHash != nil {
+		return *bnh.BlockHash, true
+	}
+	return common.Hash{}, false
+}
+
+// String implements fmt.Stringer.
+func (bnh BlockNumberOrHash) String() string {
+	if bnh.BlockNumber != nil {
+		return bnh.BlockNumber.encode()
+	}
+	if bnh.BlockHash != nil {
+		return bnh.BlockHash.Hex()
+	}
+	return "nil"
+}
+
+// BlockNumberOrHashWithNumber creates a selector for the given block number.
+func BlockNumberOrHashWithNumber(blockNr BlockNumber) BlockNumberOrHash {
+	return BlockNumberOrHash{
+		BlockNumber: &blockNr,
+	}
+}
+
+// BlockNumberOrHashWithHash creates a selector for the given block hash,
+// optionally requiring it to be canonical.
+func BlockNumberOrHashWithHash(hash common.Hash, canonical bool) BlockNumberOrHash {
+	return BlockNumberOrHash{
+		BlockHash:        &hash,
+		RequireCanonical: canonical,
+	}
+}
@@ -62,7 +62,8 @@ func (s *Server) WebsocketHandler(allowedOrigins []string) http.Handler {
 		Handshake: wsHandshakeValidator(allowedOrigins),
 		Handler: func(conn *websocket.Conn) {
 			codec := newWebsocketCodec(conn)
-			s.ServeCodec(codec, OptionMethodInvocation|OptionSubscriptions)
+			req := conn.Request()
+			s.serveCodec(codec, s.registry(req.Context()), s.clientLimiter(req.Context(), req.RemoteAddr))
 		},
 	}
 }
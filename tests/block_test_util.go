@@ -94,12 +94,19 @@ type btHeaderMarshaling struct {
 	Timestamp  math.HexOrDecimal64
 }
 
+// Run imports the test's blocks under the chain config of its network and
+// validates the resulting chain head and post-state.
 func (t *BlockTest) Run() error {
-	config, ok := Forks[t.json.Network]
-	if !ok {
-		return UnsupportedForkError{t.json.Network}
+	config, err := GetChainConfig(t.json.Network)
+	if err != nil {
+		return err
 	}
+	return t.RunWithConfig(config)
+}
 
+// RunWithConfig imports the test's blocks under the given chain config and
+// validates the resulting chain head and post-state.
+func (t *BlockTest) RunWithConfig(config *params.ChainConfig) error {
 	// import pre accounts & construct test genesis block & state root
 	db := ethdb.NewMemDatabase()
 	gblock, err := t.genesis(config).Commit(db)
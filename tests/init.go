@@ -17,7 +17,9 @@
 package tests
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/params"
@@ -75,6 +77,18 @@ var Forks = map[string]*params.ChainConfig{
 		ConstantinopleBlock: big.NewInt(0),
 		PetersburgBlock:     big.NewInt(0),
 	},
+	"Istanbul": {
+		ChainID:             big.NewInt(1),
+		HomesteadBlock:      big.NewInt(0),
+		EIP150Block:         big.NewInt(0),
+		EIP155Block:         big.NewInt(0),
+		EIP158Block:         big.NewInt(0),
+		DAOForkBlock:        big.NewInt(0),
+		ByzantiumBlock:      big.NewInt(0),
+		ConstantinopleBlock: big.NewInt(0),
+		PetersburgBlock:     big.NewInt(0),
+		IstanbulBlock:       big.NewInt(0),
+	},
 	"FrontierToHomesteadAt5": {
 		ChainID:        big.NewInt(1),
 		HomesteadBlock: big.NewInt(5),
@@ -107,6 +121,106 @@ var Forks = map[string]*params.ChainConfig{
 		ByzantiumBlock:      big.NewInt(0),
 		ConstantinopleBlock: big.NewInt(5),
 	},
+	"ClassicDieHard": {
+		ChainID:            big.NewInt(1),
+		HomesteadBlock:     big.NewInt(0),
+		EIP150Block:        big.NewInt(0),
+		EIP155Block:        big.NewInt(0),
+		EIP160FBlock:       big.NewInt(0),
+		ECIP1010PauseBlock: big.NewInt(0),
+		ECIP1010Length:     big.NewInt(2000000),
+	},
+	"ClassicGotham": {
+		ChainID:            big.NewInt(1),
+		HomesteadBlock:     big.NewInt(0),
+		EIP150Block:        big.NewInt(0),
+		EIP155Block:        big.NewInt(0),
+		EIP160FBlock:       big.NewInt(0),
+		ECIP1010PauseBlock: big.NewInt(0),
+		ECIP1010Length:     big.NewInt(2000000),
+		ECIP1017EraRounds:  big.NewInt(5000000),
+		DisposalBlock:      big.NewInt(0),
+	},
+	"ClassicAtlantis": {
+		ChainID:            big.NewInt(1),
+		HomesteadBlock:     big.NewInt(0),
+		EIP150Block:        big.NewInt(0),
+		EIP155Block:        big.NewInt(0),
+		EIP160FBlock:       big.NewInt(0),
+		EIP161FBlock:       big.NewInt(0),
+		EIP170FBlock:       big.NewInt(0),
+		EIP100FBlock:       big.NewInt(0),
+		EIP140FBlock:       big.NewInt(0),
+		EIP198FBlock:       big.NewInt(0),
+		EIP211FBlock:       big.NewInt(0),
+		EIP212FBlock:       big.NewInt(0),
+		EIP213FBlock:       big.NewInt(0),
+		EIP214FBlock:       big.NewInt(0),
+		EIP658FBlock:       big.NewInt(0),
+		ECIP1010PauseBlock: big.NewInt(0),
+		ECIP1010Length:     big.NewInt(2000000),
+		ECIP1017EraRounds:  big.NewInt(5000000),
+		DisposalBlock:      big.NewInt(0),
+	},
+}
+
+// NamedConfigs maps the bundled network configurations to names which may be
+// used in place of a fork name when running or filling tests. The tests run
+// under a copy of the configuration with every configured fork block moved to
+// the genesis block, like the Forks entries, so that the full set of the
+// network's rules applies at the block height of the tests.
+var NamedConfigs = map[string]*params.ChainConfig{
+	"Mainnet":     params.MainnetChainConfig,
+	"Classic":     params.ClassicChainConfig,
+	"Social":      params.SocialChainConfig,
+	"Mix":         params.MixChainConfig,
+	"Ethersocial": params.EthersocialChainConfig,
+	"Kotti":       params.KottiChainConfig,
+	"Ropsten":     params.TestnetChainConfig,
+	"Rinkeby":     params.RinkebyChainConfig,
+	"Goerli":      params.GoerliChainConfig,
+}
+
+// GetChainConfig returns the chain config of the given fork or bundled network
+// configuration.
+func GetChainConfig(name string) (*params.ChainConfig, error) {
+	if config, ok := Forks[name]; ok {
+		return config, nil
+	}
+	if config, ok := NamedConfigs[name]; ok {
+		return activateForks(config), nil
+	}
+	return nil, UnsupportedForkError{name}
+}
+
+// activateForks returns a copy of the chain config with every configured fork
+// block set to the genesis block. Fork parameters and chain policies are kept.
+func activateForks(config *params.ChainConfig) *params.ChainConfig {
+	cpy := *config
+	for _, field := range cpy.ForkSchedule() {
+		if field.Kind == params.ForkBlock {
+			cpy.SetValue(field, new(big.Int))
+		}
+	}
+	return &cpy
+}
+
+// LoadChainConfig returns the chain config of the given fork or bundled network
+// configuration, falling back to reading a JSON encoded params.ChainConfig from
+// the file at the given path. Configs read from files are used as given.
+func LoadChainConfig(nameOrPath string) (*params.ChainConfig, error) {
+	if config, err := GetChainConfig(nameOrPath); err == nil {
+		return config, nil
+	}
+	blob, err := ioutil.ReadFile(nameOrPath)
+	if err != nil {
+		return nil, fmt.Errorf("unknown chain config %q: %v", nameOrPath, err)
+	}
+	config := new(params.ChainConfig)
+	if err := json.Unmarshal(blob, config); err != nil {
+		return nil, fmt.Errorf("invalid chain config %q: %v", nameOrPath, err)
+	}
+	return config, nil
 }
 
 // UnsupportedForkError is returned when a test requests a fork that isn't implemented.
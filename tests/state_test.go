@@ -19,13 +19,19 @@ package tests
 import (
 	"bufio"
 	"bytes"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
 )
 
 func TestState(t *testing.T) {
@@ -68,6 +74,158 @@ func TestState(t *testing.T) {
 	})
 }
 
+const fillTestJSON = `{
+	"env": {
+		"currentCoinbase": "2adc25665018aa1fe0e6bc666dac8fc2697ff9ba",
+		"currentDifficulty": "0x020000",
+		"currentGasLimit": "0x7fffffffffffffff",
+		"currentNumber": "0x01",
+		"currentTimestamp": "0x03e8"
+	},
+	"pre": {
+		"a94f5374fce5edbc8e2a8697c15331677e6ebf0b": {
+			"balance": "0x0de0b6b3a7640000",
+			"code": "0x",
+			"nonce": "0x00",
+			"storage": {}
+		},
+		"095e7baea6a6c7c4c2dfeb977efac326af552d87": {
+			"balance": "0x00",
+			"code": "0x60ff60ff0a00",
+			"nonce": "0x00",
+			"storage": {}
+		}
+	},
+	"transaction": {
+		"data": ["0x", "0x01"],
+		"gasLimit": ["0x5208", "0x0186a0"],
+		"gasPrice": "0x01",
+		"nonce": "0x00",
+		"secretKey": "0x45a915e4d060149eb4365960e6a7a45f334393093061116b197e3240065ff2d8",
+		"to": "0x095e7baea6a6c7c4c2dfeb977efac326af552d87",
+		"value": ["0x01"]
+	},
+	"post": {}
+}`
+
+// fillTestRoots are the post-state roots of fillTestJSON under ClassicDieHard
+// rules, in subtest order. The subtests with enough gas to run the contract
+// depend on the EIP160 pricing of EXP.
+var fillTestRoots = []common.Hash{
+	common.HexToHash("0xfd4bc118ed443ab6b1b24a3c7456eb0e0e5afce1d339e3a76c2b8db4da07b43d"),
+	common.HexToHash("0x150e797a3f4fe998be6f7202ac58455ffa8d0ded65500ef8d5fa5cfe140a73c1"),
+	common.HexToHash("0x2f806236343e81f75df088d744bf32980d088bdd31823950dd28bdd9fe956120"),
+	common.HexToHash("0xb52890b41d3f7e59ffe3065fe698e26505427f08953580429fe88bf7beaa950d"),
+}
+
+// fillTestLogs is the hash of the empty log list emitted by all subtests.
+var fillTestLogs = common.HexToHash("0x1dcc4de8dec75d7aab85b567b6ccd41ad312451b948a7413f0a142fd40d49347")
+
+func TestStateFill(t *testing.T) {
+	var test StateTest
+	if err := json.Unmarshal([]byte(fillTestJSON), &test); err != nil {
+		t.Fatalf("failed to decode test: %v", err)
+	}
+	if err := test.Fill("ClassicDieHard", Forks["ClassicDieHard"], vm.Config{}); err != nil {
+		t.Fatalf("failed to fill test: %v", err)
+	}
+	if n := len(test.Subtests()); n != 4 {
+		t.Fatalf("filled subtest count mismatch: have %d, want %d", n, 4)
+	}
+	for i, post := range test.json.Post["ClassicDieHard"] {
+		if want := fillTestRoots[i]; common.Hash(post.Root) != want {
+			t.Errorf("subtest %d: root mismatch: have %x, want %x", i, post.Root, want)
+		}
+		if common.Hash(post.Logs) != fillTestLogs {
+			t.Errorf("subtest %d: logs hash mismatch: have %x, want %x", i, post.Logs, fillTestLogs)
+		}
+	}
+	// Round-trip the filled test through its JSON encoding and verify each
+	// subtest against the fork it was filled for.
+	blob, err := json.Marshal(test)
+	if err != nil {
+		t.Fatalf("failed to encode filled test: %v", err)
+	}
+	var filled StateTest
+	if err := json.Unmarshal(blob, &filled); err != nil {
+		t.Fatalf("failed to decode filled test: %v", err)
+	}
+	for _, subtest := range filled.Subtests() {
+		if _, err := filled.Run(subtest, vm.Config{}); err != nil {
+			t.Errorf("subtest %s/%d failed: %v", subtest.Fork, subtest.Index, err)
+		}
+	}
+	if _, err := filled.Run(StateSubtest{"Unknown", 0}, vm.Config{}); err == nil {
+		t.Errorf("expected unsupported fork error")
+	}
+}
+
+// Tests that named network configs have all their forks activated at genesis,
+// so that filling under them yields the post-states of their latest rules.
+func TestStateFillNamedConfig(t *testing.T) {
+	config, err := GetChainConfig("Classic")
+	if err != nil {
+		t.Fatalf("failed to get named config: %v", err)
+	}
+	for _, field := range config.ForkSchedule() {
+		if field.Kind == params.ForkBlock && config.Value(field).Sign() != 0 {
+			t.Errorf("fork %s not activated at genesis: %v", field.Name, config.Value(field))
+		}
+	}
+	if params.ClassicChainConfig.EIP160FBlock.Sign() == 0 {
+		t.Errorf("network config modified by named config lookup")
+	}
+	fill := func(config *params.ChainConfig) []stPostState {
+		var test StateTest
+		if err := json.Unmarshal([]byte(fillTestJSON), &test); err != nil {
+			t.Fatalf("failed to decode test: %v", err)
+		}
+		if err := test.Fill("Classic", config, vm.Config{}); err != nil {
+			t.Fatalf("failed to fill test: %v", err)
+		}
+		return test.json.Post["Classic"]
+	}
+	for i, post := range fill(config) {
+		if want := fillTestRoots[i]; common.Hash(post.Root) != want {
+			t.Errorf("subtest %d: root mismatch: have %x, want %x", i, post.Root, want)
+		}
+	}
+	// The network config as given runs block 1 under Frontier rules
+	if post := fill(params.ClassicChainConfig); common.Hash(post[1].Root) == fillTestRoots[1] {
+		t.Errorf("subtest 1: unexpected post-Frontier root under network fork heights")
+	}
+}
+
+func TestLoadChainConfig(t *testing.T) {
+	config, err := LoadChainConfig("Classic")
+	if err != nil {
+		t.Fatalf("failed to load named config: %v", err)
+	}
+	if want, _ := GetChainConfig("Classic"); !reflect.DeepEqual(config, want) {
+		t.Errorf("named config mismatch: have %v, want %v", config, want)
+	}
+	dir, err := ioutil.TempDir("", "chainconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "custom.json")
+	blob, _ := json.Marshal(Forks["ClassicDieHard"])
+	if err := ioutil.WriteFile(path, blob, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if config, err = LoadChainConfig(path); err != nil {
+		t.Fatalf("failed to load config file: %v", err)
+	}
+	if have, _ := json.Marshal(config); !bytes.Equal(have, blob) {
+		t.Errorf("loaded config mismatch: have %s, want %s", have, blob)
+	}
+	if _, err := LoadChainConfig(filepath.Join(dir, "missing.json")); err == nil {
+		t.Errorf("expected error for missing config")
+	}
+}
+
 // Transactions with gasLimit above this value will not get a VM trace on failure.
 const traceErrorLimit = 400000
 
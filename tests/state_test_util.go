@@ -53,6 +53,12 @@ func (t *StateTest) UnmarshalJSON(in []byte) error {
 	return json.Unmarshal(in, &t.json)
 }
 
+// MarshalJSON implements json.Marshaler, allowing filled tests to be written
+// back out in the fixture format.
+func (t StateTest) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&t.json)
+}
+
 type stJSON struct {
 	Env  stEnv                    `json:"env"`
 	Pre  core.GenesisAlloc        `json:"pre"`
@@ -68,7 +74,7 @@ type stPostState struct {
 		Data  int `json:"data"`
 		Gas   int `json:"gas"`
 		Value int `json:"value"`
-	}
+	} `json:"indexes"`
 }
 
 //go:generate gencodec -type stEnv -field-override stEnvMarshaling -out gen_stenv.go
@@ -119,19 +125,70 @@ func (t *StateTest) Subtests() []StateSubtest {
 	return sub
 }
 
-// Run executes a specific subtest.
+// Run executes a specific subtest and verifies the post-state and logs
+// against the chain config of the subtest's fork.
 func (t *StateTest) Run(subtest StateSubtest, vmconfig vm.Config) (*state.StateDB, error) {
-	config, ok := Forks[subtest.Fork]
-	if !ok {
-		return nil, UnsupportedForkError{subtest.Fork}
+	config, err := GetChainConfig(subtest.Fork)
+	if err != nil {
+		return nil, err
+	}
+	return t.RunWithConfig(subtest, config, vmconfig)
+}
+
+// RunWithConfig executes a specific subtest under the given chain config and
+// verifies the post-state and logs recorded for the subtest's fork.
+func (t *StateTest) RunWithConfig(subtest StateSubtest, config *params.ChainConfig, vmconfig vm.Config) (*state.StateDB, error) {
+	post := t.json.Post[subtest.Fork][subtest.Index]
+	statedb, root, err := t.execute(post, config, vmconfig)
+	if err != nil {
+		return nil, err
+	}
+	if root != common.Hash(post.Root) {
+		return statedb, fmt.Errorf("post state root mismatch: got %x, want %x", root, post.Root)
 	}
+	if logs := rlpHash(statedb.Logs()); logs != common.Hash(post.Logs) {
+		return statedb, fmt.Errorf("post state logs hash mismatch: got %x, want %x", logs, post.Logs)
+	}
+	return statedb, nil
+}
+
+// Fill executes every combination of the transaction's data, gas and value
+// under the given chain config and records the resulting post-states and logs
+// as the expectations of the named fork, replacing any previous ones.
+func (t *StateTest) Fill(fork string, config *params.ChainConfig, vmconfig vm.Config) error {
+	var posts []stPostState
+	for d := range t.json.Tx.Data {
+		for g := range t.json.Tx.GasLimit {
+			for v := range t.json.Tx.Value {
+				post := stPostState{}
+				post.Indexes.Data, post.Indexes.Gas, post.Indexes.Value = d, g, v
+
+				statedb, root, err := t.execute(post, config, vmconfig)
+				if err != nil {
+					return err
+				}
+				post.Root = common.UnprefixedHash(root)
+				post.Logs = common.UnprefixedHash(rlpHash(statedb.Logs()))
+				posts = append(posts, post)
+			}
+		}
+	}
+	if t.json.Post == nil {
+		t.json.Post = make(map[string][]stPostState)
+	}
+	t.json.Post[fork] = posts
+	return nil
+}
+
+// execute applies the transaction selected by the post-state indexes on top
+// of the test's pre-state and returns the resulting state and its root.
+func (t *StateTest) execute(post stPostState, config *params.ChainConfig, vmconfig vm.Config) (*state.StateDB, common.Hash, error) {
 	block := t.genesis(config).ToBlock(nil)
 	statedb := MakePreState(ethdb.NewMemDatabase(), t.json.Pre)
 
-	post := t.json.Post[subtest.Fork][subtest.Index]
 	msg, err := t.json.Tx.toMessage(post)
 	if err != nil {
-		return nil, err
+		return nil, common.Hash{}, err
 	}
 	context := core.NewEVMContext(msg, block.Header(), nil, &t.json.Env.Coinbase)
 	context.GetHash = vmTestBlockHash
@@ -155,13 +212,7 @@ func (t *StateTest) Run(subtest StateSubtest, vmconfig vm.Config) (*state.StateD
 	root := statedb.IntermediateRoot(config.IsEIP161F(block.Number()))
 	// N.B: We need to do this in a two-step process, because the first Commit takes care
 	// of suicides, and we need to touch the coinbase _after_ it has potentially suicided.
-	if root != common.Hash(post.Root) {
-		return statedb, fmt.Errorf("post state root mismatch: got %x, want %x", root, post.Root)
-	}
-	if logs := rlpHash(statedb.Logs()); logs != common.Hash(post.Logs) {
-		return statedb, fmt.Errorf("post state logs hash mismatch: got %x, want %x", logs, post.Logs)
-	}
-	return statedb, nil
+	return statedb, root, nil
 }
 
 func (t *StateTest) gasLimit(subtest StateSubtest) uint64 {